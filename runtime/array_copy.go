@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"reflect"
+)
+
+// CopyArray returns the deep copy of the array. PHP arrays are values,
+// so the array that is shared is copied before it is modified.
+func CopyArray(arr interface{}) interface{} {
+	v := reflect.ValueOf(arr)
+	if !v.IsValid() {
+		return arr
+	}
+	return copyValue(v).Interface()
+}
+
+type arrayCopier interface {
+	CopyArray() interface{}
+}
+
+func copyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i)))
+		}
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(copyValue(v.Elem()))
+		return c
+	case reflect.Ptr:
+		if a, ok := v.Interface().(arrayCopier); ok {
+			return reflect.ValueOf(a.CopyArray())
+		}
+	case reflect.Struct:
+		if v.CanInterface() {
+			if a, ok := v.Interface().(arrayCopier); ok {
+				return reflect.ValueOf(a.CopyArray())
+			}
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < c.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+		return c
+	}
+
+	return v
+}
@@ -0,0 +1,31 @@
+package runtime
+
+import "testing"
+
+func TestCopyArray(t *testing.T) {
+	arr := [][]int64{{1, 2}, {3}}
+	c := CopyArray(arr).([][]int64)
+	c[0][0] = 10
+	if arr[0][0] != 1 {
+		t.Errorf("the nested slice is shared")
+	}
+
+	vars := []Var{NewVarFromInterface([]string{"a"})}
+	cv := CopyArray(vars).([]Var)
+	cv[0].Value().([]string)[0] = "b"
+	if vars[0].Value().([]string)[0] != "a" {
+		t.Errorf("the slice in Var is shared")
+	}
+
+	a := NewOrderedArray(Pair{Key: "x", Value: []int64{1}})
+	ca := CopyArray(a).(*OrderedArray)
+	ca.Get("x").([]int64)[0] = 5
+	ca.Set("y", int64(1))
+	if a.Get("x").([]int64)[0] != 1 || a.Has("y") {
+		t.Errorf("OrderedArray is shared")
+	}
+
+	if CopyArray(nil) != nil {
+		t.Errorf("the copy of nil is not nil")
+	}
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import "reflect"
+
+// The functions below implement is_int(), is_float() and the other
+// checks of types for the values of union types.
+
+func Isint64(val Var) bool {
+	return val.Type == Constantint64
+}
+
+func Isfloat64(val Var) bool {
+	return val.Type == Constantfloat64
+}
+
+func Isstring(val Var) bool {
+	return val.Type == Constantstring
+}
+
+func Isbool(val Var) bool {
+	return val.Type == Constantbool
+}
+
+func Isnull(val Var) bool {
+	return val.Type == Constantnull
+}
+
+func Isarray(val Var) bool {
+	return val.Type == Constantarray
+}
+
+// The functions below implement the checks of types
+// for the values of the types known at translation time.
+
+func Isint64Simple(val interface{}) bool {
+	_, ok := val.(int64)
+	return ok
+}
+
+func Isfloat64Simple(val interface{}) bool {
+	_, ok := val.(float64)
+	return ok
+}
+
+func IsstringSimple(val interface{}) bool {
+	_, ok := val.(string)
+	return ok
+}
+
+func IsboolSimple(val interface{}) bool {
+	_, ok := val.(bool)
+	return ok
+}
+
+func IsnullSimple(val interface{}) bool {
+	return val == nil
+}
+
+func IsarraySimple(val interface{}) bool {
+	if _, ok := val.(*OrderedArray); ok {
+		return true
+	}
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
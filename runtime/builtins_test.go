@@ -0,0 +1,21 @@
+package runtime
+
+import "testing"
+
+func TestIsFunctions(t *testing.T) {
+	i, s, n, arr := NewVarint64(1), NewVarstring("1"), Var{}, NewVarFromInterface([]int64{})
+
+	if !Isint64(i) || Isint64(s) || !Isstring(s) || !Isnull(n) || Isnull(i) || !Isarray(arr) || Isarray(i) {
+		t.Errorf("the types of Var are checked wrong")
+	}
+	if !Isfloat64(NewVarfloat64(1)) || !Isbool(NewVarbool(false)) {
+		t.Errorf("the types of Var are checked wrong")
+	}
+
+	if !Isint64Simple(int64(1)) || Isint64Simple(1.0) || !IsnullSimple(nil) || !IsstringSimple("") || !IsboolSimple(true) || !Isfloat64Simple(1.0) {
+		t.Errorf("the types of values are checked wrong")
+	}
+	if !IsarraySimple([]string{}) || !IsarraySimple(NewOrderedArray()) || IsarraySimple("a") {
+		t.Errorf("the arrays are checked wrong")
+	}
+}
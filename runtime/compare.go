@@ -0,0 +1,171 @@
+package runtime
+
+import (
+	"reflect"
+)
+
+// CompareType is the kind of comparison passed to the
+// CompareWith methods of Var and nullable types.
+type CompareType uint8
+
+const (
+	Equal CompareType = iota
+	NotEqual
+	Greater
+	GreaterEqual
+	Smaller
+	SmallerEqual
+)
+
+// compareResult returns the result of the comparison
+// by the result of Compare.
+func compareResult(c int, compare CompareType) bool {
+	switch compare {
+	case Equal:
+		return c == 0
+	case NotEqual:
+		return c != 0
+	case Greater:
+		return c > 0
+	case GreaterEqual:
+		return c >= 0
+	case Smaller:
+		return c < 0
+	case SmallerEqual:
+		return c <= 0
+	}
+	return false
+}
+
+// comparable reports whether the value is compared with the scalar
+// values, the comparisons of arrays and objects are false.
+func (v Var) comparable() bool {
+	return v.Type != Constantarray && v.Type != ConstantGenerator
+}
+
+func (v Var) CompareWithbool(val bool, compare CompareType) bool {
+	if !v.comparable() {
+		return false
+	}
+	if v.Type != Constantbool {
+		return compareResult(Compare(v, NewVarbool(val)), compare)
+	}
+
+	// true is greater than false.
+	switch compare {
+	case Equal:
+		return v.b == val
+	case NotEqual:
+		return v.b != val
+	case Greater:
+		return v.b && !val
+	case GreaterEqual:
+		return v.b || !val
+	case Smaller:
+		return !v.b && val
+	case SmallerEqual:
+		return !v.b || val
+	}
+
+	return false
+}
+
+func (v Var) CompareWithfloat64(val float64, compare CompareType) bool {
+	if !v.comparable() {
+		return false
+	}
+	if v.Type != Constantfloat64 {
+		return compareResult(Compare(v, NewVarfloat64(val)), compare)
+	}
+
+	switch compare {
+	case Equal:
+		return v.f == val
+	case NotEqual:
+		return v.f != val
+	case Greater:
+		return v.f > val
+	case GreaterEqual:
+		return v.f >= val
+	case Smaller:
+		return v.f < val
+	case SmallerEqual:
+		return v.f <= val
+	}
+
+	return false
+}
+
+func (v Var) CompareWithint64(val int64, compare CompareType) bool {
+	if !v.comparable() {
+		return false
+	}
+	if v.Type != Constantint64 {
+		return compareResult(Compare(v, NewVarint64(val)), compare)
+	}
+
+	switch compare {
+	case Equal:
+		return v.i == val
+	case NotEqual:
+		return v.i != val
+	case Greater:
+		return v.i > val
+	case GreaterEqual:
+		return v.i >= val
+	case Smaller:
+		return v.i < val
+	case SmallerEqual:
+		return v.i <= val
+	}
+
+	return false
+}
+
+func (v Var) CompareWithstring(val string, compare CompareType) bool {
+	if !v.comparable() {
+		return false
+	}
+	if v.Type != Constantstring {
+		return compareResult(Compare(v, NewVarstring(val)), compare)
+	}
+
+	switch compare {
+	case Equal:
+		return v.s == val
+	case NotEqual:
+		return v.s != val
+	case Greater:
+		return v.s > val
+	case GreaterEqual:
+		return v.s >= val
+	case Smaller:
+		return v.s < val
+	case SmallerEqual:
+		return v.s <= val
+	}
+
+	return false
+}
+
+// CompareWithnull compares the value with null like PHP does.
+func (v Var) CompareWithnull(val interface{}, compare CompareType) bool {
+	return compareResult(Compare(v, Var{}), compare)
+}
+
+// Identical reports whether the values are of the same type and equal,
+// like the === operator. The arrays are identical if they have the same
+// elements in the same order.
+func Identical(a, b Var) bool {
+	if a.Type != b.Type {
+		return false
+	}
+
+	switch a.Type {
+	case Constantarray:
+		return reflect.DeepEqual(a.other, b.other)
+	case ConstantGenerator:
+		return a.other == b.other
+	}
+	return a.Value() == b.Value()
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import "testing"
+
+func TestCompareWith(t *testing.T) {
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"1 == 1", NewVarint64(1).CompareWithint64(1, Equal), true},
+		{"1 < 2", NewVarint64(1).CompareWithint64(2, Smaller), true},
+		{`"10" > 9`, NewVarstring("10").CompareWithint64(9, Greater), true},
+		{`"abc" == 0`, NewVarstring("abc").CompareWithint64(0, Equal), false},
+		{"1.5 >= 1.5", NewVarfloat64(1.5).CompareWithfloat64(1.5, GreaterEqual), true},
+		{"true > false", NewVarbool(true).CompareWithbool(false, Greater), true},
+		{`"a" < "b"`, NewVarstring("a").CompareWithstring("b", Smaller), true},
+		{"null == 0", Var{}.CompareWithint64(0, Equal), true},
+		{"null == false", Var{}.CompareWithbool(false, Equal), true},
+		{"0 == null", NewVarint64(0).CompareWithnull(nil, Equal), true},
+		{"1 != null", NewVarint64(1).CompareWithnull(nil, NotEqual), true},
+		{"array == 0", NewVarFromInterface([]int64{}).CompareWithint64(0, Equal), false},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s is %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestIdentical(t *testing.T) {
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"1 === 1", Identical(NewVarint64(1), NewVarint64(1)), true},
+		{"1 === 1.0", Identical(NewVarint64(1), NewVarfloat64(1)), false},
+		{`1 === "1"`, Identical(NewVarint64(1), NewVarstring("1")), false},
+		{"null === null", Identical(Var{}, Var{}), true},
+		{"null === false", Identical(Var{}, NewVarbool(false)), false},
+		{"[1] === [1]", Identical(NewVarFromInterface([]int64{1}), NewVarFromInterface([]int64{1})), true},
+		{"[1] === [2]", Identical(NewVarFromInterface([]int64{1}), NewVarFromInterface([]int64{2})), false},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s is %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
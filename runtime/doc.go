@@ -0,0 +1,10 @@
+// Package runtime implements the PHP semantics the code translated by
+// php2go relies on: the values of union types, the conversions of the
+// operands of operators, the arrays with keys, the generators and the
+// builtin functions like isset().
+//
+// The translated code imports the package with the dot import, so the
+// names are used without the qualifier. With the inline runtime option
+// the translator writes the package into the core file of the output
+// instead, so the output has no dependencies.
+package runtime
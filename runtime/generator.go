@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"runtime"
+)
+
+type generatorStop struct{}
+
+type Yielder struct {
+	body func(*Yielder) interface{}
+
+	started  bool
+	finished bool
+
+	key     interface{}
+	current interface{}
+	ret     interface{}
+	autoKey int64
+	failure interface{}
+
+	resume  chan interface{}
+	suspend chan struct{}
+}
+
+type Generator struct {
+	y *Yielder
+}
+
+func NewGenerator(body func(*Yielder) interface{}) *Generator {
+	g := &Generator{y: &Yielder{body: body}}
+	runtime.SetFinalizer(g, func(g *Generator) {
+		g.y.stop()
+	})
+	return g
+}
+
+func (y *Yielder) start() {
+	if y.started {
+		return
+	}
+
+	y.started = true
+	y.resume = make(chan interface{})
+	y.suspend = make(chan struct{})
+
+	go y.run()
+
+	<-y.suspend
+	y.rethrow()
+}
+
+func (y *Yielder) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(generatorStop); !ok {
+				y.failure = r
+			}
+		}
+
+		y.finished = true
+		y.key, y.current = nil, nil
+		close(y.suspend)
+	}()
+
+	y.ret = y.body(y)
+}
+
+func (y *Yielder) rethrow() {
+	if y.failure != nil {
+		failure := y.failure
+		y.failure = nil
+		panic(failure)
+	}
+}
+
+func (y *Yielder) resumeWith(val interface{}) {
+	y.resume <- val
+	<-y.suspend
+	y.rethrow()
+}
+
+func (y *Yielder) stop() {
+	if y.started && !y.finished {
+		y.resume <- generatorStop{}
+		<-y.suspend
+	}
+}
+
+func (y *Yielder) Yield(value interface{}) interface{} {
+	return y.YieldWithKey(y.autoKey, value)
+}
+
+func (y *Yielder) YieldWithKey(key interface{}, value interface{}) interface{} {
+	if k, ok := key.(int64); ok && k >= y.autoKey {
+		y.autoKey = k + 1
+	}
+
+	return y.yield(key, value)
+}
+
+func (y *Yielder) yield(key interface{}, value interface{}) interface{} {
+	y.key, y.current = key, value
+	y.suspend <- struct{}{}
+
+	val := <-y.resume
+	if _, ok := val.(generatorStop); ok {
+		panic(val)
+	}
+
+	return val
+}
+
+// YieldElement yields the element of the array delegated to by yield
+// from with its key, which does not change the keys of the values
+// yielded later, unlike YieldWithKey.
+func (y *Yielder) YieldElement(key interface{}, value interface{}) interface{} {
+	return y.yield(key, value)
+}
+
+// YieldFrom delegates to the inner generator, the values sent
+// to the outer generator are passed through to the inner one.
+func (y *Yielder) YieldFrom(g *Generator) interface{} {
+	inner := g.y
+	inner.start()
+
+	for !inner.finished {
+		inner.resumeWith(y.yield(inner.key, inner.current))
+	}
+
+	return inner.ret
+}
+
+func (g *Generator) Current() interface{} {
+	g.y.start()
+	return g.y.current
+}
+
+func (g *Generator) Key() interface{} {
+	g.y.start()
+	return g.y.key
+}
+
+func (g *Generator) Next() {
+	g.y.start()
+	if !g.y.finished {
+		g.y.resumeWith(nil)
+	}
+}
+
+func (g *Generator) Send(val interface{}) interface{} {
+	g.y.start()
+	if !g.y.finished {
+		g.y.resumeWith(val)
+	}
+	return g.y.current
+}
+
+func (g *Generator) Valid() bool {
+	g.y.start()
+	return !g.y.finished
+}
+
+func (g *Generator) Rewind() {
+	g.y.start()
+}
+
+func (g *Generator) GetReturn() interface{} {
+	return g.y.ret
+}
+
+// Close stops the generator that is not finished, so the goroutine
+// running its body exits without waiting for the finalizer.
+func (g *Generator) Close() {
+	g.y.stop()
+}
@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerator(t *testing.T) {
+	g := NewGenerator(func(y *Yielder) interface{} {
+		y.Yield(int64(1))
+		y.YieldWithKey("k", int64(2))
+		y.Yield(int64(3))
+		return "done"
+	})
+
+	var keys, values []interface{}
+	for ; g.Valid(); g.Next() {
+		keys = append(keys, g.Key())
+		values = append(values, g.Current())
+	}
+
+	if want := []interface{}{int64(0), "k", int64(1)}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys are %v, want %v", keys, want)
+	}
+	if want := []interface{}{int64(1), int64(2), int64(3)}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values are %v, want %v", values, want)
+	}
+	if r := g.GetReturn(); r != "done" {
+		t.Errorf("GetReturn() = %v, want done", r)
+	}
+}
+
+func TestGeneratorSend(t *testing.T) {
+	var received []interface{}
+	g := NewGenerator(func(y *Yielder) interface{} {
+		for i := int64(0); i < 2; i++ {
+			received = append(received, y.Yield(i))
+		}
+		return nil
+	})
+
+	g.Current()
+	if v := g.Send("a"); v != int64(1) {
+		t.Errorf(`Send("a") = %v, want 1`, v)
+	}
+	g.Send("b")
+
+	if want := []interface{}{"a", "b"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("received %v, want %v", received, want)
+	}
+	if g.Valid() {
+		t.Errorf("the generator is not finished")
+	}
+}
+
+func TestGeneratorYieldFrom(t *testing.T) {
+	inner := NewGenerator(func(y *Yielder) interface{} {
+		y.Yield("a")
+		y.Yield("b")
+		return "inner"
+	})
+	g := NewGenerator(func(y *Yielder) interface{} {
+		return y.YieldFrom(inner)
+	})
+
+	var values []interface{}
+	for ; g.Valid(); g.Next() {
+		values = append(values, g.Current())
+	}
+
+	if want := []interface{}{"a", "b"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("values are %v, want %v", values, want)
+	}
+	if r := g.GetReturn(); r != "inner" {
+		t.Errorf("GetReturn() = %v, want inner", r)
+	}
+}
+
+func TestGeneratorPanic(t *testing.T) {
+	g := NewGenerator(func(y *Yielder) interface{} {
+		panic("failure")
+	})
+
+	defer func() {
+		if r := recover(); r != "failure" {
+			t.Errorf("the generator panics with %v, want failure", r)
+		}
+	}()
+	g.Current()
+}
+
+func TestGeneratorYieldElement(t *testing.T) {
+	g := NewGenerator(func(y *Yielder) interface{} {
+		y.YieldElement(int64(0), int64(10))
+		y.YieldElement(int64(1), int64(20))
+		y.Yield(int64(30))
+		return nil
+	})
+
+	var keys []interface{}
+	for ; g.Valid(); g.Next() {
+		keys = append(keys, g.Key())
+	}
+
+	if want := []interface{}{int64(0), int64(1), int64(0)}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys are %v, want %v", keys, want)
+	}
+}
+
+func TestGeneratorClose(t *testing.T) {
+	stopped := false
+	g := NewGenerator(func(y *Yielder) interface{} {
+		defer func() { stopped = true }()
+		for i := int64(0); ; i++ {
+			y.Yield(i)
+		}
+	})
+
+	g.Current()
+	g.Close()
+
+	if !stopped {
+		t.Errorf("the body of the closed generator is not stopped")
+	}
+	if g.Valid() {
+		t.Errorf("the closed generator is valid")
+	}
+}
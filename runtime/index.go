@@ -0,0 +1,50 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+)
+
+// ArrayKeys is the policy for reads of the undefined array keys and of
+// the indexes out of range, the translated code passes the policy of
+// its package to the functions reading the elements.
+type ArrayKeys bool
+
+const (
+	// Lenient prints the warning to stderr and reads the zero value
+	// instead, which is null for the elements of union types, like PHP
+	// does.
+	Lenient ArrayKeys = false
+
+	// Strict panics.
+	Strict ArrayKeys = true
+)
+
+// UndefinedKey reports the read of the missing element by the key,
+// pos is the position of the read in the PHP source.
+func UndefinedKey(key interface{}, pos string, policy ArrayKeys) {
+	k := fmt.Sprint(key)
+	if s, ok := key.(string); ok {
+		k = "\"" + s + "\""
+	}
+
+	msg := "Undefined array key " + k + " in " + pos
+	if policy == Strict {
+		panic(msg)
+	}
+	fmt.Fprintln(os.Stderr, "Warning: "+msg)
+}
+
+// Fetch returns the element of the array stored in Var by the key.
+// The missing element is reported and zero is returned instead.
+func Fetch(arr interface{}, key interface{}, zero interface{}, pos string, policy ArrayKeys) interface{} {
+	val, ok := lookup(arr, key)
+	if !ok {
+		UndefinedKey(unwrapValue(key), pos, policy)
+		return zero
+	}
+	if val == nil {
+		return zero
+	}
+	return val
+}
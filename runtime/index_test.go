@@ -0,0 +1,21 @@
+package runtime
+
+import "testing"
+
+func TestFetch(t *testing.T) {
+	arr := NewVarFromInterface([]string{"a", "b"})
+	if v := Fetch(arr, int64(1), "", "test.php:1", Strict); v != "b" {
+		t.Errorf("Fetch(1) = %v, want b", v)
+	}
+
+	if v := Fetch(arr, int64(5), "", "test.php:2", Lenient); v != "" {
+		t.Errorf("Fetch(5) = %v, want the zero value", v)
+	}
+
+	defer func() {
+		if r := recover(); r != "Undefined array key 5 in test.php:3" {
+			t.Errorf("Fetch(5) panics with %v", r)
+		}
+	}()
+	Fetch(arr, int64(5), "", "test.php:3", Strict)
+}
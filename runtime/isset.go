@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"reflect"
+)
+
+// keyedArray is the array with keys, that is OrderedArray.
+type keyedArray interface {
+	Has(key interface{}) bool
+	Get(key interface{}) interface{}
+}
+
+// valueHolder is the value of union type, that is Var.
+type valueHolder interface {
+	Value() interface{}
+}
+
+func unwrapValue(val interface{}) interface{} {
+	if v, ok := val.(valueHolder); ok {
+		return v.Value()
+	}
+	return val
+}
+
+// lookup returns the element of the array val by the keys. The missing
+// element or intermediate array is reported instead of the panic.
+func lookup(val interface{}, keys ...interface{}) (interface{}, bool) {
+	for _, key := range keys {
+		val = unwrapValue(val)
+		key = unwrapValue(key)
+
+		if arr, ok := val.(keyedArray); ok {
+			if !arr.Has(key) {
+				return nil, false
+			}
+			val = arr.Get(key)
+			continue
+		}
+
+		v := reflect.ValueOf(val)
+		i, ok := key.(int64)
+		if !ok || v.Kind() != reflect.Slice || i < 0 || i >= int64(v.Len()) {
+			return nil, false
+		}
+		val = v.Index(int(i)).Interface()
+	}
+
+	return unwrapValue(val), true
+}
+
+// Isset reports whether the element of val by the keys exists and is
+// not null, like isset($a['x']['y']). Without keys val itself is checked.
+func Isset(val interface{}, keys ...interface{}) bool {
+	val, ok := lookup(val, keys...)
+	return ok && val != nil
+}
+
+// Empty reports whether the element of val by the keys is missing or
+// is falsy, that is "", "0", 0, 0.0, false, null or the empty array.
+func Empty(val interface{}, keys ...interface{}) bool {
+	val, ok := lookup(val, keys...)
+	if !ok {
+		return true
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return true
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case string:
+		return v == "" || v == "0"
+	case bool:
+		return !v
+	case interface{ Len() int }:
+		return v.Len() == 0
+	}
+
+	v := reflect.ValueOf(val)
+	return v.Kind() == reflect.Slice && v.Len() == 0
+}
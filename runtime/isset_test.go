@@ -0,0 +1,41 @@
+package runtime
+
+import "testing"
+
+func TestIssetAndEmpty(t *testing.T) {
+	a := NewOrderedArray(
+		Pair{Key: "zero", Value: int64(0)},
+		Pair{Key: "null", Value: nil},
+		Pair{Key: "list", Value: []int64{1}},
+		Pair{Key: "nested", Value: NewOrderedArray(Pair{Key: "s", Value: "0"})},
+	)
+
+	tests := []struct {
+		keys  []interface{}
+		isset bool
+		empty bool
+	}{
+		{[]interface{}{"zero"}, true, true},
+		{[]interface{}{"null"}, false, true},
+		{[]interface{}{"missing"}, false, true},
+		{[]interface{}{"list"}, true, false},
+		{[]interface{}{"list", int64(0)}, true, false},
+		{[]interface{}{"list", int64(1)}, false, true},
+		{[]interface{}{"nested", "s"}, true, true},
+		{[]interface{}{"nested", "s", "x"}, false, true},
+		{[]interface{}{NewVarstring("zero")}, true, true},
+	}
+
+	for _, tt := range tests {
+		if isset := Isset(a, tt.keys...); isset != tt.isset {
+			t.Errorf("Isset(%v) = %v, want %v", tt.keys, isset, tt.isset)
+		}
+		if empty := Empty(a, tt.keys...); empty != tt.empty {
+			t.Errorf("Empty(%v) = %v, want %v", tt.keys, empty, tt.empty)
+		}
+	}
+
+	if Isset(Var{}) || !Isset(NewVarint64(0)) || !Empty([]string{}) {
+		t.Errorf("the values without keys are checked wrong")
+	}
+}
@@ -0,0 +1,408 @@
+package runtime
+
+import (
+	"fmt"
+)
+
+// Nullbool is the bool value that can be null, the zero value is null.
+type Nullbool struct {
+	Val   bool
+	Valid bool
+}
+
+func NewNullbool() Nullbool {
+	return Nullbool{}
+}
+
+func NewNullboolFromInterface(val interface{}) Nullbool {
+	switch val := val.(type) {
+	case bool:
+		return Nullbool{Val: val, Valid: true}
+	case Nullbool:
+		return val
+	case interface{ Value() interface{} }:
+		return NewNullboolFromInterface(val.Value())
+	}
+	return Nullbool{}
+}
+
+func (v Nullbool) IsNull() bool {
+	return !v.Valid
+}
+
+// Value returns the underlying value, nil for null.
+func (v Nullbool) Value() interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Val
+}
+
+func (v Nullbool) Getbool() bool {
+	return v.Val
+}
+
+func (v *Nullbool) Setbool(val bool) {
+	v.Val = val
+	v.Valid = true
+}
+
+func (v Nullbool) Getnull() interface{} {
+	return nil
+}
+
+// Setnull makes the value null, val is always nil.
+func (v *Nullbool) Setnull(val interface{}) {
+	*v = Nullbool{}
+}
+
+// String converts the value like echo does, null is the empty string.
+func (v Nullbool) String() string {
+	if !v.Valid {
+		return ""
+	}
+	if v.Val {
+		return "1"
+	}
+	return ""
+}
+
+func (v Nullbool) Bool() bool {
+	return v.Valid && v.Val
+}
+
+// CompareWithbool compares the value with val, null is
+// compared as the zero value of the type, like PHP does.
+func (v Nullbool) CompareWithbool(val bool, compare CompareType) bool {
+	switch compare {
+	case Equal:
+		return v.Val == val
+	case NotEqual:
+		return v.Val != val
+	case Greater:
+		return v.Val && !val
+	case GreaterEqual:
+		return v.Val || !val
+	case Smaller:
+		return !v.Val && val
+	case SmallerEqual:
+		return !v.Val || val
+	}
+
+	return false
+}
+
+// CompareWithnull compares the value with null,
+// which is compared as false, like PHP does.
+func (v Nullbool) CompareWithnull(val interface{}, compare CompareType) bool {
+	switch compare {
+	case Equal, SmallerEqual:
+		return !v.Bool()
+	case NotEqual, Greater:
+		return v.Bool()
+	case GreaterEqual:
+		return true
+	}
+
+	return false
+}
+
+// Nullfloat64 is the float64 value that can be null, the zero value is null.
+type Nullfloat64 struct {
+	Val   float64
+	Valid bool
+}
+
+func NewNullfloat64() Nullfloat64 {
+	return Nullfloat64{}
+}
+
+func NewNullfloat64FromInterface(val interface{}) Nullfloat64 {
+	switch val := val.(type) {
+	case float64:
+		return Nullfloat64{Val: val, Valid: true}
+	case Nullfloat64:
+		return val
+	case interface{ Value() interface{} }:
+		return NewNullfloat64FromInterface(val.Value())
+	}
+	return Nullfloat64{}
+}
+
+func (v Nullfloat64) IsNull() bool {
+	return !v.Valid
+}
+
+// Value returns the underlying value, nil for null.
+func (v Nullfloat64) Value() interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Val
+}
+
+func (v Nullfloat64) Getfloat64() float64 {
+	return v.Val
+}
+
+func (v *Nullfloat64) Setfloat64(val float64) {
+	v.Val = val
+	v.Valid = true
+}
+
+func (v Nullfloat64) Getnull() interface{} {
+	return nil
+}
+
+// Setnull makes the value null, val is always nil.
+func (v *Nullfloat64) Setnull(val interface{}) {
+	*v = Nullfloat64{}
+}
+
+// String converts the value like echo does, null is the empty string.
+func (v Nullfloat64) String() string {
+	if !v.Valid {
+		return ""
+	}
+	return fmt.Sprint(v.Val)
+}
+
+func (v Nullfloat64) Bool() bool {
+	return v.Valid && v.Val != 0
+}
+
+// CompareWithfloat64 compares the value with val, null is
+// compared as the zero value of the type, like PHP does.
+func (v Nullfloat64) CompareWithfloat64(val float64, compare CompareType) bool {
+	switch compare {
+	case Equal:
+		return v.Val == val
+	case NotEqual:
+		return v.Val != val
+	case Greater:
+		return v.Val > val
+	case GreaterEqual:
+		return v.Val >= val
+	case Smaller:
+		return v.Val < val
+	case SmallerEqual:
+		return v.Val <= val
+	}
+
+	return false
+}
+
+// CompareWithnull compares the value with null,
+// which is compared as false, like PHP does.
+func (v Nullfloat64) CompareWithnull(val interface{}, compare CompareType) bool {
+	switch compare {
+	case Equal, SmallerEqual:
+		return !v.Bool()
+	case NotEqual, Greater:
+		return v.Bool()
+	case GreaterEqual:
+		return true
+	}
+
+	return false
+}
+
+// Nullint64 is the int64 value that can be null, the zero value is null.
+type Nullint64 struct {
+	Val   int64
+	Valid bool
+}
+
+func NewNullint64() Nullint64 {
+	return Nullint64{}
+}
+
+func NewNullint64FromInterface(val interface{}) Nullint64 {
+	switch val := val.(type) {
+	case int64:
+		return Nullint64{Val: val, Valid: true}
+	case Nullint64:
+		return val
+	case interface{ Value() interface{} }:
+		return NewNullint64FromInterface(val.Value())
+	}
+	return Nullint64{}
+}
+
+func (v Nullint64) IsNull() bool {
+	return !v.Valid
+}
+
+// Value returns the underlying value, nil for null.
+func (v Nullint64) Value() interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Val
+}
+
+func (v Nullint64) Getint64() int64 {
+	return v.Val
+}
+
+func (v *Nullint64) Setint64(val int64) {
+	v.Val = val
+	v.Valid = true
+}
+
+func (v Nullint64) Getnull() interface{} {
+	return nil
+}
+
+// Setnull makes the value null, val is always nil.
+func (v *Nullint64) Setnull(val interface{}) {
+	*v = Nullint64{}
+}
+
+// String converts the value like echo does, null is the empty string.
+func (v Nullint64) String() string {
+	if !v.Valid {
+		return ""
+	}
+	return fmt.Sprint(v.Val)
+}
+
+func (v Nullint64) Bool() bool {
+	return v.Valid && v.Val != 0
+}
+
+// CompareWithint64 compares the value with val, null is
+// compared as the zero value of the type, like PHP does.
+func (v Nullint64) CompareWithint64(val int64, compare CompareType) bool {
+	switch compare {
+	case Equal:
+		return v.Val == val
+	case NotEqual:
+		return v.Val != val
+	case Greater:
+		return v.Val > val
+	case GreaterEqual:
+		return v.Val >= val
+	case Smaller:
+		return v.Val < val
+	case SmallerEqual:
+		return v.Val <= val
+	}
+
+	return false
+}
+
+// CompareWithnull compares the value with null,
+// which is compared as false, like PHP does.
+func (v Nullint64) CompareWithnull(val interface{}, compare CompareType) bool {
+	switch compare {
+	case Equal, SmallerEqual:
+		return !v.Bool()
+	case NotEqual, Greater:
+		return v.Bool()
+	case GreaterEqual:
+		return true
+	}
+
+	return false
+}
+
+// Nullstring is the string value that can be null, the zero value is null.
+type Nullstring struct {
+	Val   string
+	Valid bool
+}
+
+func NewNullstring() Nullstring {
+	return Nullstring{}
+}
+
+func NewNullstringFromInterface(val interface{}) Nullstring {
+	switch val := val.(type) {
+	case string:
+		return Nullstring{Val: val, Valid: true}
+	case Nullstring:
+		return val
+	case interface{ Value() interface{} }:
+		return NewNullstringFromInterface(val.Value())
+	}
+	return Nullstring{}
+}
+
+func (v Nullstring) IsNull() bool {
+	return !v.Valid
+}
+
+// Value returns the underlying value, nil for null.
+func (v Nullstring) Value() interface{} {
+	if !v.Valid {
+		return nil
+	}
+	return v.Val
+}
+
+func (v Nullstring) Getstring() string {
+	return v.Val
+}
+
+func (v *Nullstring) Setstring(val string) {
+	v.Val = val
+	v.Valid = true
+}
+
+func (v Nullstring) Getnull() interface{} {
+	return nil
+}
+
+// Setnull makes the value null, val is always nil.
+func (v *Nullstring) Setnull(val interface{}) {
+	*v = Nullstring{}
+}
+
+// String converts the value like echo does, null is the empty string.
+func (v Nullstring) String() string {
+	if !v.Valid {
+		return ""
+	}
+	return fmt.Sprint(v.Val)
+}
+
+func (v Nullstring) Bool() bool {
+	return v.Valid && v.Val != "" && v.Val != "0"
+}
+
+// CompareWithstring compares the value with val, null is
+// compared as the zero value of the type, like PHP does.
+func (v Nullstring) CompareWithstring(val string, compare CompareType) bool {
+	switch compare {
+	case Equal:
+		return v.Val == val
+	case NotEqual:
+		return v.Val != val
+	case Greater:
+		return v.Val > val
+	case GreaterEqual:
+		return v.Val >= val
+	case Smaller:
+		return v.Val < val
+	case SmallerEqual:
+		return v.Val <= val
+	}
+
+	return false
+}
+
+// CompareWithnull compares the value with null,
+// which is compared as false, like PHP does.
+func (v Nullstring) CompareWithnull(val interface{}, compare CompareType) bool {
+	switch compare {
+	case Equal, SmallerEqual:
+		return !v.Bool()
+	case NotEqual, Greater:
+		return v.Bool()
+	case GreaterEqual:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,35 @@
+package runtime
+
+import "testing"
+
+func TestNullable(t *testing.T) {
+	n := NewNullint64()
+	if !n.IsNull() || n.Value() != nil || n.String() != "" || n.Bool() {
+		t.Fatalf("zero Nullint64 is %v, want null", n.Value())
+	}
+
+	n.Setint64(5)
+	if n.IsNull() || n.Getint64() != 5 || n.String() != "5" || !n.Bool() {
+		t.Errorf("Setint64(5) gives %v", n.Value())
+	}
+	if !n.CompareWithint64(3, Greater) || n.CompareWithnull(nil, Equal) {
+		t.Errorf("5 is compared wrong")
+	}
+
+	n.Setnull(nil)
+	if !n.CompareWithint64(0, Equal) || !n.CompareWithnull(nil, Equal) {
+		t.Errorf("null is compared wrong")
+	}
+
+	if s := NewNullstringFromInterface(NewVarstring("a")); s.IsNull() || s.Getstring() != "a" {
+		t.Errorf("Nullstring from Var is %v", s.Value())
+	}
+	if s := NewNullstringFromInterface(nil); !s.IsNull() {
+		t.Errorf("Nullstring from nil is %v", s.Value())
+	}
+
+	b := NewNullboolFromInterface(true)
+	if !b.CompareWithbool(false, Greater) || b.String() != "1" {
+		t.Errorf("true is compared or converted wrong")
+	}
+}
@@ -0,0 +1,270 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// typeName returns the PHP name of the type of the value.
+func typeName(v Var) string {
+	switch v.Type {
+	case Constantint64:
+		return "int"
+	case Constantfloat64:
+		return "float"
+	case Constantstring:
+		return "string"
+	case Constantbool:
+		return "bool"
+	case Constantnull:
+		return "null"
+	case ConstantGenerator:
+		return "Generator"
+	}
+	return "array"
+}
+
+// numericPrefix returns the longest prefix of s, without the leading
+// whitespace, which is a number, and whether it is the whole string.
+func numericPrefix(s string) (string, bool) {
+	s = strings.TrimLeft(s, " \t\n\r\v\f")
+
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return "", false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			i = j
+		}
+	}
+
+	return s[:i], strings.TrimRight(s[i:], " \t\n\r\v\f") == ""
+}
+
+// parseNumber converts the number to int64 if it is an integer
+// which fits, otherwise to float64.
+func parseNumber(s string) Var {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return NewVarint64(n)
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return NewVarfloat64(f)
+}
+
+// numericString returns the number of the numeric string.
+func numericString(s string) (Var, bool) {
+	prefix, whole := numericPrefix(s)
+	if !whole {
+		return Var{}, false
+	}
+	return parseNumber(prefix), true
+}
+
+// toNumber converts the operand of the arithmetic operator to int64
+// or float64. Strings with a numeric prefix give a warning, other
+// strings and arrays are unsupported.
+func toNumber(v Var, a, b Var, op string) Var {
+	switch v.Type {
+	case Constantint64, Constantfloat64:
+		return v
+	case Constantbool:
+		if v.b {
+			return NewVarint64(1)
+		}
+		return NewVarint64(0)
+	case Constantnull:
+		return NewVarint64(0)
+	case Constantstring:
+		prefix, whole := numericPrefix(v.s)
+		if whole {
+			return parseNumber(prefix)
+		}
+		if prefix != "" {
+			fmt.Fprintln(os.Stderr, "Warning: A non-numeric value encountered")
+			return parseNumber(prefix)
+		}
+	}
+
+	panic("Unsupported operand types: " + typeName(a) + " " + op + " " + typeName(b))
+}
+
+func (v Var) float() float64 {
+	if v.Type == Constantint64 {
+		return float64(v.i)
+	}
+	return v.f
+}
+
+// floats returns the numbers converted to float64, ok is false if any
+// of the operands is not a number.
+func floats(a, b Var) (x, y float64, ok bool) {
+	switch {
+	case a.Type == Constantfloat64 && b.Type == Constantfloat64:
+		return a.f, b.f, true
+	case a.Type == Constantfloat64 && b.Type == Constantint64:
+		return a.f, float64(b.i), true
+	case a.Type == Constantint64 && b.Type == Constantfloat64:
+		return float64(a.i), b.f, true
+	}
+	return 0, 0, false
+}
+
+// Add returns a + b, the result is int64 if both operands
+// are integers, otherwise float64. The numbers are added
+// first, since they need no conversion.
+func Add(a, b Var) Var {
+	if a.Type == Constantint64 && b.Type == Constantint64 {
+		return NewVarint64(a.i + b.i)
+	}
+	if x, y, ok := floats(a, b); ok {
+		return NewVarfloat64(x + y)
+	}
+
+	x, y := toNumber(a, a, b, "+"), toNumber(b, a, b, "+")
+	if x.Type == Constantint64 && y.Type == Constantint64 {
+		return NewVarint64(x.i + y.i)
+	}
+	return NewVarfloat64(x.float() + y.float())
+}
+
+// Sub returns a - b.
+func Sub(a, b Var) Var {
+	if a.Type == Constantint64 && b.Type == Constantint64 {
+		return NewVarint64(a.i - b.i)
+	}
+	if x, y, ok := floats(a, b); ok {
+		return NewVarfloat64(x - y)
+	}
+
+	x, y := toNumber(a, a, b, "-"), toNumber(b, a, b, "-")
+	if x.Type == Constantint64 && y.Type == Constantint64 {
+		return NewVarint64(x.i - y.i)
+	}
+	return NewVarfloat64(x.float() - y.float())
+}
+
+// Mul returns a * b.
+func Mul(a, b Var) Var {
+	if a.Type == Constantint64 && b.Type == Constantint64 {
+		return NewVarint64(a.i * b.i)
+	}
+	if x, y, ok := floats(a, b); ok {
+		return NewVarfloat64(x * y)
+	}
+
+	x, y := toNumber(a, a, b, "*"), toNumber(b, a, b, "*")
+	if x.Type == Constantint64 && y.Type == Constantint64 {
+		return NewVarint64(x.i * y.i)
+	}
+	return NewVarfloat64(x.float() * y.float())
+}
+
+// Div returns a / b, the result is int64 only if both operands
+// are integers and a is divisible by b.
+func Div(a, b Var) Var {
+	x, y := toNumber(a, a, b, "/"), toNumber(b, a, b, "/")
+	if y.float() == 0 {
+		panic("Division by zero")
+	}
+	if x.Type == Constantint64 && y.Type == Constantint64 && x.i%y.i == 0 {
+		return NewVarint64(x.i / y.i)
+	}
+	return NewVarfloat64(x.float() / y.float())
+}
+
+// Concat returns the concatenation of the values converted to strings.
+func Concat(a, b Var) string {
+	return a.String() + b.String()
+}
+
+func compareNumbers(a, b Var) int {
+	if a.Type == Constantint64 && b.Type == Constantint64 {
+		x, y := a.i, b.i
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	x, y := a.float(), b.float()
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
+// Compare returns -1, 0 or 1 if a is less than, equal to or greater
+// than b, like the <=> operator. Null and bools are compared as bools,
+// except null and strings, numeric strings are compared as numbers.
+func Compare(a, b Var) int {
+	switch {
+	case a.Type == Constantnull && b.Type == Constantstring:
+		return strings.Compare("", b.s)
+	case a.Type == Constantstring && b.Type == Constantnull:
+		return strings.Compare(a.s, "")
+	case a.Type == Constantbool || b.Type == Constantbool || a.Type == Constantnull || b.Type == Constantnull:
+		return compareBools(a.Bool(), b.Bool())
+
+	case a.Type == Constantstring && b.Type == Constantstring:
+		x, okx := numericString(a.s)
+		y, oky := numericString(b.s)
+		if okx && oky {
+			return compareNumbers(x, y)
+		}
+		return strings.Compare(a.s, b.s)
+
+	case a.Type == Constantstring:
+		if x, ok := numericString(a.s); ok {
+			return compareNumbers(x, b)
+		}
+		return strings.Compare(a.s, b.String())
+	case b.Type == Constantstring:
+		if y, ok := numericString(b.s); ok {
+			return compareNumbers(a, y)
+		}
+		return strings.Compare(a.String(), b.s)
+	}
+
+	return compareNumbers(a, b)
+}
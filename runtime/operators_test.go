@@ -0,0 +1,105 @@
+package runtime
+
+import "testing"
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Var
+		want interface{}
+	}{
+		{"1 + 2", Add(NewVarint64(1), NewVarint64(2)), int64(3)},
+		{"1 + 0.5", Add(NewVarint64(1), NewVarfloat64(0.5)), 1.5},
+		{`"5" + 1`, Add(NewVarstring("5"), NewVarint64(1)), int64(6)},
+		{`"1.5" + 1`, Add(NewVarstring("1.5"), NewVarint64(1)), 2.5},
+		{"true + null", Add(NewVarbool(true), Var{}), int64(1)},
+		{"5 - 7", Sub(NewVarint64(5), NewVarint64(7)), int64(-2)},
+		{"2 * 2.5", Mul(NewVarint64(2), NewVarfloat64(2.5)), 5.0},
+		{"6 / 3", Div(NewVarint64(6), NewVarint64(3)), int64(2)},
+		{"7 / 2", Div(NewVarint64(7), NewVarint64(2)), 3.5},
+	}
+
+	for _, tt := range tests {
+		if v := tt.got.Value(); v != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.name, v, tt.want)
+		}
+	}
+}
+
+func TestArithmeticPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func()
+		want string
+	}{
+		{"1 / 0", func() { Div(NewVarint64(1), NewVarint64(0)) }, "Division by zero"},
+		{`"a" + 1`, func() { Add(NewVarstring("a"), NewVarint64(1)) }, "Unsupported operand types: string + int"},
+		{"array * 1", func() { Mul(NewVarFromInterface([]int64{}), NewVarint64(1)) }, "Unsupported operand types: array * int"},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != tt.want {
+					t.Errorf("%s panics with %v, want %q", tt.name, r, tt.want)
+				}
+			}()
+			tt.op()
+		}()
+	}
+}
+
+func TestConcat(t *testing.T) {
+	if s := Concat(NewVarint64(1), NewVarstring("a")); s != "1a" {
+		t.Errorf(`1 . "a" = %q, want "1a"`, s)
+	}
+	if s := Concat(NewVarbool(false), NewVarfloat64(0.5)); s != "0.5" {
+		t.Errorf(`false . 0.5 = %q, want "0.5"`, s)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Var
+		want int
+	}{
+		{"1 <=> 2", NewVarint64(1), NewVarint64(2), -1},
+		{"2.5 <=> 2", NewVarfloat64(2.5), NewVarint64(2), 1},
+		{`"10" <=> "9"`, NewVarstring("10"), NewVarstring("9"), 1},
+		{`"abc" <=> "abd"`, NewVarstring("abc"), NewVarstring("abd"), -1},
+		{`"1e1" <=> 10`, NewVarstring("1e1"), NewVarint64(10), 0},
+		{`null <=> ""`, Var{}, NewVarstring(""), 0},
+		{"null <=> false", Var{}, NewVarbool(false), 0},
+		{"true <=> 5", NewVarbool(true), NewVarint64(5), 0},
+	}
+
+	for _, tt := range tests {
+		if c := Compare(tt.a, tt.b); c != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, c, tt.want)
+		}
+	}
+}
+
+func TestNumericPrefix(t *testing.T) {
+	tests := []struct {
+		s      string
+		prefix string
+		whole  bool
+	}{
+		{"12", "12", true},
+		{" 1.5 ", "1.5", true},
+		{"-3e2", "-3e2", true},
+		{"12abc", "12", false},
+		{"1e", "1", false},
+		{"abc", "", false},
+		{".", "", false},
+	}
+
+	for _, tt := range tests {
+		prefix, whole := numericPrefix(tt.s)
+		if prefix != tt.prefix || whole != tt.whole {
+			t.Errorf("numericPrefix(%q) = %q, %v, want %q, %v", tt.s, prefix, whole, tt.prefix, tt.whole)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// OrderedArray is the PHP array with arbitrary int and string keys,
+// the iteration order is the order in which the keys were added.
+type OrderedArray struct {
+	keys      []interface{}
+	values    map[interface{}]interface{}
+	nextIndex int64
+}
+
+// Pair is the key and the value of the OrderedArray element,
+// in NewOrderedArray the pair with nil key gets the next index.
+type Pair struct {
+	Key   interface{}
+	Value interface{}
+}
+
+func NewOrderedArray(pairs ...Pair) *OrderedArray {
+	a := &OrderedArray{values: make(map[interface{}]interface{}, len(pairs))}
+
+	for _, p := range pairs {
+		if p.Key == nil {
+			a.Append(p.Value)
+			continue
+		}
+		a.Set(p.Key, p.Value)
+	}
+
+	return a
+}
+
+// ArrayKey converts the key the same way PHP does: strings containing
+// decimal integers become integers, floats are truncated, bools become
+// 0 and 1, and null becomes the empty string.
+func ArrayKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case int64:
+		return k
+	case int:
+		return int64(k)
+	case string:
+		n, err := strconv.ParseInt(k, 10, 64)
+		if err == nil && strconv.FormatInt(n, 10) == k {
+			return n
+		}
+		return k
+	case float64:
+		return int64(k)
+	case bool:
+		if k {
+			return int64(1)
+		}
+		return int64(0)
+	case nil:
+		return ""
+	}
+
+	panic(fmt.Sprintf("illegal offset type %T", key))
+}
+
+func (a *OrderedArray) Set(key interface{}, val interface{}) {
+	k := ArrayKey(key)
+
+	if _, ok := a.values[k]; !ok {
+		a.keys = append(a.keys, k)
+
+		if i, ok := k.(int64); ok && i >= a.nextIndex {
+			a.nextIndex = i + 1
+		}
+	}
+
+	a.values[k] = val
+}
+
+// Append adds the value with the key that is greater
+// by one than the largest integer key, like $a[] = $val.
+func (a *OrderedArray) Append(val interface{}) {
+	a.Set(a.nextIndex, val)
+}
+
+func (a *OrderedArray) Get(key interface{}) interface{} {
+	return a.values[ArrayKey(key)]
+}
+
+// Fetch returns the element by the key, like $a[$key]. The missing
+// element is reported by UndefinedKey and zero is returned instead.
+func (a *OrderedArray) Fetch(key interface{}, zero interface{}, pos string, policy ArrayKeys) interface{} {
+	k := ArrayKey(key)
+
+	if a != nil {
+		if val, ok := a.values[k]; ok {
+			if val == nil {
+				return zero
+			}
+			return val
+		}
+	}
+
+	UndefinedKey(k, pos, policy)
+	return zero
+}
+
+// Nested returns the array stored by the key. If there is no array,
+// the new one is stored, like PHP does for writes like $a['x']['y'] = 1.
+func (a *OrderedArray) Nested(key interface{}) *OrderedArray {
+	if inner, ok := a.Get(key).(*OrderedArray); ok {
+		return inner
+	}
+
+	inner := NewOrderedArray()
+	a.Set(key, inner)
+	return inner
+}
+
+// Delete removes the element by the key, like unset($a[$key]).
+// The next index is not changed, like in PHP.
+func (a *OrderedArray) Delete(key interface{}) {
+	k := ArrayKey(key)
+	if _, ok := a.values[k]; !ok {
+		return
+	}
+
+	delete(a.values, k)
+	for i, kk := range a.keys {
+		if kk == k {
+			a.keys = append(a.keys[:i], a.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (a *OrderedArray) Has(key interface{}) bool {
+	_, ok := a.values[ArrayKey(key)]
+	return ok
+}
+
+func (a *OrderedArray) Len() int {
+	return len(a.keys)
+}
+
+// CopyArray returns the copy of the array, nested arrays are copied too.
+func (a *OrderedArray) CopyArray() interface{} {
+	c := &OrderedArray{
+		keys:      append([]interface{}(nil), a.keys...),
+		values:    make(map[interface{}]interface{}, len(a.values)),
+		nextIndex: a.nextIndex,
+	}
+
+	for k, v := range a.values {
+		c.values[k] = CopyArray(v)
+	}
+
+	return c
+}
+
+// Entries returns the elements in order, the result does
+// not change if the array is modified during the iteration.
+func (a *OrderedArray) Entries() []Pair {
+	entries := make([]Pair, 0, len(a.keys))
+	for _, k := range a.keys {
+		entries = append(entries, Pair{Key: k, Value: a.values[k]})
+	}
+	return entries
+}
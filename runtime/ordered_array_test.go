@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArrayKey(t *testing.T) {
+	tests := []struct {
+		key  interface{}
+		want interface{}
+	}{
+		{int64(1), int64(1)},
+		{"1", int64(1)},
+		{"01", "01"},
+		{"-5", int64(-5)},
+		{"a", "a"},
+		{1.7, int64(1)},
+		{true, int64(1)},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if k := ArrayKey(tt.key); k != tt.want {
+			t.Errorf("ArrayKey(%#v) = %#v, want %#v", tt.key, k, tt.want)
+		}
+	}
+}
+
+func TestOrderedArray(t *testing.T) {
+	a := NewOrderedArray(
+		Pair{Key: "b", Value: int64(1)},
+		Pair{Value: "first"},
+		Pair{Key: int64(5), Value: "five"},
+	)
+	a.Append("six")
+	a.Set("1", "one")
+
+	want := []Pair{
+		{Key: "b", Value: int64(1)},
+		{Key: int64(0), Value: "first"},
+		{Key: int64(5), Value: "five"},
+		{Key: int64(6), Value: "six"},
+		{Key: int64(1), Value: "one"},
+	}
+	if entries := a.Entries(); !reflect.DeepEqual(entries, want) {
+		t.Fatalf("Entries() = %v, want %v", entries, want)
+	}
+
+	a.Delete(int64(6))
+	a.Append("seven")
+	if !a.Has(int64(7)) || a.Has(int64(6)) || a.Len() != 5 {
+		t.Errorf("Delete changes the next index or the length")
+	}
+
+	a.Nested("x").Set("y", int64(1))
+	if a.Get("x").(*OrderedArray).Get("y") != int64(1) {
+		t.Errorf("Nested does not store the array")
+	}
+}
+
+func TestOrderedArrayFetch(t *testing.T) {
+	a := NewOrderedArray(Pair{Key: "a", Value: int64(1)})
+	if v := a.Fetch("a", int64(0), "test.php:1", Strict); v != int64(1) {
+		t.Errorf(`Fetch("a") = %v, want 1`, v)
+	}
+
+	defer func() {
+		if r := recover(); r != `Undefined array key "b" in test.php:2` {
+			t.Errorf("Fetch of the missing key panics with %v", r)
+		}
+	}()
+	a.Fetch("b", int64(0), "test.php:2", Strict)
+}
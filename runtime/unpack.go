@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"reflect"
+)
+
+// Vars returns the elements of the spread array, like ...$a,
+// as Var, so they can be appended to the slice of Var.
+func Vars(arr interface{}) []Var {
+	values := unpackValues(arr)
+	res := make([]Var, 0, len(values))
+	for _, val := range values {
+		res = append(res, NewVarFromInterface(val))
+	}
+	return res
+}
+
+// Unpack returns the elements of the spread array as the pairs
+// with nil keys, so NewOrderedArray appends them with the next
+// indexes, like PHP does for the spread arrays with int keys.
+func Unpack(arr interface{}) []Pair {
+	values := unpackValues(arr)
+	res := make([]Pair, 0, len(values))
+	for _, val := range values {
+		if v, ok := val.(interface{ Value() interface{} }); ok {
+			val = v.Value()
+		}
+		res = append(res, Pair{Value: val})
+	}
+	return res
+}
+
+// unpackValues returns the elements of the slice, the OrderedArray
+// or the array stored in Var in order.
+func unpackValues(arr interface{}) []interface{} {
+	switch arr := arr.(type) {
+	case nil:
+		return nil
+	case Var:
+		return unpackValues(arr.Value())
+	case *OrderedArray:
+		var res []interface{}
+		for _, e := range arr.Entries() {
+			res = append(res, e.Value)
+		}
+		return res
+	}
+
+	v := reflect.ValueOf(arr)
+	if v.Kind() != reflect.Slice {
+		panic("only arrays can be unpacked")
+	}
+
+	res := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		res = append(res, v.Index(i).Interface())
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+package runtime
+
+import "testing"
+
+func TestVars(t *testing.T) {
+	vars := Vars([]int64{1, 2})
+	if len(vars) != 2 || vars[0].Getint64() != 1 || vars[1].Getint64() != 2 {
+		t.Errorf("Vars([]int64{1, 2}) = %v", vars)
+	}
+
+	vars = Vars(NewVarFromInterface([]string{"a"}))
+	if len(vars) != 1 || vars[0].Getstring() != "a" {
+		t.Errorf("the slice in Var is not unpacked: %v", vars)
+	}
+
+	if len(Vars(nil)) != 0 {
+		t.Errorf("null is not empty")
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	a := NewOrderedArray(append([]Pair{{Key: "k", Value: int64(1)}}, Unpack([]Var{NewVarint64(3), NewVarstring("x")})...)...)
+
+	entries := a.Entries()
+	want := []Pair{{"k", int64(1)}, {int64(0), int64(3)}, {int64(1), "x"}}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %v, want %v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %v, want %v", i, entries[i], want[i])
+		}
+	}
+
+	b := NewOrderedArray(Unpack(NewOrderedArray(Pair{Key: int64(5), Value: true}))...)
+	if b.Get(int64(0)) != true {
+		t.Errorf("the keys of the unpacked array are kept")
+	}
+}
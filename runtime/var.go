@@ -0,0 +1,211 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ValueType is the type of the value stored in Var.
+type ValueType uint8
+
+const (
+	// Constantnull is the first, so the zero Var is null.
+	Constantnull ValueType = iota
+	Constantbool
+	Constantfloat64
+	Constantint64
+	Constantstring
+	// Constantarray is the type of slices, maps and OrderedArray.
+	Constantarray
+	ConstantGenerator
+)
+
+// Var is the value of a union type. The values of the scalar types are
+// stored in their own fields, so they are read and written without
+// allocations and type assertions, Type tells which field is set.
+type Var struct {
+	i    int64
+	f    float64
+	s    string
+	b    bool
+	Type ValueType
+
+	// other holds the values of the other types, like arrays.
+	other interface{}
+}
+
+func NewVar() Var {
+	return Var{}
+}
+
+func NewVarbool(val bool) Var {
+	var v Var
+	v.Setbool(val)
+	return v
+}
+
+func NewVarfloat64(val float64) Var {
+	var v Var
+	v.Setfloat64(val)
+	return v
+}
+
+func NewVarint64(val int64) Var {
+	var v Var
+	v.Setint64(val)
+	return v
+}
+
+func NewVarstring(val string) Var {
+	var v Var
+	v.Setstring(val)
+	return v
+}
+
+// NewVarFromInterface converts the value to Var, the values of Var
+// and of the nullable types are unwrapped.
+func NewVarFromInterface(val interface{}) Var {
+	var v Var
+	switch val := val.(type) {
+	case nil:
+	case Var:
+		return val
+	case bool:
+		v.Setbool(val)
+	case float64:
+		v.Setfloat64(val)
+	case int64:
+		v.Setint64(val)
+	case string:
+		v.Setstring(val)
+	case *Generator:
+		v.other, v.Type = val, ConstantGenerator
+	case *OrderedArray:
+		v.other, v.Type = val, Constantarray
+	case interface{ Value() interface{} }:
+		return NewVarFromInterface(val.Value())
+	default:
+		switch reflect.TypeOf(val).Kind() {
+		case reflect.Slice, reflect.Map:
+			v.other, v.Type = val, Constantarray
+		}
+	}
+	return v
+}
+
+func (v Var) IsNull() bool {
+	return v.Type == Constantnull
+}
+
+// Value returns the underlying value, nil for null.
+func (v Var) Value() interface{} {
+	switch v.Type {
+	case Constantnull:
+		return nil
+	case Constantint64:
+		return v.i
+	case Constantfloat64:
+		return v.f
+	case Constantstring:
+		return v.s
+	case Constantbool:
+		return v.b
+	}
+	return v.other
+}
+
+// Set stores the value of any type, the values of the scalar
+// types are stored by the typed setters, like Setint64.
+func (v *Var) Set(val interface{}) {
+	*v = NewVarFromInterface(val)
+}
+
+func (v Var) Getbool() bool {
+	return v.b
+}
+
+func (v Var) Getfloat64() float64 {
+	return v.f
+}
+
+func (v Var) Getint64() int64 {
+	return v.i
+}
+
+func (v Var) Getstring() string {
+	return v.s
+}
+
+func (v Var) Getnull() interface{} {
+	return nil
+}
+
+func (v *Var) Setbool(val bool) {
+	v.b = val
+	v.Type = Constantbool
+}
+
+func (v *Var) Setfloat64(val float64) {
+	v.f = val
+	v.Type = Constantfloat64
+}
+
+func (v *Var) Setint64(val int64) {
+	v.i = val
+	v.Type = Constantint64
+}
+
+func (v *Var) Setstring(val string) {
+	v.s = val
+	v.Type = Constantstring
+}
+
+// Setnull makes the value null, val is always nil.
+func (v *Var) Setnull(val interface{}) {
+	*v = Var{}
+}
+
+// CopyArray returns the copy of the value, the array stored in it
+// is copied, so Var behaves like the PHP value in CopyArray.
+func (v Var) CopyArray() interface{} {
+	if v.other != nil {
+		v.other = CopyArray(v.other)
+	}
+	return v
+}
+
+// Bool converts the value like PHP does in conditions.
+func (v Var) Bool() bool {
+	switch v.Type {
+	case Constantbool:
+		return v.b
+	case Constantfloat64:
+		return v.f != 0
+	case Constantint64:
+		return v.i != 0
+	case Constantstring:
+		return v.s != "" && v.s != "0"
+	}
+
+	return false
+}
+
+// String converts the value like echo does.
+func (v Var) String() string {
+	switch v.Type {
+	case Constantbool:
+		if v.b {
+			return "1"
+		}
+		return ""
+	case Constantfloat64:
+		return fmt.Sprint(v.f)
+	case Constantint64:
+		return strconv.FormatInt(v.i, 10)
+	case Constantstring:
+		return v.s
+	}
+
+	return ""
+}
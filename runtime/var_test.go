@@ -0,0 +1,97 @@
+package runtime
+
+import "testing"
+
+func TestVarSetters(t *testing.T) {
+	var v Var
+	if !v.IsNull() || v.Value() != nil {
+		t.Fatalf("zero Var is %v, want null", v.Value())
+	}
+
+	v.Setint64(10)
+	if v.Type != Constantint64 || v.Getint64() != 10 || v.Value() != int64(10) {
+		t.Errorf("Setint64(10) gives %v", v.Value())
+	}
+
+	v.Setstring("a")
+	if v.Type != Constantstring || v.Getstring() != "a" || v.Value() != "a" {
+		t.Errorf(`Setstring("a") gives %v`, v.Value())
+	}
+
+	v.Setnull(nil)
+	if !v.IsNull() {
+		t.Errorf("Setnull gives %v", v.Value())
+	}
+}
+
+func TestNewVarFromInterface(t *testing.T) {
+	tests := []struct {
+		val  interface{}
+		want ValueType
+	}{
+		{nil, Constantnull},
+		{int64(1), Constantint64},
+		{1.5, Constantfloat64},
+		{"s", Constantstring},
+		{true, Constantbool},
+		{[]int64{1}, Constantarray},
+		{map[string]int64{}, Constantarray},
+		{NewOrderedArray(), Constantarray},
+		{NewGenerator(func(*Yielder) interface{} { return nil }), ConstantGenerator},
+		{NewVarint64(1), Constantint64},
+		{Nullstring{Val: "s", Valid: true}, Constantstring},
+		{Nullstring{}, Constantnull},
+	}
+
+	for _, tt := range tests {
+		if v := NewVarFromInterface(tt.val); v.Type != tt.want {
+			t.Errorf("NewVarFromInterface(%#v).Type = %d, want %d", tt.val, v.Type, tt.want)
+		}
+	}
+
+	arr := []int64{1, 2}
+	var v Var
+	v.Set(arr)
+	v.Value().([]int64)[0] = 5
+	if arr[0] != 5 {
+		t.Errorf("the slice stored in Var is copied")
+	}
+}
+
+func TestVarConversions(t *testing.T) {
+	tests := []struct {
+		v    Var
+		str  string
+		bool bool
+	}{
+		{Var{}, "", false},
+		{NewVarint64(0), "0", false},
+		{NewVarint64(-3), "-3", true},
+		{NewVarfloat64(1.5), "1.5", true},
+		{NewVarstring("0"), "0", false},
+		{NewVarstring("a"), "a", true},
+		{NewVarbool(true), "1", true},
+		{NewVarbool(false), "", false},
+	}
+
+	for _, tt := range tests {
+		if s := tt.v.String(); s != tt.str {
+			t.Errorf("String() of %#v = %q, want %q", tt.v.Value(), s, tt.str)
+		}
+		if b := tt.v.Bool(); b != tt.bool {
+			t.Errorf("Bool() of %#v = %v, want %v", tt.v.Value(), b, tt.bool)
+		}
+	}
+}
+
+func TestVarDoesNotAllocate(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		v := NewVarint64(1)
+		v = Add(v, NewVarfloat64(0.5))
+		v.Setstring("10")
+		_ = v.CompareWithint64(5, Greater)
+	})
+	if allocs != 0 {
+		t.Errorf("scalar Var operations allocate %v times", allocs)
+	}
+}
@@ -1,12 +1,17 @@
 package block
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/node/expr"
 	"github.com/i582/php2go/src/php/node/expr/assign"
+	"github.com/i582/php2go/src/php/node/expr/binary"
 	"github.com/i582/php2go/src/php/node/stmt"
 	"github.com/i582/php2go/src/php/walker"
 	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/utils"
 	"github.com/i582/php2go/src/variable"
 
 	"github.com/i582/php2go/src/ctx"
@@ -26,13 +31,42 @@ func (b *BlockWalker) LeaveNode(w walker.Walkable)                 {}
 func (b *BlockWalker) EnterNode(w walker.Walkable) bool {
 	n := w.(node.Node)
 
+	if v, ok := solver.MutatedVariable(n); ok && b.Ctx.CurrentFunction != nil {
+		b.Ctx.CurrentFunction.MarkMutated(v.VarName.(*node.Identifier).Value)
+	}
+
 	switch n := n.(type) {
 	case *node.Root:
 
 	case *stmt.Expression:
 
+	case *stmt.Function:
+		return false
+	case *stmt.ConstList:
+		return false
+	case *stmt.Namespace:
+		return b.handleNamespace(n)
+
+	case *expr.FunctionCall:
+		return b.handleFunctionCall(n)
+	case *expr.MethodCall:
+		return b.handleMethodCall(n)
+	case *expr.Yield:
+		return b.handleYield(n)
+	case *expr.YieldFrom:
+		return b.handleYieldFrom(n)
 	case *expr.ShortArray:
 		return b.handleArray(n)
+	case *expr.Isset:
+		return b.handleIsset(n.Variables)
+	case *expr.Empty:
+		return b.handleIsset([]node.Node{n.Expr})
+	case *binary.Coalesce:
+		b.handleIsset([]node.Node{n.Left})
+		n.Right.Walk(b)
+		return false
+	case *stmt.Unset:
+		return b.handleUnset(n)
 	case *assign.Assign:
 		return b.handleAssign(n)
 	case *stmt.For:
@@ -64,12 +98,150 @@ func (b *BlockWalker) handleArray(a *expr.ShortArray) bool {
 	return false
 }
 
+// definedRoot reports whether the variable accessed by n is defined,
+// n that does not access a variable is always considered defined.
+func (b *BlockWalker) definedRoot(n node.Node) bool {
+	return solver.IsDefined(&b.Ctx, n)
+}
+
+// handleIsset handles the arguments of isset() and empty(), and the
+// left operand of ??, which can be undefined variables, those are not
+// walked.
+func (b *BlockWalker) handleIsset(vars []node.Node) bool {
+	for _, v := range vars {
+		if b.definedRoot(v) {
+			v.Walk(b)
+		}
+	}
+
+	return false
+}
+
+// handleUnset adds null to the types of the unset variables.
+// The array with the unset element is no longer the list, so
+// it becomes the array with keys.
+func (b *BlockWalker) handleUnset(u *stmt.Unset) bool {
+	for _, v := range u.Vars {
+		if !b.definedRoot(v) {
+			continue
+		}
+
+		v.Walk(b)
+
+		switch v := v.(type) {
+		case *expr.Variable:
+			b.Ctx.Session.Variable(v).AddType(types.NewBaseTypes(types.Null), true)
+		case *expr.ArrayDimFetch:
+			if root, ok := solver.RootVariable(v); ok && b.Ctx.CurrentFunction != nil {
+				b.Ctx.CurrentFunction.MarkMutated(root.VarName.(*node.Identifier).Value)
+			}
+
+			if arr, ok := b.arrayType(v.Variable); ok {
+				arr.IsAssociative = true
+				arr.Shape = nil
+			}
+		}
+	}
+
+	return false
+}
+
+func (b *BlockWalker) handleNamespace(n *stmt.Namespace) bool {
+	b.Ctx.CurrentFunction.Namespace = utils.NamespaceName(n.NamespaceName)
+
+	for _, st := range n.Stmts {
+		st.Walk(b)
+	}
+
+	if n.Stmts != nil {
+		b.Ctx.CurrentFunction.Namespace = ""
+	}
+
+	return false
+}
+
+func (b *BlockWalker) handleFunctionCall(c *expr.FunctionCall) bool {
+	// The define() run unconditionally at the top level before the uses
+	// of the constant is declared by RootWalker, the other constants
+	// are defined at runtime, since they can be undefined.
+	if solver.IsDefineCall(c) {
+		def := solver.DefinedConstant(&b.Ctx, c)
+		def.Runtime = true
+		b.Ctx.Session.AddConstant(def)
+	}
+
+	for _, arg := range c.ArgumentList.Arguments {
+		arg.Walk(b)
+	}
+
+	if fn, ok := solver.CalledFunction(&b.Ctx, c); ok {
+		solver.MergeArgumentTypes(&b.Ctx, fn, c.ArgumentList.Arguments, false)
+	}
+
+	if solver.IsArrayPushCall(c) {
+		args := c.ArgumentList.Arguments
+		target := &expr.ArrayDimFetch{Variable: args[0].(*node.Argument).Expr}
+
+		for _, arg := range args[1:] {
+			b.handleArrayDimAssign(target, solver.ExprTypeLocal(&b.Ctx, arg))
+		}
+	}
+
+	return false
+}
+
+func (b *BlockWalker) handleMethodCall(m *expr.MethodCall) bool {
+	if solver.MethodName(m) != "send" || len(m.ArgumentList.Arguments) == 0 {
+		return true
+	}
+
+	gen, ok := solver.GeneratorType(&b.Ctx, m.Variable)
+	if !ok {
+		return true
+	}
+
+	if fn, ok := b.Ctx.Session.GetFunction(gen.FunctionName); ok {
+		fn.SendTypes.Merge(solver.ExprType(&b.Ctx, m.ArgumentList.Arguments[0]))
+	}
+
+	return true
+}
+
+func (b *BlockWalker) handleYield(y *expr.Yield) bool {
+	fn := b.Ctx.CurrentFunction
+
+	if y.Key != nil {
+		fn.YieldKeyTypes.Merge(solver.ExprType(&b.Ctx, y.Key))
+	} else {
+		fn.YieldKeyTypes.Add(types.NewType(types.Integer))
+	}
+
+	if y.Value != nil {
+		fn.YieldTypes.Merge(solver.ExprType(&b.Ctx, y.Value))
+	} else {
+		fn.YieldTypes.Add(types.NewType(types.Null))
+	}
+
+	return true
+}
+
+func (b *BlockWalker) handleYieldFrom(y *expr.YieldFrom) bool {
+	fn := b.Ctx.CurrentFunction
+	tp := solver.ExprType(&b.Ctx, y.Expr)
+
+	fn.YieldKeyTypes.Merge(tp.KeyType())
+	fn.YieldTypes.Merge(tp.ElementType())
+
+	return true
+}
+
 func (b *BlockWalker) handleFor(f *stmt.For) bool {
 	w := &BlockWalker{
 		Ctx: ctx.Context{
 			Parent:          &b.Ctx,
 			Variables:       variable.NewTable(),
-			CurrentFunction: nil,
+			CurrentFunction: b.Ctx.CurrentFunction,
+			Session:         b.Ctx.Session,
 		},
 	}
 	for _, init := range f.Init {
@@ -86,7 +258,7 @@ func (b *BlockWalker) handleFor(f *stmt.For) bool {
 
 	f.Stmt.Walk(w)
 
-	f.Ctx = w.Ctx
+	b.Ctx.Session.SetScope(f, &w.Ctx)
 
 	return false
 }
@@ -97,6 +269,7 @@ func (b *BlockWalker) handleForeach(f *stmt.Foreach) bool {
 			Parent:          &b.Ctx,
 			Variables:       variable.NewTable(),
 			CurrentFunction: b.Context().CurrentFunction,
+			Session:         b.Ctx.Session,
 		},
 	}
 
@@ -105,7 +278,8 @@ func (b *BlockWalker) handleForeach(f *stmt.Foreach) bool {
 	switch f := f.Expr.(type) {
 	case *expr.Variable:
 		varName := f.VarName.(*node.Identifier).Value
-		f.Var, _ = b.Ctx.GetVariable(varName)
+		v, _ := b.Ctx.GetVariable(varName)
+		b.Ctx.Session.SetVariable(f, v)
 	}
 
 	exprType := solver.ExprType(&w.Ctx, f.Expr)
@@ -115,7 +289,8 @@ func (b *BlockWalker) handleForeach(f *stmt.Foreach) bool {
 		case *expr.Variable:
 			varName := f.VarName.(*node.Identifier).Value
 			w.Ctx.Variables.Add(varName, exprType.KeyType())
-			f.Var, _ = w.Ctx.Variables.Get(varName)
+			v, _ := w.Ctx.Variables.Get(varName)
+			b.Ctx.Session.SetVariable(f, v)
 		}
 	}
 
@@ -124,13 +299,18 @@ func (b *BlockWalker) handleForeach(f *stmt.Foreach) bool {
 		case *expr.Variable:
 			varName := f.VarName.(*node.Identifier).Value
 			w.Ctx.Variables.Add(varName, exprType.ElementType())
-			f.Var, _ = w.Ctx.Variables.Get(varName)
+			v, _ := w.Ctx.Variables.Get(varName)
+			b.Ctx.Session.SetVariable(f, v)
+		case *expr.List:
+			w.handleListItems(f.Items, exprType.ElementType())
+		case *expr.ShortList:
+			w.handleListItems(f.Items, exprType.ElementType())
 		}
 	}
 
 	f.Stmt.Walk(w)
 
-	f.Ctx = w.Ctx
+	b.Ctx.Session.SetScope(f, &w.Ctx)
 
 	return false
 }
@@ -140,42 +320,56 @@ func (b *BlockWalker) handleWhile(wl *stmt.While) bool {
 		Ctx: ctx.Context{
 			Parent:          &b.Ctx,
 			Variables:       variable.NewTable(),
-			CurrentFunction: nil,
+			CurrentFunction: b.Ctx.CurrentFunction,
+			Session:         b.Ctx.Session,
 		},
 	}
 
 	wl.Cond.Walk(w)
 	wl.Stmt.Walk(w)
 
-	wl.Ctx = w.Ctx
+	b.Ctx.Session.SetScope(wl, &w.Ctx)
 
 	return false
 }
 
 func (b *BlockWalker) handleIf(i *stmt.If) bool {
+	nestElseIf(i)
+
 	w := &BlockWalker{
 		Ctx: ctx.Context{
 			Parent:          &b.Ctx,
 			Variables:       variable.NewTable(),
 			CurrentFunction: b.Ctx.CurrentFunction,
+			Session:         b.Ctx.Session,
 		},
 	}
 
 	w.Ctx.InBranching = true
 
+	before := currentTypes(&b.Ctx)
+
 	i.Cond.Walk(b)
+
+	elseNarrowings := solver.Narrowings(&b.Ctx, i.Cond, true)
+
+	restore := solver.ApplyNarrowings(solver.Narrowings(&b.Ctx, i.Cond, false))
 	i.Stmt.Walk(w)
+	restore()
 
 	ww := &BlockWalker{
 		Ctx: ctx.Context{
 			Parent:          &b.Ctx,
 			Variables:       variable.NewTable(),
 			CurrentFunction: b.Ctx.CurrentFunction,
+			Session:         b.Ctx.Session,
 		},
 	}
 
 	if i.Else != nil {
+		restore := solver.ApplyNarrowings(elseNarrowings)
 		i.Else.Walk(ww)
+		restore()
 	}
 
 	for _, v := range w.Ctx.Variables.Vars {
@@ -190,14 +384,64 @@ func (b *BlockWalker) handleIf(i *stmt.If) bool {
 		}
 	}
 
-	i.IfCtx = w.Ctx
-	i.ElseCtx = ww.Ctx
+	b.Ctx.Session.SetScope(i, &w.Ctx)
+	b.Ctx.Session.SetElseScope(i, &ww.Ctx)
 
 	w.Ctx.InBranching = false
 
+	resetCurrentTypes(&b.Ctx, before)
+
+	// The code after the if that always exits
+	// runs only if the condition is false.
+	if i.Else == nil && solver.BranchExits(i.Stmt) {
+		solver.ApplyNarrowings(elseNarrowings)
+	}
+
 	return false
 }
 
+// nestElseIf replaces the elseif branches with the if in the else
+// branch, like else { if ... }, which is generated as the Go if.
+func nestElseIf(i *stmt.If) {
+	if len(i.ElseIf) == 0 {
+		return
+	}
+
+	first := i.ElseIf[0].(*stmt.ElseIf)
+	nested := &stmt.If{
+		Cond:     first.Cond,
+		Stmt:     first.Stmt,
+		ElseIf:   i.ElseIf[1:],
+		Else:     i.Else,
+		Position: first.Position,
+	}
+
+	i.ElseIf = nil
+	i.Else = &stmt.Else{Stmt: nested, Position: first.Position}
+}
+
+// currentTypes returns the current types of the variables, which
+// are reset by resetCurrentTypes after a branch, like the generator
+// does, so both see the same types.
+func currentTypes(c *ctx.Context) map[string]string {
+	vars := c.AllVariables()
+	res := make(map[string]string, len(vars))
+	for name, v := range vars {
+		res[name] = v.CurrentType.String()
+	}
+	return res
+}
+
+// resetCurrentTypes forgets the current types of the variables
+// assigned or narrowed in a branch.
+func resetCurrentTypes(c *ctx.Context, before map[string]string) {
+	for name, v := range c.AllVariables() {
+		if tp, ok := before[name]; !ok || tp != v.CurrentType.String() {
+			v.CurrentType = types.Types{}
+		}
+	}
+}
+
 func (b *BlockWalker) handleReturn(ret *stmt.Return) bool {
 	tp := solver.ExprTypeLocal(&b.Ctx, ret.Expr)
 
@@ -205,7 +449,17 @@ func (b *BlockWalker) handleReturn(ret *stmt.Return) bool {
 		tp = types.NewBaseTypes(types.Void)
 	}
 	if b.Ctx.CurrentFunction != nil {
-		b.Ctx.CurrentFunction.ReturnType.Merge(tp)
+		if ret.Expr != nil && solver.IsAliasExpr(&b.Ctx, ret.Expr) {
+			if rt := solver.ExprType(&b.Ctx, ret.Expr); !rt.SingleType() || rt.Is(types.Arr) {
+				b.Ctx.CurrentFunction.ReturnsAlias = true
+			}
+		}
+
+		if b.Ctx.CurrentFunction.IsGenerator {
+			b.Ctx.CurrentFunction.GeneratorReturnType.Merge(tp)
+		} else {
+			b.Ctx.CurrentFunction.ReturnType.MergeDeep(tp)
+		}
 	}
 
 	return true
@@ -214,26 +468,191 @@ func (b *BlockWalker) handleReturn(ret *stmt.Return) bool {
 func (b *BlockWalker) handleAssign(a *assign.Assign) bool {
 	e := a.Expression
 	e.Walk(b)
-	switch a := a.Variable.(type) {
+	switch v := a.Variable.(type) {
 	case *expr.Variable:
-		var varName string
-		switch name := a.VarName.(type) {
-		case *node.Identifier:
-			varName = name.Value
-		}
-		var ok bool
-		a.Var, ok = b.Ctx.GetVariable(varName)
-		if ok {
-			a.Var.AddType(solver.ExprTypeLocal(&b.Ctx, e), b.Ctx.InBranching)
-		} else {
-			b.Ctx.Variables.Add(varName, solver.ExprTypeLocal(&b.Ctx, e))
-			a.Var, _ = b.Ctx.Variables.Get(varName)
-		}
+		b.assignVariable(v, solver.ExprTypeLocal(&b.Ctx, e))
+	case *expr.List:
+		b.handleListItems(v.Items, solver.ExprTypeLocal(&b.Ctx, e))
+		return false
+	case *expr.ShortList:
+		b.handleListItems(v.Items, solver.ExprTypeLocal(&b.Ctx, e))
+		return false
+	case *expr.ArrayDimFetch:
+		b.handleArrayDimAssign(v, solver.ExprTypeLocal(&b.Ctx, e))
 	}
 	a.Variable.Walk(b)
 	return false
 }
 
+func (b *BlockWalker) assignVariable(v *expr.Variable, tp types.Types) {
+	var varName string
+	switch name := v.VarName.(type) {
+	case *node.Identifier:
+		varName = name.Value
+	}
+	vr, ok := b.Ctx.GetVariable(varName)
+	if ok {
+		vr.AddType(tp, b.Ctx.InBranching)
+	} else {
+		b.Ctx.Variables.Add(varName, tp)
+		vr, _ = b.Ctx.Variables.Get(varName)
+	}
+	b.Ctx.Session.SetVariable(v, vr)
+}
+
+// handleArrayDimAssign adds the types of the key and the value to the
+// array type, since writes can add elements of other types, so the types
+// of arrays created empty are inferred from writes. The shape of the array
+// is kept only if the element keeps its type.
+func (b *BlockWalker) handleArrayDimAssign(f *expr.ArrayDimFetch, valueType types.Types) {
+	arr, ok := b.arrayType(f.Variable)
+	if !ok {
+		parent, isFetch := f.Variable.(*expr.ArrayDimFetch)
+		if !isFetch {
+			return
+		}
+
+		// The write creates the missing inner array, like $a['x'][] = 1.
+		b.handleArrayDimAssign(parent, types.NewTypes(types.NewPlainArrayType(types.Types{}, 1)))
+
+		arr, ok = b.arrayType(f.Variable)
+		if !ok {
+			return
+		}
+	}
+
+	if arr.Shape != nil {
+		arr.Shape = shapeWithElement(arr.Shape, f.Dim, valueType)
+	}
+
+	empty := arr.ElemTypes.Len() == 0
+
+	valueType = solver.ResolveTypes(&b.Ctx, valueType)
+	if !arr.ElemTypes.ContainsMap(valueType) || valueType.Is(types.Arr) {
+		arr.ElemTypes = arr.ElemTypes.Clone()
+		arr.ElemTypes.MergeDeep(valueType)
+	}
+
+	if f.Dim == nil {
+		return
+	}
+
+	keyType := solver.ArrayKeyType(&b.Ctx, f.Dim)
+
+	// The array written by keys before any append, like $m[$k] = $v
+	// after $m = [], or by string keys, is the array with keys.
+	switch {
+	case arr.IsOrderedArray():
+	case empty:
+		arr.IsAssociative = true
+		arr.KeysTypes = types.Types{}
+	case keyType.Contains(types.NewType(types.String)):
+		arr.IsAssociative = true
+	}
+
+	if !arr.IsOrderedArray() {
+		return
+	}
+
+	arr.KeysTypes = arr.KeysTypes.Clone()
+	arr.KeysTypes.Merge(keyType)
+}
+
+// shapeWithElement returns the shape of the array after the write of
+// the element with the key dim. The element written by the key unknown
+// at translation time can replace any element, so the shape is dropped.
+func shapeWithElement(shape map[string]types.Types, dim node.Node, tp types.Types) map[string]types.Types {
+	var key string
+
+	if dim == nil {
+		// The appended element gets the key after the largest integer key.
+		var next int64
+		for k := range shape {
+			if !strings.HasPrefix(k, "i:") {
+				continue
+			}
+			if n, _ := strconv.ParseInt(k[2:], 10, 64); n >= next {
+				next = n + 1
+			}
+		}
+		key = "i:" + strconv.FormatInt(next, 10)
+	} else {
+		k, ok := solver.LiteralKey(dim)
+		if !ok {
+			return nil
+		}
+		key = k
+	}
+
+	res := make(map[string]types.Types, len(shape)+1)
+	for k, t := range shape {
+		res[k] = t
+	}
+
+	elem := res[key].Clone()
+	elem.Merge(tp)
+	res[key] = elem
+
+	return res
+}
+
+// arrayType returns the type of the array n stored in the
+// type of the variable, so the changes of it are preserved.
+func (b *BlockWalker) arrayType(n node.Node) (*types.Type, bool) {
+	switch n := n.(type) {
+	case *expr.Variable:
+		vr, ok := b.Ctx.GetVariable(n.VarName.(*node.Identifier).Value)
+		if !ok || !vr.Type.Is(types.Arr) {
+			return nil, false
+		}
+		// The types can be shared with other variables.
+		vr.Type = vr.Type.Clone()
+		return &vr.Type.Types[0], true
+	case *expr.ArrayDimFetch:
+		parent, ok := b.arrayType(n.Variable)
+		if !ok || !parent.ElemTypes.Is(types.Arr) {
+			return nil, false
+		}
+		parent.ElemTypes = parent.ElemTypes.Clone()
+		return &parent.ElemTypes.Types[0], true
+	}
+
+	return nil, false
+}
+
+// handleListItems handles the targets of the destructuring assignment,
+// each target gets the type of its element of the destructured array.
+func (b *BlockWalker) handleListItems(items []node.Node, arrType types.Types) {
+	var index int64
+	for _, item := range items {
+		item, ok := item.(*expr.ArrayItem)
+		if !ok || item.Val == nil {
+			index++
+			continue
+		}
+
+		elemType := solver.ListElementType(arrType, item, index)
+		if item.Key != nil {
+			item.Key.Walk(b)
+		} else {
+			index++
+		}
+
+		switch v := item.Val.(type) {
+		case *expr.Variable:
+			b.assignVariable(v, elemType)
+		case *expr.List:
+			b.handleListItems(v.Items, elemType)
+		case *expr.ShortList:
+			b.handleListItems(v.Items, elemType)
+		case *expr.ShortArray:
+			b.handleListItems(v.Items, elemType)
+		default:
+			v.Walk(b)
+		}
+	}
+}
+
 func (b *BlockWalker) handleVariable(v *expr.Variable) bool {
 	var varName string
 	switch name := v.VarName.(type) {
@@ -241,11 +660,11 @@ func (b *BlockWalker) handleVariable(v *expr.Variable) bool {
 		varName = name.Value
 	}
 
-	var ok bool
-	v.Var, ok = b.Ctx.GetVariable(varName)
+	vr, ok := b.Ctx.GetVariable(varName)
 	if !ok {
 		panic("var not found")
 	}
+	b.Ctx.Session.SetVariable(v, vr)
 
 	return true
 }
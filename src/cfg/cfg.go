@@ -0,0 +1,358 @@
+package cfg
+
+import (
+	"strconv"
+
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/scalar"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/php/walker"
+)
+
+// Block is the basic block, the nodes that run one after another.
+type Block struct {
+	Nodes []node.Node
+
+	// Cond is the condition evaluated at the end of the block, the
+	// first successor is taken if it is true and the second one
+	// otherwise. The blocks with several successors and without the
+	// condition, like the header of the foreach, take any of them.
+	Cond node.Node
+
+	Succs []*Block
+	Preds []*Block
+}
+
+// Graph is the control flow graph of the function body.
+type Graph struct {
+	Entry  *Block
+	Exit   *Block
+	Blocks []*Block
+}
+
+// loop holds the targets of break and continue
+// in the loop or the switch.
+type loop struct {
+	brk  *Block
+	cont *Block
+}
+
+type builder struct {
+	graph *Graph
+	cur   *Block
+	loops []loop
+}
+
+// Build returns the control flow graph of the statements. Nested
+// functions are not part of the graph, and the statements the graph
+// is not built for, like try, are the simple nodes of the blocks.
+func Build(stmts []node.Node) *Graph {
+	b := &builder{graph: &Graph{}}
+
+	b.graph.Entry = b.newBlock()
+	b.graph.Exit = b.newBlock()
+	b.cur = b.graph.Entry
+
+	b.stmts(stmts)
+	b.jump(b.graph.Exit)
+
+	return b.graph
+}
+
+func (b *builder) newBlock() *Block {
+	block := &Block{}
+	b.graph.Blocks = append(b.graph.Blocks, block)
+	return block
+}
+
+func addEdge(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+// jump ends the current block with the edge to the target.
+func (b *builder) jump(to *Block) {
+	addEdge(b.cur, to)
+}
+
+// branch ends the current block with the condition.
+func (b *builder) branch(cond node.Node, then, els *Block) {
+	b.cur.Cond = cond
+	addEdge(b.cur, then)
+	addEdge(b.cur, els)
+}
+
+func (b *builder) stmts(stmts []node.Node) {
+	for _, st := range stmts {
+		b.stmt(st)
+	}
+}
+
+func (b *builder) stmt(n node.Node) {
+	switch n := n.(type) {
+	case nil:
+	case *stmt.StmtList:
+		b.stmts(n.Stmts)
+	case *stmt.Namespace:
+		b.stmts(n.Stmts)
+	case *stmt.Function, *stmt.ConstList:
+
+	case *stmt.If:
+		b.ifStmt(n)
+	case *stmt.While:
+		b.whileStmt(n)
+	case *stmt.Do:
+		b.doStmt(n)
+	case *stmt.For:
+		b.forStmt(n)
+	case *stmt.Foreach:
+		b.foreachStmt(n)
+	case *stmt.Switch:
+		b.switchStmt(n)
+
+	case *stmt.Return:
+		b.cur.Nodes = append(b.cur.Nodes, n)
+		b.jump(b.graph.Exit)
+		b.cur = b.newBlock()
+	case *stmt.Break:
+		b.loopJump(n.Expr, false)
+	case *stmt.Continue:
+		b.loopJump(n.Expr, true)
+
+	default:
+		b.cur.Nodes = append(b.cur.Nodes, n)
+	}
+}
+
+func (b *builder) ifStmt(i *stmt.If) {
+	after := b.newBlock()
+
+	cond, body := i.Cond, i.Stmt
+	for _, n := range i.ElseIf {
+		elseIf := n.(*stmt.ElseIf)
+
+		then, next := b.newBlock(), b.newBlock()
+		b.branch(cond, then, next)
+
+		b.cur = then
+		b.stmt(body)
+		b.jump(after)
+
+		b.cur = next
+		cond, body = elseIf.Cond, elseIf.Stmt
+	}
+
+	then, els := b.newBlock(), b.newBlock()
+	b.branch(cond, then, els)
+
+	b.cur = then
+	b.stmt(body)
+	b.jump(after)
+
+	b.cur = els
+	if i.Else != nil {
+		b.stmt(i.Else.(*stmt.Else).Stmt)
+	}
+	b.jump(after)
+
+	b.cur = after
+}
+
+func (b *builder) whileStmt(w *stmt.While) {
+	header, body, after := b.newBlock(), b.newBlock(), b.newBlock()
+
+	b.jump(header)
+	b.cur = header
+	b.branch(w.Cond, body, after)
+
+	b.cur = body
+	b.loopBody(w.Stmt, after, header)
+	b.jump(header)
+
+	b.cur = after
+}
+
+func (b *builder) doStmt(d *stmt.Do) {
+	body, cond, after := b.newBlock(), b.newBlock(), b.newBlock()
+
+	b.jump(body)
+	b.cur = body
+	b.loopBody(d.Stmt, after, cond)
+	b.jump(cond)
+
+	b.cur = cond
+	b.branch(d.Cond, body, after)
+
+	b.cur = after
+}
+
+func (b *builder) forStmt(f *stmt.For) {
+	b.cur.Nodes = append(b.cur.Nodes, f.Init...)
+
+	header, body, step, after := b.newBlock(), b.newBlock(), b.newBlock(), b.newBlock()
+
+	b.jump(header)
+	b.cur = header
+
+	// All conditions are evaluated, the last one decides.
+	if len(f.Cond) == 0 {
+		b.jump(body)
+	} else {
+		b.cur.Nodes = append(b.cur.Nodes, f.Cond[:len(f.Cond)-1]...)
+		b.branch(f.Cond[len(f.Cond)-1], body, after)
+	}
+
+	b.cur = body
+	b.loopBody(f.Stmt, after, step)
+	b.jump(step)
+
+	b.cur = step
+	b.cur.Nodes = append(b.cur.Nodes, f.Loop...)
+	b.jump(header)
+
+	b.cur = after
+}
+
+// foreachStmt builds the loop, the body starts with the foreach
+// itself, which stands for the assignment of the key and the value.
+func (b *builder) foreachStmt(f *stmt.Foreach) {
+	b.cur.Nodes = append(b.cur.Nodes, f.Expr)
+
+	header, body, after := b.newBlock(), b.newBlock(), b.newBlock()
+
+	b.jump(header)
+	b.cur = header
+	b.jump(body)
+	b.jump(after)
+
+	b.cur = body
+	b.cur.Nodes = append(b.cur.Nodes, f)
+	b.loopBody(f.Stmt, after, header)
+	b.jump(header)
+
+	b.cur = after
+}
+
+// switchStmt builds the switch, any case can be taken
+// and the cases fall through to the next ones.
+func (b *builder) switchStmt(s *stmt.Switch) {
+	b.cur.Nodes = append(b.cur.Nodes, s.Cond)
+
+	header, after := b.cur, b.newBlock()
+	hasDefault := false
+
+	b.loops = append(b.loops, loop{brk: after, cont: after})
+
+	b.cur = b.newBlock()
+	for _, c := range s.CaseList.Cases {
+		var stmts []node.Node
+		switch c := c.(type) {
+		case *stmt.Case:
+			header.Nodes = append(header.Nodes, c.Cond)
+			stmts = c.Stmts
+		case *stmt.Default:
+			hasDefault = true
+			stmts = c.Stmts
+		}
+
+		// The previous case falls through.
+		body := b.newBlock()
+		b.jump(body)
+		addEdge(header, body)
+
+		b.cur = body
+		b.stmts(stmts)
+	}
+	b.jump(after)
+
+	b.loops = b.loops[:len(b.loops)-1]
+
+	if !hasDefault {
+		addEdge(header, after)
+	}
+
+	b.cur = after
+}
+
+func (b *builder) loopBody(body node.Node, brk, cont *Block) {
+	b.loops = append(b.loops, loop{brk: brk, cont: cont})
+	b.stmt(body)
+	b.loops = b.loops[:len(b.loops)-1]
+}
+
+// loopJump ends the current block with the jump of break or continue,
+// the code after it is unreachable.
+func (b *builder) loopJump(level node.Node, cont bool) {
+	n := JumpLevel(level)
+	if n > len(b.loops) {
+		n = len(b.loops)
+	}
+
+	if n != 0 {
+		target := b.loops[len(b.loops)-n]
+		if cont {
+			b.jump(target.cont)
+		} else {
+			b.jump(target.brk)
+		}
+	}
+
+	b.cur = b.newBlock()
+}
+
+// JumpLevel returns the number of the enclosing loops
+// left by break or continue with the level.
+func JumpLevel(level node.Node) int {
+	lnum, ok := level.(*scalar.Lnumber)
+	if !ok {
+		return 1
+	}
+
+	n, err := strconv.Atoi(lnum.Value)
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// nestedJump finds break and continue of the loops nested in the body,
+// which leave the loop of the body, like break 2.
+type nestedJump struct {
+	depth int
+	found bool
+}
+
+func (j nestedJump) EnterChildNode(key string, w walker.Walkable) {}
+func (j nestedJump) LeaveChildNode(key string, w walker.Walkable) {}
+func (j nestedJump) EnterChildList(key string, w walker.Walkable) {}
+func (j nestedJump) LeaveChildList(key string, w walker.Walkable) {}
+
+func (j *nestedJump) EnterNode(w walker.Walkable) bool {
+	switch n := w.(type) {
+	case *stmt.Function, *expr.Closure, *expr.ArrowFunction:
+		return false
+	case *stmt.For, *stmt.Foreach, *stmt.While, *stmt.Do, *stmt.Switch:
+		j.depth++
+	case *stmt.Break:
+		j.found = j.found || j.depth != 0 && JumpLevel(n.Expr) == j.depth+1
+	case *stmt.Continue:
+		j.found = j.found || j.depth != 0 && JumpLevel(n.Expr) == j.depth+1
+	}
+	return !j.found
+}
+
+func (j *nestedJump) LeaveNode(w walker.Walkable) {
+	switch w.(type) {
+	case *stmt.For, *stmt.Foreach, *stmt.While, *stmt.Do, *stmt.Switch:
+		j.depth--
+	}
+}
+
+// HasNestedJump reports whether break or continue of a loop nested in
+// the body leaves the loop of the body, so the loop needs the label.
+func HasNestedJump(body node.Node) bool {
+	j := &nestedJump{}
+	body.Walk(j)
+	return j.found
+}
@@ -0,0 +1,404 @@
+package cfg
+
+import (
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/expr/assign"
+	"github.com/i582/php2go/src/php/node/expr/binary"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/php/walker"
+	"github.com/i582/php2go/src/variable"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/solver"
+	"github.com/i582/php2go/src/types"
+)
+
+// state holds the types the variables can have at the program point,
+// the variables that are not assigned yet on any path are absent.
+type state map[string]types.Types
+
+func (s state) clone() state {
+	res := make(state, len(s))
+	for name, tp := range s {
+		res[name] = tp
+	}
+	return res
+}
+
+// join adds the types of the other state, it reports
+// whether the state has changed.
+func (s state) join(other state) bool {
+	changed := false
+	for name, tp := range other {
+		cur, ok := s[name]
+		if !ok {
+			s[name] = tp
+			changed = true
+			continue
+		}
+		if cur.ContainsMap(tp) {
+			continue
+		}
+		cur = cur.Clone()
+		cur.Merge(tp)
+		s[name] = cur
+		changed = true
+	}
+	return changed
+}
+
+type analysis struct {
+	ctx *ctx.Context
+	fn  *function.Function
+
+	// vars holds the variable nodes seen, their flow type
+	// in the session is the type of the variable at the node.
+	vars map[*expr.Variable]struct{}
+
+	// returns holds the types of the returned values.
+	returns types.Types
+
+	// yields holds the types of the values yielded by the generator.
+	yields types.Types
+}
+
+// Analyze finds the types of the variables at each read and write
+// in the statements of the function and stores them into the session as
+// the flow types of the variable nodes. The types are propagated over the
+// control flow graph until they no longer change, so the types assigned
+// in a loop reach the code of the loop before the assignment, and the
+// types from the branches are joined after them. The conditions narrow
+// the types on their edges, like is_int($x).
+//
+// The declared types of the variables and the return type of the
+// function are widened to hold all types found, since the values can
+// be of the types the linear walk of the code has not seen, like the
+// types assigned in the later iterations of the loop. Analyze reports
+// whether the return type is widened. The types of the values yielded
+// by the generator are widened the same way.
+func Analyze(c *ctx.Context, fn *function.Function, stmts []node.Node) bool {
+	a := &analysis{
+		ctx:  c,
+		fn:   fn,
+		vars: make(map[*expr.Variable]struct{}),
+	}
+
+	graph := Build(stmts)
+
+	entry := state{}
+	for _, p := range fn.Params {
+		v, ok := fn.Variables.Get(p.Name)
+		if !ok {
+			continue
+		}
+		entry[p.Name] = solver.ResolveTypes(c, v.Type)
+	}
+
+	in := map[*Block]state{graph.Entry: entry}
+	queued := map[*Block]bool{graph.Entry: true}
+	work := []*Block{graph.Entry}
+
+	for len(work) != 0 {
+		block := work[0]
+		work = work[1:]
+		queued[block] = false
+
+		out := a.transfer(block, in[block].clone())
+
+		for i, succ := range block.Succs {
+			s := out
+			if block.Cond != nil {
+				s = a.narrow(out, block.Cond, i == 1)
+			}
+
+			cur, ok := in[succ]
+			if !ok {
+				in[succ] = s.clone()
+			} else if !cur.join(s) {
+				continue
+			}
+
+			if !queued[succ] {
+				queued[succ] = true
+				work = append(work, succ)
+			}
+		}
+	}
+
+	a.widen()
+	return a.widenReturnType() || a.widenYieldTypes()
+}
+
+// transfer returns the state after the nodes of the block.
+func (a *analysis) transfer(block *Block, s state) state {
+	t := &transfer{analysis: a, state: s}
+
+	for _, n := range block.Nodes {
+		n.Walk(t)
+	}
+	if block.Cond != nil {
+		block.Cond.Walk(t)
+	}
+
+	return t.state
+}
+
+// narrow returns the state on the edge taken if the condition
+// is true, or if it is false when negated is set.
+func (a *analysis) narrow(s state, cond node.Node, negated bool) state {
+	ns := solver.Narrowings(a.ctx, cond, negated)
+	if len(ns) == 0 {
+		return s
+	}
+
+	res := s.clone()
+	for _, n := range ns {
+		res[n.Var.Name] = n.Type
+	}
+	return res
+}
+
+// widen adds the types found to the declared types of the variables,
+// the types of the parameters are declared by the signature.
+func (a *analysis) widen() {
+	params := make(map[*variable.Variable]bool)
+	for _, p := range a.fn.Params {
+		if v, ok := a.fn.Variables.Get(p.Name); ok {
+			params[v] = true
+		}
+	}
+
+	for n := range a.vars {
+		v := a.ctx.Session.Variable(n)
+		flowType := a.ctx.Session.FlowType(n)
+		if v == nil || params[v] || flowType.Len() == 0 {
+			continue
+		}
+
+		if !v.Type.Resolved() {
+			v.Type = solver.ResolveTypes(a.ctx, v.Type)
+		}
+		if !v.Type.ContainsMap(flowType) {
+			v.Type = v.Type.Clone()
+			v.Type.Merge(flowType)
+		}
+	}
+}
+
+// widenReturnType adds the types of the returned values to the return
+// type of the function, the values returned by generators are not
+// the values of the calls.
+func (a *analysis) widenReturnType() bool {
+	if a.fn.IsGenerator || a.returns.Len() == 0 {
+		return false
+	}
+
+	if !a.fn.ReturnType.Resolved() {
+		a.fn.ReturnType = solver.ResolveTypes(a.ctx, a.fn.ReturnType)
+	}
+	// The returned arrays are united into one array
+	// type, whose elements can be of the other types.
+	rt := a.fn.ReturnType.Clone()
+	rt.MergeDeep(a.returns)
+	if rt.String() == a.fn.ReturnType.String() {
+		return false
+	}
+
+	a.fn.ReturnType = rt
+	return true
+}
+
+// widenYieldTypes adds the types of the yielded values to the yielded
+// types of the generator, the generator type returned by the function
+// holds them as its element types.
+func (a *analysis) widenYieldTypes() bool {
+	if !a.fn.IsGenerator || a.yields.Len() == 0 {
+		return false
+	}
+
+	if !a.fn.YieldTypes.Resolved() {
+		a.fn.YieldTypes = solver.ResolveTypes(a.ctx, a.fn.YieldTypes)
+	}
+	if a.fn.YieldTypes.ContainsMap(a.yields) {
+		return false
+	}
+
+	a.fn.YieldTypes = a.fn.YieldTypes.Clone()
+	a.fn.YieldTypes.Merge(a.yields)
+	a.fn.ReturnType = types.NewTypes(types.NewGeneratorType(a.fn.Name, a.fn.YieldKeyTypes, a.fn.YieldTypes))
+	return true
+}
+
+// transfer walks the nodes of the block and updates the state
+// by the assignments, nested functions and closures are skipped.
+type transfer struct {
+	*analysis
+	state state
+}
+
+func (t transfer) EnterChildNode(key string, w walker.Walkable) {}
+func (t transfer) LeaveChildNode(key string, w walker.Walkable) {}
+func (t transfer) EnterChildList(key string, w walker.Walkable) {}
+func (t transfer) LeaveChildList(key string, w walker.Walkable) {}
+func (t *transfer) LeaveNode(w walker.Walkable)                 {}
+
+func (t *transfer) EnterNode(w walker.Walkable) bool {
+	switch n := w.(type) {
+	case *stmt.Function, *expr.Closure, *expr.ArrowFunction:
+		return false
+
+	case *stmt.Foreach:
+		// The foreach at the start of the loop body
+		// assigns the key and the value.
+		tp := solver.ExprType(t.ctx, n.Expr)
+		t.assign(n.Key, tp.KeyType())
+		t.assign(n.Variable, tp.ElementType())
+		return false
+
+	case *expr.Variable:
+		t.read(n)
+		return false
+
+	case *assign.Assign:
+		n.Expression.Walk(t)
+		t.assign(n.Variable, solver.ExprType(t.ctx, n.Expression))
+		return false
+	case *assign.Concat:
+		t.compoundAssign(n.Variable, n.Expression, types.NewBaseTypes(types.String))
+		return false
+	case *assign.Plus:
+		t.compoundAssign(n.Variable, n.Expression, solver.ExprType(t.ctx, &binary.Plus{Left: n.Variable, Right: n.Expression}))
+		return false
+	case *assign.Minus:
+		t.compoundAssign(n.Variable, n.Expression, solver.ExprType(t.ctx, &binary.Minus{Left: n.Variable, Right: n.Expression}))
+		return false
+	case *assign.Mul:
+		t.compoundAssign(n.Variable, n.Expression, solver.ExprType(t.ctx, &binary.Mul{Left: n.Variable, Right: n.Expression}))
+		return false
+	case *assign.Div:
+		t.compoundAssign(n.Variable, n.Expression, solver.ExprType(t.ctx, &binary.Div{Left: n.Variable, Right: n.Expression}))
+		return false
+
+	case *binary.BooleanAnd:
+		t.shortCircuit(n.Left, n.Right, false)
+		return false
+	case *binary.LogicalAnd:
+		t.shortCircuit(n.Left, n.Right, false)
+		return false
+	case *binary.BooleanOr:
+		t.shortCircuit(n.Left, n.Right, true)
+		return false
+	case *binary.LogicalOr:
+		t.shortCircuit(n.Left, n.Right, true)
+		return false
+
+	case *stmt.Return:
+		if n.Expr == nil {
+			return false
+		}
+		n.Expr.Walk(t)
+		t.returns.Merge(solver.ExprType(t.ctx, n.Expr))
+		return false
+
+	case *expr.Yield:
+		if n.Key != nil {
+			n.Key.Walk(t)
+		}
+		if n.Value != nil {
+			n.Value.Walk(t)
+			t.yields.Merge(solver.ExprType(t.ctx, n.Value))
+		}
+		return false
+
+	case *stmt.Unset:
+		for _, v := range n.Vars {
+			if v, ok := v.(*expr.Variable); ok {
+				t.assign(v, types.NewBaseTypes(types.Null))
+				continue
+			}
+			v.Walk(t)
+		}
+		return false
+	}
+
+	return true
+}
+
+func (t *transfer) read(n *expr.Variable) {
+	id, ok := n.VarName.(*node.Identifier)
+	if !ok {
+		return
+	}
+
+	t.ctx.Session.SetFlowType(n, t.state[id.Value])
+	t.vars[n] = struct{}{}
+}
+
+// shortCircuit handles && and ||, the right operand is evaluated only
+// if the left one is true, or false when negated is set, so it is
+// narrowed by the left operand.
+func (t *transfer) shortCircuit(left, right node.Node, negated bool) {
+	left.Walk(t)
+
+	skipped := t.state
+	t.state = t.narrow(skipped, left, negated).clone()
+	right.Walk(t)
+	t.state.join(skipped)
+}
+
+// compoundAssign handles the assignment like $x .= $y,
+// which reads the variable before the write.
+func (t *transfer) compoundAssign(target, value node.Node, tp types.Types) {
+	value.Walk(t)
+	target.Walk(t)
+	t.assign(target, tp)
+}
+
+// assign sets the type of the variable written by the target,
+// the elements of the destructured arrays get their element types.
+func (t *transfer) assign(target node.Node, tp types.Types) {
+	var items []node.Node
+
+	switch target := target.(type) {
+	case nil:
+		return
+	case *expr.Variable:
+		id, ok := target.VarName.(*node.Identifier)
+		if !ok {
+			return
+		}
+		t.state[id.Value] = tp
+		t.ctx.Session.SetFlowType(target, tp)
+		t.vars[target] = struct{}{}
+		return
+	case *expr.List:
+		items = target.Items
+	case *expr.ShortList:
+		items = target.Items
+	case *expr.ShortArray:
+		items = target.Items
+	default:
+		// The element of the array keeps the type of the array.
+		target.Walk(t)
+		return
+	}
+
+	var index int64
+	for _, item := range items {
+		item, ok := item.(*expr.ArrayItem)
+		if !ok || item.Val == nil {
+			index++
+			continue
+		}
+
+		elemType := solver.ListElementType(tp, item, index)
+		if item.Key != nil {
+			item.Key.Walk(t)
+		} else {
+			index++
+		}
+		t.assign(item.Val, elemType)
+	}
+}
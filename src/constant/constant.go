@@ -0,0 +1,40 @@
+package constant
+
+import (
+	"fmt"
+
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/utils"
+)
+
+type Constant struct {
+	// Name is the fully qualified name without the leading backslash.
+	Name  string
+	Type  types.Types
+	Value node.Node
+
+	// Runtime is set for constants whose value is not known
+	// at translation time, such constants are generated as
+	// variables that are assigned in place of define() call.
+	Runtime bool
+}
+
+func NewConstant(name string, typ types.Types, value node.Node, runtime bool) *Constant {
+	return &Constant{Name: name, Type: typ, Value: value, Runtime: runtime}
+}
+
+func (c Constant) String() string {
+	return fmt.Sprintf("%s: %v", c.Name, c.Type)
+}
+
+// GoName returns the name of the constant in the generated code.
+func (c Constant) GoName() string {
+	return utils.GoIdentifier(c.Name)
+}
+
+// GoDefinedName returns the name of the flag that is set
+// when the define() call for a runtime constant is executed.
+func (c Constant) GoDefinedName() string {
+	return c.GoName() + "Defined"
+}
@@ -0,0 +1,46 @@
+package constant
+
+import (
+	"sort"
+)
+
+type Table struct {
+	Constants map[string]*Constant
+}
+
+func NewTable() Table {
+	return Table{Constants: make(map[string]*Constant)}
+}
+
+func (t *Table) Add(c *Constant) bool {
+	if t.Contains(c.Name) {
+		return false
+	}
+
+	t.Constants[c.Name] = c
+	return true
+}
+
+func (t Table) Contains(name string) bool {
+	_, ok := t.Constants[name]
+	return ok
+}
+
+func (t Table) Get(name string) (*Constant, bool) {
+	c, ok := t.Constants[name]
+	return c, ok
+}
+
+// Sorted returns all constants ordered by name.
+func (t Table) Sorted() []*Constant {
+	res := make([]*Constant, 0, len(t.Constants))
+	for _, c := range t.Constants {
+		res = append(res, c)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
@@ -10,13 +10,9 @@ type Context struct {
 	Variables       variable.Table
 	CurrentFunction *function.Function
 
-	InAssignLvalue      bool
-	InAssignRvalue      bool
-	InPrintFunctionCall bool
-	InCondition         bool
-	InCompare           bool
-	InBoolean           bool
-	InIsTFunction       bool
+	// Session holds the information about the nodes
+	// of the program the context belongs to.
+	Session *Session
 
 	InBranching bool
 }
@@ -38,3 +34,30 @@ func (c Context) GetVariable(name string) (*variable.Variable, bool) {
 
 	return v, true
 }
+
+// AllVariables returns the variables visible in the context,
+// including the variables of the enclosing contexts.
+func (c Context) AllVariables() map[string]*variable.Variable {
+	res := make(map[string]*variable.Variable)
+	for cur := &c; cur != nil; cur = cur.Parent {
+		for name, v := range cur.Variables.Vars {
+			if _, ok := res[name]; !ok {
+				res[name] = v
+			}
+		}
+	}
+	return res
+}
+
+// Namespace returns the namespace of the current function.
+func (c Context) Namespace() string {
+	if c.CurrentFunction != nil {
+		return c.CurrentFunction.Namespace
+	}
+
+	if c.Parent == nil {
+		return ""
+	}
+
+	return c.Parent.Namespace()
+}
@@ -0,0 +1,92 @@
+package ctx
+
+import (
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/stmt"
+
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/meta"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/variable"
+)
+
+// Session is the translation of the program. It owns the symbol tables
+// of the program, so independent translations can run concurrently. The
+// nodes of the parser hold only the syntax, the information found by the
+// analysis about them is stored in the session, keyed by the node.
+type Session struct {
+	*meta.Program
+
+	// scopes holds the contexts of the bodies of the loops and
+	// of the branches of if, elses holds the else branches.
+	scopes map[node.Node]*Context
+	elses  map[*stmt.If]*Context
+
+	functions map[*stmt.Function]*function.Function
+
+	// variables holds the variables accessed by the variable nodes,
+	// flowTypes holds the types of the variables at the nodes found
+	// by the dataflow analysis of the function.
+	variables map[*expr.Variable]*variable.Variable
+	flowTypes map[*expr.Variable]types.Types
+}
+
+func NewSession() *Session {
+	return &Session{
+		Program:   meta.NewProgram(),
+		scopes:    make(map[node.Node]*Context),
+		elses:     make(map[*stmt.If]*Context),
+		functions: make(map[*stmt.Function]*function.Function),
+		variables: make(map[*expr.Variable]*variable.Variable),
+		flowTypes: make(map[*expr.Variable]types.Types),
+	}
+}
+
+// Scope returns the context of the body of the loop
+// or of the if branch of the if statement.
+func (s *Session) Scope(n node.Node) *Context {
+	return s.scopes[n]
+}
+
+func (s *Session) SetScope(n node.Node, c *Context) {
+	s.scopes[n] = c
+}
+
+// ElseScope returns the context of the else branch of the if statement.
+func (s *Session) ElseScope(n *stmt.If) *Context {
+	return s.elses[n]
+}
+
+func (s *Session) SetElseScope(n *stmt.If, c *Context) {
+	s.elses[n] = c
+}
+
+// Function returns the function declared by the node.
+func (s *Session) Function(n *stmt.Function) *function.Function {
+	return s.functions[n]
+}
+
+func (s *Session) SetFunction(n *stmt.Function, fn *function.Function) {
+	s.functions[n] = fn
+}
+
+// Variable returns the variable accessed by the node,
+// or nil if the node has not been analyzed.
+func (s *Session) Variable(n *expr.Variable) *variable.Variable {
+	return s.variables[n]
+}
+
+func (s *Session) SetVariable(n *expr.Variable, v *variable.Variable) {
+	s.variables[n] = v
+}
+
+// FlowType returns the type of the variable at the node
+// found by the dataflow analysis of the function.
+func (s *Session) FlowType(n *expr.Variable) types.Types {
+	return s.flowTypes[n]
+}
+
+func (s *Session) SetFlowType(n *expr.Variable, tp types.Types) {
+	s.flowTypes[n] = tp
+}
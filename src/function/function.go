@@ -3,30 +3,98 @@ package function
 import (
 	"fmt"
 
+	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/utils"
 	"github.com/i582/php2go/src/variable"
 )
 
 type Param struct {
 	Name string
 	Type types.Types
+
+	// Typed is set when the type of the parameter comes from its
+	// declaration, otherwise it is collected from the call sites.
+	Typed    bool
+	Default  node.Node
+	Variadic bool
 }
 
 func (v Param) String() string {
+	if v.Variadic {
+		return fmt.Sprintf("...%s: %v", v.Name, v.Type)
+	}
 	return fmt.Sprintf("%s: %v", v.Name, v.Type)
 }
 
+// ParamIndex returns the index of the parameter that takes
+// the argument at position i, taking variadic parameter into account.
+func (v Function) ParamIndex(i int) (int, bool) {
+	if i < len(v.Params) {
+		return i, true
+	}
+	if len(v.Params) != 0 && v.Params[len(v.Params)-1].Variadic {
+		return len(v.Params) - 1, true
+	}
+	return 0, false
+}
+
 type Function struct {
 	Name       string
+	Namespace  string
 	ReturnType types.Types
 	Params     []Param
 	Variables  variable.Table
+
+	// IsGenerator is set for functions that contain yield,
+	// their ReturnType is the generator type, and the types
+	// below describe the values that pass through it.
+	IsGenerator         bool
+	YieldKeyTypes       types.Types
+	YieldTypes          types.Types
+	SendTypes           types.Types
+	GeneratorReturnType types.Types
+
+	// Mutated holds the names of array variables whose elements
+	// are modified in the function, copies of arrays are made
+	// only when one of the sides of the assignment is mutated.
+	Mutated map[string]struct{}
+	// ReturnsAlias is set if the function can return the array
+	// that is still referenced by someone, for example, a parameter.
+	ReturnsAlias bool
+	// Widened is set if the types of the function do not settle
+	// during the inference, its results and the parameters without
+	// declared types are of the mixed type.
+	Widened bool
+
+	// Declared holds the position of the declaration,
+	// like a.php:3, which is used in the errors.
+	Declared string
 }
 
 func NewFunction(name string, returnType types.Types, params []Param) *Function {
 	return &Function{Name: name, ReturnType: returnType, Params: params}
 }
 
+// GoName returns the name of the function in the generated code, the
+// names reserved in Go, like main and init, are changed like the names
+// of constants.
+func (v Function) GoName() string {
+	return utils.GoIdentifier(v.Name)
+}
+
+func (v *Function) MarkMutated(name string) {
+	if v.Mutated == nil {
+		v.Mutated = make(map[string]struct{})
+	}
+	v.Mutated[name] = struct{}{}
+}
+
+func (v Function) IsMutated(name string) bool {
+	_, ok := v.Mutated[name]
+	return ok
+}
+
 func (v Function) String() string {
 	var params string
 
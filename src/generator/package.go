@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"io"
+	"sync"
+
+	"github.com/i582/php2go/src/goast"
+	"github.com/i582/php2go/src/inline"
+	"github.com/i582/php2go/src/types"
+)
+
+// Package holds the code shared by the files translated into one Go
+// package, that is the functions reading the elements of slices, the
+// policy for reads of undefined array keys and the inlined runtime,
+// which would be declared by each of the files otherwise. The files
+// of the package can be generated concurrently.
+type Package struct {
+	name string
+
+	strictArrayKeys bool
+	inlineRuntime   bool
+
+	mu      sync.Mutex
+	varInfo types.VarInfo
+}
+
+func NewPackage(name string) *Package {
+	return &Package{
+		name:    name,
+		varInfo: types.NewVarInfo(),
+	}
+}
+
+// SetStrictArrayKeys is like Generator.SetStrictArrayKeys
+// for all files of the package.
+func (p *Package) SetStrictArrayKeys(strict bool) {
+	p.strictArrayKeys = strict
+}
+
+// SetInlineRuntime is like Generator.SetInlineRuntime
+// for all files of the package.
+func (p *Package) SetInlineRuntime(inline bool) {
+	p.inlineRuntime = inline
+}
+
+// merge adds the parts of the runtime used by the generated file.
+func (p *Package) merge(v types.VarInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.varInfo.Merge(v)
+}
+
+// Final prints the shared code to core once all files of the package
+// are generated, nothing is printed if the files share no code. The
+// code is checked like by Generator.Final.
+func (p *Package) Final(core io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	decls, err := indexDecls(&p.varInfo, p.strictArrayKeys)
+	if err != nil {
+		return err
+	}
+
+	var src []byte
+	switch {
+	case p.inlineRuntime && p.varInfo.NeedRuntime():
+		src, err = appendDecls(inline.Source(p.name), decls)
+	case len(decls) != 0:
+		importDecl := &ast.GenDecl{
+			Tok:    token.IMPORT,
+			Lparen: 1,
+			Specs: []ast.Spec{&ast.ImportSpec{
+				Name: goast.Ident("."),
+				Path: goast.String(runtimePackage),
+			}},
+		}
+		src, err = printFile(p.name, append([]ast.Decl{importDecl}, decls...))
+	default:
+		return nil
+	}
+
+	_, _ = core.Write(src)
+	return err
+}
@@ -3,756 +3,2025 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 
-	"github.com/i582/php2go/src/ctx"
-	"github.com/i582/php2go/src/php/node"
-	"github.com/i582/php2go/src/php/node/expr"
-	"github.com/i582/php2go/src/php/node/expr/assign"
-	"github.com/i582/php2go/src/php/node/expr/binary"
-	"github.com/i582/php2go/src/php/node/name"
-	"github.com/i582/php2go/src/php/node/scalar"
-	"github.com/i582/php2go/src/php/node/stmt"
-	"github.com/i582/php2go/src/php/walker"
+	"github.com/i582/php2go/src/constant"
+	"github.com/i582/php2go/src/goast"
+	"github.com/i582/php2go/src/inline"
+	"github.com/i582/php2go/src/ir"
 	"github.com/i582/php2go/src/solver"
 	"github.com/i582/php2go/src/types"
 	"github.com/i582/php2go/src/utils"
-	"github.com/i582/php2go/src/variable"
 )
 
-type GeneratorWalker struct {
+// Generator builds the Go syntax tree of the lowered file, which is
+// printed by Final. The statements are appended to the list of the
+// block being generated, the expressions are returned.
+type Generator struct {
 	main     io.Writer
 	core     io.Writer
 	filename string
 
+	// executable is set if the file has the top-level
+	// code, which becomes func main().
+	executable bool
+
+	// strictArrayKeys is the policy for reads of undefined array keys,
+	// which is passed to the runtime, see ArrayKeys in the runtime.
+	strictArrayKeys bool
+
+	// inlineRuntime makes the runtime written to the core file
+	// instead of the import of the runtime package.
+	inlineRuntime bool
+
+	// pkg holds the code shared by the files of the package,
+	// it is nil if the file is translated alone.
+	pkg *Package
+
 	requireImports map[string]struct{}
 
-	varStructDefinitionWriter *bytes.Buffer
+	// consts holds the declarations of the constants,
+	// which precede the functions held by decls.
+	consts         []ast.Decl
+	decls          []ast.Decl
+	declaredConsts map[string]struct{}
+
+	// stmts holds the statements of the block being generated.
+	stmts *[]ast.Stmt
+
+	tempVars int
+
+	// pos is the position of the statement being generated.
+	pos ir.Pos
+	// err holds the first error of the generation, see fail.
+	err error
 
-	mainWriter *bytes.Buffer
+	// loopLabels holds the labels of the enclosing loops, the label
+	// is empty if no break or continue of a nested loop leaves the loop.
+	loopLabels []string
 
-	varInfo types.VarInfo
+	// loopKey and loopValue hold the key and the value of the current
+	// element of the enclosing foreach over the ordered array or the
+	// generator, see ir.LoopKey and ir.LoopValue.
+	loopKey   ast.Expr
+	loopValue ast.Expr
 
-	ctx *ctx.Context
+	// destructured holds the names of the temporary variables
+	// that hold the destructured arrays.
+	destructured map[*ir.Destructure]string
 
-	indents int
+	// fn is the function being generated.
+	fn *ir.Func
+
+	flags flags
+
+	varInfo *types.VarInfo
+}
+
+// flags hold the context of the expression being generated,
+// which decides how the variables of union types are accessed.
+type flags struct {
+	inAssignLvalue bool
+	inAssignRvalue bool
+	inPrint        bool
+	inCompare      bool
+	inBoolean      bool
+	inIsT          bool
 }
 
-func NewGeneratorWalker(main io.Writer, core io.Writer, filename string) GeneratorWalker {
-	return GeneratorWalker{
-		main:                      main,
-		core:                      core,
-		filename:                  filename,
-		requireImports:            make(map[string]struct{}),
-		mainWriter:                bytes.NewBufferString(""),
-		varStructDefinitionWriter: bytes.NewBufferString(""),
-		varInfo:                   types.NewVarInfo(),
+func NewGenerator(main io.Writer, core io.Writer, filename string) *Generator {
+	varInfo := types.NewVarInfo()
+
+	return &Generator{
+		main:           main,
+		core:           core,
+		filename:       filename,
+		requireImports: make(map[string]struct{}),
+		declaredConsts: make(map[string]struct{}),
+		stmts:          new([]ast.Stmt),
+		destructured:   make(map[*ir.Destructure]string),
+		varInfo:        &varInfo,
 	}
 }
 
-func (g GeneratorWalker) EnterChildNode(key string, w walker.Walkable) {}
-func (g GeneratorWalker) LeaveChildNode(key string, w walker.Walkable) {}
-func (g GeneratorWalker) EnterChildList(key string, w walker.Walkable) {}
-func (g GeneratorWalker) LeaveChildList(key string, w walker.Walkable) {}
-func (g *GeneratorWalker) LeaveNode(w walker.Walkable)                 {}
+// SetStrictArrayKeys makes reads of undefined array keys and
+// indexes out of range panic instead of the warning.
+func (g *Generator) SetStrictArrayKeys(strict bool) {
+	g.strictArrayKeys = strict
+}
 
-func (g *GeneratorWalker) EnterNode(w walker.Walkable) bool {
-	n := w.(node.Node)
+// SetInlineRuntime makes the generator write the runtime to the core
+// file, so the output has no dependencies, instead of the import of
+// the runtime package.
+func (g *Generator) SetInlineRuntime(inline bool) {
+	g.inlineRuntime = inline
+}
+
+// SetPackage makes the file one of the files of the package p, the
+// code shared by the files is then written by p instead and the options
+// of p apply to the file.
+func (g *Generator) SetPackage(p *Package) {
+	g.pkg = p
+	g.strictArrayKeys = p.strictArrayKeys
+	g.inlineRuntime = p.inlineRuntime
+}
+
+// Generate generates the code of the file. The file with the top-level
+// code is generated in the executable mode, in which the output is a main
+// package and the top-level code is placed into func main(). The panic
+// of the generation is reported by Final like the other errors.
+func (g *Generator) Generate(f *ir.File) {
+	g.executable = f.Main != nil
+
+	defer func() {
+		if r := recover(); r != nil {
+			g.fail(fmt.Errorf("%v", r))
+		}
+	}()
+
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ir.Func:
+			g.GenerateFunction(d)
+		case *ir.ConstDecl:
+			g.GenerateConstantDeclaration(d)
+		}
+	}
+
+	if f.Main == nil {
+		return
+	}
+
+	g.fn = f.Main
+	g.decls = append(g.decls, &ast.FuncDecl{
+		Name: goast.Ident("main"),
+		Type: goast.FuncType(nil, nil),
+		Body: g.generateBlock(f.Main.Body),
+	})
+}
+
+// generateStmt generates the statement n, the Go statements
+// are appended to the statements of the current block.
+func (g *Generator) generateStmt(n ir.Stmt) {
+	g.pos = n.Position()
 
 	switch n := n.(type) {
-	case *node.Root:
+	case *ir.ExprStmt:
+		g.generateExpressionStmt(n)
+	case *ir.Assign:
+		g.GenerateAssign(n)
+	case *ir.IndexAssign:
+		g.GenerateIndexAssign(n)
+	case *ir.NestedAppend:
+		g.generateNestedAppend(n)
+	case *ir.Destructure:
+		g.GenerateListAssign(n)
+	case *ir.IncDec:
+		tok := token.DEC
+		if n.Inc {
+			tok = token.INC
+		}
+		g.emit(&ast.IncDecStmt{X: g.generateExpr(n.X), Tok: tok})
+	case *ir.Echo:
+		g.GenerateEcho(n)
+	case *ir.Return:
+		g.GenerateReturn(n)
+
+	case *ir.If:
+		g.GenerateIf(n)
+	case *ir.For:
+		g.GenerateFor(n)
+	case *ir.While:
+		g.GenerateWhile(n)
+	case *ir.Foreach:
+		g.GenerateForeach(n)
+	case *ir.Jump:
+		g.GenerateLoopJump(n)
+
+	case *ir.UnsetVar:
+		g.GenerateUnset(n)
+	case *ir.DeleteKey:
+		arr := g.generateExpr(n.Container)
+		g.emit(goast.ExprStmt(goast.Method(arr, "Delete", g.generateExpr(n.Key))))
+	case *ir.Define:
+		g.GenerateDefine(n)
 
-	case *expr.ShortArray:
-		return g.GenerateArray(n)
-	case *expr.ArrayDimFetch:
-		return g.GenerateArrayDimFetch(n)
+	default:
+		panic(fmt.Sprintf("unsupported statement %T", n))
+	}
+}
 
-	case *expr.FunctionCall:
-		return g.GenerateFunctionCall(n)
-	case *stmt.Function:
-		return g.GenerateFunction(n)
-	case *stmt.Return:
-		return g.GenerateReturn(n)
+func (g *Generator) generateExpressionStmt(n *ir.ExprStmt) {
+	switch x := n.X.(type) {
+	case *ir.Yield:
+		g.emit(goast.ExprStmt(g.generateYieldCall(x)))
+	case *ir.YieldFrom:
+		// The values sent to the generator are not used.
+		if !x.X.Type().Is(types.Generator) {
+			g.generateYieldFromArray(x)
+			return
+		}
+		g.emit(goast.ExprStmt(goast.Method(goast.Ident("_gen"), "YieldFrom", g.generateExpr(x.X))))
+	case *ir.GeneratorMethod:
+		g.emit(goast.ExprStmt(g.generateGeneratorMethodCall(x, false)))
+	default:
+		g.emit(goast.ExprStmt(g.generateExpr(n.X)))
+	}
+}
 
-	case *expr.Variable:
+// generateExpr returns the Go expression of the expression n.
+func (g *Generator) generateExpr(n ir.Expr) ast.Expr {
+	switch n := n.(type) {
+	case *ir.Lit:
+		switch n.Kind {
+		case ir.IntLit:
+			return goast.Int64(n.Value)
+		case ir.FloatLit:
+			return goast.Float(n.Value)
+		}
+		return goast.String(n.Value)
+	case *ir.Name:
+		return goast.Ident(n.Name)
+	case *ir.Null:
+		return goast.Nil()
+	case *ir.Bool:
+		return goast.Bool(n.Value)
+
+	case *ir.Var:
 		return g.GenerateVariable(n)
+	case *ir.ConstRef:
+		if n.Const == nil {
+			return goast.Ident(utils.GoIdentifier(n.Name))
+		}
+		return goast.Ident(n.Const.GoName())
+	case *ir.Defined:
+		return goast.Ident(n.Const.GoDefinedName())
+	case *ir.DefineExpr:
+		return g.GenerateDefineCall(n)
 
-	case *stmt.Expression:
-		g.GenerateIndents()
-		n.Expr.Walk(g)
-		g.Write("\n")
-		return false
-
-	case *stmt.Echo:
-		return g.GenerateEcho(n)
-	case *assign.Assign:
-		return g.GenerateAssign(n)
-
-	case *stmt.For:
-		return g.GenerateFor(n)
-	case *stmt.Foreach:
-		return g.GenerateForeach(n)
-	case *stmt.While:
-		return g.GenerateWhile(n)
-	case *stmt.If:
-		return g.GenerateIf(n)
-
-	case *scalar.Lnumber:
-		g.Write(fmt.Sprintf("int64(%s)", n.Value))
-	case *scalar.Dnumber:
-		g.Write(n.Value)
-	case *scalar.String:
-		g.Write(n.Value)
-	case *name.Name:
-		val := utils.NamePartsToString(n.Parts)
-		if val == "true" || val == "false" {
-			g.Write(val)
-		} else if strings.EqualFold(val, "null") {
-			g.Write("0")
-		}
-
-	case *binary.Plus:
-		return g.GenerateBinaryOps(n)
-	case *binary.Minus:
-		return g.GenerateBinaryOps(n)
-	case *binary.Mul:
-		return g.GenerateBinaryOps(n)
-	case *binary.Div:
-		return g.GenerateBinaryOps(n)
-	case *binary.Concat:
+	case *ir.Binary:
 		return g.GenerateBinaryOps(n)
+	case *ir.InstanceOf:
+		return g.GenerateInstanceOf(n)
+	case *ir.Not:
+		g.flags.inBoolean = true
+		x := g.generateExpr(n.X)
+		g.flags.inBoolean = false
+		return goast.Unary(token.NOT, x)
+	case *ir.Neg:
+		return goast.Unary(token.SUB, g.generateExpr(n.X))
+
+	case *ir.Isset:
+		return g.GenerateIsset(n)
+	case *ir.Empty:
+		return g.generateLookupCall("Empty", n.Item, true)
+	case *ir.Coalesce:
+		return g.GenerateCoalesce(n)
+
+	case *ir.Index:
+		return g.GenerateArrayDimFetch(n)
+	case *ir.NestedRef:
+		return goast.Method(g.generateExpr(n.X), "Nested", g.generateExpr(n.Key))
+	case *ir.IndexRef:
+		return goast.Index(g.generateExpr(n.X), g.generateExpr(n.Key))
+	case *ir.ArrayLit:
+		return g.GenerateArray(n)
+	case *ir.Element:
+		return g.generateArrayElement(n)
+	case *ir.Copy:
+		return g.generateArrayCopy(n.T, g.generateExpr(n.X))
+	case *ir.ElemAt:
+		return goast.Index(g.generateExpr(n.X), goast.Int(n.Index))
+	case *ir.Rest:
+		rest := g.generateExpr(n.X)
+		if n.From != 0 {
+			rest = goast.SliceFrom(rest, goast.Int(n.From))
+		}
+		return rest
+	case *ir.Append:
+		return g.generateAppend(n)
+	case *ir.ListElem:
+		return g.generateListElement(n)
 
-	case *binary.NotEqual:
-		return g.GenerateBinaryOps(n)
-	case *binary.Equal:
-		return g.GenerateBinaryOps(n)
-	case *binary.Smaller:
-		return g.GenerateBinaryOps(n)
-	case *binary.SmallerOrEqual:
-		return g.GenerateBinaryOps(n)
-	case *binary.Greater:
-		return g.GenerateBinaryOps(n)
-	case *binary.GreaterOrEqual:
-		return g.GenerateBinaryOps(n)
+	case *ir.Call:
+		return g.GenerateFunctionCall(n)
+	case *ir.IsT:
+		return g.GenerateFunctionIsT(n)
+	case *ir.GeneratorMethod:
+		return g.generateGeneratorMethodCall(n, true)
+	case *ir.Yield:
+		return g.generateFromInterface(n.T, g.generateYieldCall(n))
+	case *ir.YieldFrom:
+		return g.GenerateYieldFrom(n)
+	case *ir.LoopKey:
+		return g.generateFromInterface(n.T, g.loopKey)
+	case *ir.LoopValue:
+		return g.generateFromInterface(n.T, g.loopValue)
+	}
 
-	case *binary.BooleanAnd:
-		return g.GenerateBinaryOps(n)
-	case *binary.BooleanOr:
-		return g.GenerateBinaryOps(n)
+	panic(fmt.Sprintf("unsupported expression %T", n))
+}
+
+// emit appends the statements to the block being generated.
+func (g *Generator) emit(stmts ...ast.Stmt) {
+	*g.stmts = append(*g.stmts, stmts...)
+}
+
+// generateStmts returns the statements emitted by generate.
+func (g *Generator) generateStmts(generate func()) []ast.Stmt {
+	prev := g.stmts
+	var stmts []ast.Stmt
+	g.stmts = &stmts
 
-	case *expr.PostInc:
-		n.Variable.Walk(g)
-		g.Write("++")
-		return false
-	case *expr.PostDec:
-		n.Variable.Walk(g)
-		g.Write("--")
-		return false
-	case *expr.PreInc:
-		g.Write("++")
-		n.Variable.Walk(g)
-		return false
-	case *expr.PreDec:
-		g.Write("--")
-		n.Variable.Walk(g)
-		return false
+	generate()
+
+	g.stmts = prev
+	return stmts
+}
+
+// generateBlock returns the Go block of the block b.
+func (g *Generator) generateBlock(b *ir.Block) *ast.BlockStmt {
+	return goast.Block(g.generateStmts(func() {
+		g.generateBlockStmts(b)
+	})...)
+}
+
+// generateBlockStmts generates the statements of the block b. The
+// variables narrowed in the block are shadowed by the locals of the
+// narrowed types, so the value is taken from Var only once.
+func (g *Generator) generateBlockStmts(b *ir.Block) {
+	for _, l := range b.Locals {
+		g.emit(goast.Define(goast.Ident(l.Var.GoName()), g.getter(l.T, goast.Ident(l.Var.GoName()))))
+	}
+	for _, l := range b.Locals {
+		l.Var.Local = true
 	}
 
-	return true
+	for _, st := range b.Stmts {
+		g.generateStmt(st)
+	}
+
+	for _, l := range b.Locals {
+		l.Var.Local = false
+	}
 }
 
-func (g GeneratorWalker) Final() {
-	g.WriteToMain("// Code generated by php2go. PLEASE DO NOT EDIT.\n")
-	g.WriteToMain("package " + strings.TrimSuffix(g.filename, ".php") + "\n\n")
+func (g *Generator) packageName() string {
+	if g.pkg != nil {
+		return g.pkg.name
+	}
+	if g.executable {
+		return "main"
+	}
 
-	if len(g.requireImports) != 0 {
-		g.WriteToMain("import (\n")
+	return strings.TrimSuffix(g.filename, ".php")
+}
+
+// runtimePackage is the import path of the runtime.
+const runtimePackage = "github.com/i582/php2go/runtime"
+
+// Final prints the generated code. The code is checked by the parser,
+// if it is not valid, it is written as is and the error is returned.
+// The first error of the generation, like the value whose type has no
+// Go type, is returned as well.
+func (g *Generator) Final() error {
+	// OrderedArray uses CopyArray to copy nested arrays
+	// and UndefinedKey to report reads of missing keys.
+	if g.varInfo.NeedOrderedArray {
+		g.varInfo.NeedArrayCopy = true
+		g.varInfo.NeedIndex = true
+	}
 
-		for imp := range g.requireImports {
-			g.WriteToMain(fmt.Sprintf("\t\"%s\"\n", imp))
+	needRuntime := g.varInfo.NeedRuntime()
+	if g.pkg != nil {
+		g.pkg.merge(*g.varInfo)
+		needRuntime = g.varInfo.CallsRuntime()
+	}
+
+	imports := make([]string, 0, len(g.requireImports))
+	for imp := range g.requireImports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	var decls []ast.Decl
+
+	if len(imports) != 0 || needRuntime && !g.inlineRuntime {
+		// The valid Lparen makes the imports printed in parentheses.
+		importDecl := &ast.GenDecl{Tok: token.IMPORT, Lparen: 1}
+		for _, imp := range imports {
+			importDecl.Specs = append(importDecl.Specs, &ast.ImportSpec{Path: goast.String(imp)})
 		}
 
-		g.WriteToMain(")\n\n")
+		// The names of the runtime are used without the qualifier,
+		// the same way as when the runtime is in the core file.
+		if needRuntime && !g.inlineRuntime {
+			importDecl.Specs = append(importDecl.Specs, &ast.ImportSpec{
+				Name: goast.Ident("."),
+				Path: goast.String(runtimePackage),
+			})
+		}
+
+		decls = append(decls, importDecl)
 	}
 
-	if g.varInfo.NeedGenerate {
-		g.WriteToCore("// Core file\n")
-		g.WriteToCore("// Code generated by php2go. PLEASE DO NOT EDIT.\n")
-		g.WriteToCore("package " + strings.TrimSuffix(g.filename, ".php") + "\n")
-		g.WriteToCore(`
-import (
-	"fmt"
-)
-`)
+	decls = append(decls, g.consts...)
+	decls = append(decls, g.decls...)
+
+	if g.pkg == nil {
+		index, err := indexDecls(g.varInfo, g.strictArrayKeys)
+		if err != nil {
+			g.fail(err)
+		}
+		decls = append(decls, index...)
+	}
 
-		g.WriteToCore(g.varInfo.Generate())
+	if needRuntime && g.inlineRuntime && g.pkg == nil {
+		g.WriteToCore(inline.Source(g.packageName()))
 	}
 
-	g.WriteToMain(g.mainWriter.String())
+	src, err := printFile(g.packageName(), decls)
+	g.WriteToMain(string(src))
+
+	if g.err != nil {
+		return g.err
+	}
+	return err
+}
+
+// indexDecls returns the functions reading the elements of slices,
+// which report the reads out of range by the policy of the package.
+func indexDecls(v *types.VarInfo, strictArrayKeys bool) ([]ast.Decl, error) {
+	return v.IndexFunctions(strictArrayKeys)
 }
 
-func (g GeneratorWalker) WithNewContext() GeneratorWalker {
-	newCtx := &ctx.Context{
-		Variables:       variable.NewTable(),
-		CurrentFunction: nil,
+// fail records the error of the generation at the current position,
+// Final returns the first of them.
+func (g *Generator) fail(err error) {
+	if g.err == nil {
+		g.err = fmt.Errorf("%s:%d: %v", g.filename, g.pos.Line, err)
 	}
-	gg := g
-	gg.ctx = newCtx
-	return gg
 }
 
-func (g GeneratorWalker) WithContext(c *ctx.Context) GeneratorWalker {
-	gg := g
-	gg.ctx = c
-	return gg
+// goType returns the Go type of the values of the types tp. If the
+// types have no Go type, the error is recorded and interface{} is
+// returned, so the generation goes on.
+func (g *Generator) goType(tp types.Types) ast.Expr {
+	res, err := tp.GoType()
+	if err != nil {
+		g.fail(err)
+		return goast.InterfaceType()
+	}
+	return res
 }
 
-func (g GeneratorWalker) GenerateIndents() {
-	for i := 0; i < g.indents; i++ {
-		g.Write("\t")
+// getter returns the read of the value of the single type tp from x,
+// see types.Types.Getter, the errors are recorded like by goType.
+func (g *Generator) getter(tp types.Types, x ast.Expr) ast.Expr {
+	res, err := tp.Getter(x)
+	if err != nil {
+		g.fail(err)
+		return x
 	}
+	return res
+}
+
+// printFile returns the code of the file of the package pkg with the
+// declarations separated by blank lines. The code is parsed and is
+// formatted by gofmt, if it is not valid, the code is returned as is
+// along with the error.
+func printFile(pkg string, decls []ast.Decl) ([]byte, error) {
+	return appendDecls("// Code generated by php2go. PLEASE DO NOT EDIT.\npackage "+pkg+"\n", decls)
 }
 
-func (g *GeneratorWalker) Write(s string) {
-	_, _ = g.mainWriter.Write([]byte(s))
+// appendDecls returns the code of the file src followed by the
+// declarations, which is checked and formatted like by printFile.
+func appendDecls(src string, decls []ast.Decl) ([]byte, error) {
+	fset := token.NewFileSet()
+
+	var buf bytes.Buffer
+	buf.WriteString(src)
+
+	for _, d := range decls {
+		buf.WriteString("\n")
+		if err := format.Node(&buf, fset, d); err != nil {
+			return buf.Bytes(), fmt.Errorf("generated code is not valid: %v", err)
+		}
+		buf.WriteString("\n")
+	}
+
+	res, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("generated code is not valid: %v", err)
+	}
+
+	return res, nil
 }
 
-func (g *GeneratorWalker) WriteToMain(s string) {
+func (g *Generator) WriteToMain(s string) {
 	_, _ = g.main.Write([]byte(s))
 }
 
-func (g *GeneratorWalker) WriteToCore(s string) {
+func (g *Generator) WriteToCore(s string) {
 	_, _ = g.core.Write([]byte(s))
 }
 
-func (g *GeneratorWalker) GenerateEcho(e *stmt.Echo) bool {
-	g.GenerateIndents()
+func (g *Generator) GenerateEcho(e *ir.Echo) {
 	g.requireImports["fmt"] = struct{}{}
-	g.Write("fmt.Print(")
-	g.ctx.InPrintFunctionCall = true
-	for i, ex := range e.Exprs {
-		ex.Walk(g)
-		if i < len(e.Exprs)-1 {
-			g.Write(", ")
+	g.flags.inPrint = true
+
+	var args []ast.Expr
+	for _, ex := range e.Args {
+		var arg ast.Expr
+		tp := ex.Type()
+		// null is printed as the empty string.
+		if tp.Is(types.Null) {
+			arg = goast.String("")
+		} else {
+			arg = g.generateExpr(ex)
+		}
+		// Variables of union type are printed by their access.
+		if _, ok := ex.(*ir.Var); !ok && tp.Len() > 1 {
+			arg = goast.Method(arg, "String")
 		}
+		args = append(args, arg)
 	}
-	g.ctx.InPrintFunctionCall = false
-	g.Write(")\n")
-	return false
+
+	g.flags.inPrint = false
+	g.emit(goast.ExprStmt(goast.Method(goast.Ident("fmt"), "Print", args...)))
 }
 
-func (g *GeneratorWalker) GenerateArrayDimFetch(f *expr.ArrayDimFetch) bool {
-	f.Variable.Walk(g)
-	g.Write("[")
-	f.Dim.Walk(g)
-	g.Write("]")
-	return false
+// GenerateArrayDimFetch generates the read of the array element, the
+// runtime functions report the undefined keys and indexes out of range.
+func (g *Generator) GenerateArrayDimFetch(f *ir.Index) ast.Expr {
+	arrType := f.X.Type()
+	tp := f.T
+
+	switch {
+	case arrType.IsOrderedArray():
+		arr := g.generateExpr(f.X)
+		fetch := goast.Method(arr, "Fetch", g.generateOperand(f.Key), g.zeroValue(tp), g.position(f), types.ArrayKeysPolicy(g.strictArrayKeys))
+		return g.generateFromInterface(tp, fetch)
+
+	case arrType.Is(types.Arr):
+		g.varInfo.AddIndexType(arrType.Types[0])
+		arr := g.generateExpr(f.X)
+		res := goast.CallName("Index"+utils.TransformType(arrType.String()), arr, g.generateOperand(f.Key), g.position(f))
+
+		// The element of Var slice with the type known by its key.
+		if elemType := arrType.ElementType(); !elemType.SingleType() && tp.SingleType() {
+			return g.getter(tp, res)
+		}
+		return res
+
+	case arrType.Contains(types.NewType(types.Arr)):
+		// The array is stored in Var.
+		g.varInfo.NeedIndex = true
+		arr := g.generateOperand(f.X)
+		fetch := goast.CallName("Fetch", arr, g.generateOperand(f.Key), g.zeroValue(tp), g.position(f), types.ArrayKeysPolicy(g.strictArrayKeys))
+		return g.generateFromInterface(tp, fetch)
+	}
+
+	arr := g.generateExpr(f.X)
+	return goast.Index(arr, g.generateOperand(f.Key))
 }
 
-func (g *GeneratorWalker) GenerateArray(a *expr.ShortArray) bool {
-	if len(a.Items) == 0 {
-		g.Write("[]Var{}")
-		return false
+// generateOperand generates n that is passed to the runtime function,
+// so the value of union type is passed as Var regardless of the context.
+func (g *Generator) generateOperand(n ir.Expr) ast.Expr {
+	prev := g.flags
+	g.flags.inPrint, g.flags.inBoolean, g.flags.inCompare = false, false, false
+
+	res := g.generateExpr(n)
+
+	g.flags.inPrint, g.flags.inBoolean, g.flags.inCompare = prev.inPrint, prev.inBoolean, prev.inCompare
+	return res
+}
+
+// position returns the Go string literal with the position
+// of n in the PHP source, which is used in runtime messages.
+func (g *Generator) position(n ir.Node) ast.Expr {
+	return goast.String(fmt.Sprintf("%s on line %d", g.filename, n.Position().Line))
+}
+
+// zeroValue returns the Go zero value of the type tp,
+// which is read instead of null for values of single type.
+func (g *Generator) zeroValue(tp types.Types) ast.Expr {
+	if !tp.SingleType() {
+		return goast.Nil()
 	}
 
-	isAssoc := a.Items[0].(*expr.ArrayItem).Key != nil
-	for _, item := range a.Items {
-		haveKey := item.(*expr.ArrayItem).Key != nil
+	switch tp.Types[0].BaseType {
+	case types.Integer:
+		return goast.Int64("0")
+	case types.Float:
+		return goast.CallName("float64", goast.Int(0))
+	case types.String:
+		return goast.String("")
+	case types.Bool:
+		return goast.Bool(false)
+	case types.Null:
+		return goast.Nil()
+	}
+
+	return goast.Convert(g.goType(tp), goast.Nil())
+}
 
-		if isAssoc && !haveKey {
-			panic("mixed array key")
+// GenerateArray generates the array literal. Its type can differ from
+// the type of the PHP literal itself if the literal is nested into
+// another array with elements of other types.
+func (g *Generator) GenerateArray(a *ir.ArrayLit) ast.Expr {
+	tp := a.T
+
+	g.varInfo.AddTypes(tp)
+	g.varInfo.AddTypes(tp.ElementType())
+
+	if len(a.Items) == 0 {
+		switch {
+		case tp.IsOrderedArray():
+			return goast.CallName("NewOrderedArray")
+		case tp.Is(types.Arr):
+			return goast.Composite(g.goType(tp))
 		}
+		return goast.Composite(goast.SliceType(goast.InterfaceType()))
 	}
 
-	if isAssoc {
+	if tp.IsOrderedArray() {
 		return g.GenerateAssociativeArray(a)
-	} else {
-		return g.GeneratePlainArray(a)
 	}
 
-	return false
+	return g.GeneratePlainArray(a)
 }
 
-func (g *GeneratorWalker) GenerateAssociativeArray(a *expr.ShortArray) bool {
-	valType := solver.ExprType(g.ctx, a.Items[0].(*expr.ArrayItem).Val)
-	keyType := solver.ExprType(g.ctx, a.Items[0].(*expr.ArrayItem).Key)
+// generateArrayElement generates the value stored as the array element.
+// The elements of union type are stored as Var in slices, and as the
+// underlying value in OrderedArray, which stores interface{}.
+func (g *Generator) generateArrayElement(e *ir.Element) ast.Expr {
+	elemType := e.T
+	ordered := e.Array.IsOrderedArray()
 
-	if !valType.SingleType() {
-		g.Write("map[string]Var{")
-	} else {
-		g.Write("map[" + keyType.String() + "]" + valType.String() + "{")
-		g.varInfo.AddTypes(types.NewTypes(types.NewAssociativeArrayType(keyType, valType, 1)))
+	if _, ok := e.X.(*ir.ArrayLit); ok && elemType.Is(types.Arr) {
+		return g.generateExpr(e.X)
 	}
 
-	for i, item := range a.Items {
-		item := item.(*expr.ArrayItem)
+	valueType := e.X.Type()
 
-		itemType := solver.ExprType(g.ctx, item.Val)
-		if !valType.Equal(itemType) {
-			panic("different types in array")
-		}
+	switch {
+	case ordered && !valueType.SingleType():
+		return goast.Method(g.generateExpr(e.X), "Value")
+	case !ordered && elemType.Is(types.Null):
+		return g.generateNullValue(e.X)
+	case !ordered && !elemType.SingleType() && valueType.GenerateName() != elemType.GenerateName():
+		g.varInfo.AddTypes(elemType)
+		return goast.CallName("New"+elemType.GenerateName()+"FromInterface", g.generateExpr(e.X))
+	}
+
+	return g.generateExpr(e.X)
+}
 
-		item.Key.Walk(g)
+func (g *Generator) GenerateAssociativeArray(a *ir.ArrayLit) ast.Expr {
+	pair := func(item ir.ArrayItem) ast.Expr {
+		// The spread array has int keys, its elements
+		// are appended with the next indexes.
+		if item.Unpack {
+			return goast.CallName("Unpack", g.generateExpr(item.Value))
+		}
 
-		g.Write(": ")
+		key := goast.Nil()
+		if item.Key != nil {
+			key = g.generateExpr(item.Key)
+		}
+		value := g.generateExpr(item.Value)
 
-		item.Val.Walk(g)
+		return goast.Composite(goast.Ident("Pair"), key, value)
+	}
 
-		if i < len(a.Items)-1 {
-			g.Write(", ")
+	if !hasSpread(a) {
+		var pairs []ast.Expr
+		for _, item := range a.Items {
+			pairs = append(pairs, pair(item))
 		}
+		return goast.CallName("NewOrderedArray", pairs...)
 	}
 
-	g.Write("}")
+	pairs := g.appendItems(goast.SliceType(goast.Ident("Pair")), a.Items, pair)
+	return goast.Spread(goast.CallName("NewOrderedArray", pairs))
+}
+
+func (g *Generator) GeneratePlainArray(a *ir.ArrayLit) ast.Expr {
+	elemType := a.T.ElementType()
 
-	return false
+	return g.appendItems(g.goType(a.T), a.Items, func(item ir.ArrayItem) ast.Expr {
+		value := g.generateExpr(item.Value)
+
+		// The elements of the spread array of other
+		// type are converted to the Var elements.
+		if valueElem := item.Value.Type().ElementType(); item.Unpack && !elemType.SingleType() &&
+			valueElem.GenerateName() != elemType.GenerateName() {
+			return goast.CallName("Vars", value)
+		}
+		return value
+	})
 }
 
-func (g *GeneratorWalker) GeneratePlainArray(a *expr.ShortArray) bool {
-	valType := solver.ExprType(g.ctx, a.Items[0].(*expr.ArrayItem).Val)
+func hasSpread(a *ir.ArrayLit) bool {
+	for _, item := range a.Items {
+		if item.Unpack {
+			return true
+		}
+	}
+	return false
+}
 
-	if !valType.SingleType() {
-		g.Write("[]Var{")
-	} else {
-		g.Write("[]" + valType.String() + "{")
-		g.varInfo.AddTypes(types.NewTypes(types.NewPlainArrayType(valType, 1)))
+// appendItems returns the slice literal of the type typ with the
+// elements of the items before the first spread array, the rest are
+// appended to it, each spread array with a separate append. The
+// element of the item, or the slice of the spread array, is returned
+// by elem.
+func (g *Generator) appendItems(typ ast.Expr, items []ir.ArrayItem, elem func(ir.ArrayItem) ast.Expr) ast.Expr {
+	first := len(items)
+	for i, item := range items {
+		if item.Unpack {
+			first = i
+			break
+		}
 	}
 
-	for i, item := range a.Items {
-		item := item.(*expr.ArrayItem)
+	var groups [][]ir.ArrayItem
+	for i := first; i < len(items); i++ {
+		item := items[i]
+		last := len(groups) - 1
 
-		itemType := solver.ExprType(g.ctx, item.Val)
-		if !valType.Equal(itemType) {
-			panic("different types in array")
+		if item.Unpack || i == first || items[i-1].Unpack {
+			groups = append(groups, []ir.ArrayItem{item})
+			continue
 		}
+		groups[last] = append(groups[last], item)
+	}
+
+	var elts []ast.Expr
+	for _, item := range items[:first] {
+		elts = append(elts, elem(item))
+	}
+
+	var res ast.Expr = goast.Composite(typ, elts...)
 
-		item.Walk(g)
+	for _, group := range groups {
+		args := []ast.Expr{res}
+		spread := false
 
-		if i < len(a.Items)-1 {
-			g.Write(", ")
+		for _, item := range group {
+			args = append(args, elem(item))
+			if item.Unpack {
+				spread = true
+			}
 		}
+
+		call := goast.CallName("append", args...)
+		if spread {
+			call = goast.Spread(call)
+		}
+		res = call
 	}
 
-	g.Write("}")
+	return res
+}
 
-	return false
+// enterLoop returns the label of the loop, if break or continue of a
+// nested loop leaves it, and adds it to the labels of the loops.
+func (g *Generator) enterLoop(labeled bool) string {
+	label := ""
+	if labeled {
+		g.tempVars++
+		label = fmt.Sprintf("_loop%d", g.tempVars)
+	}
+
+	g.loopLabels = append(g.loopLabels, label)
+
+	return label
 }
 
-func (g *GeneratorWalker) GenerateFor(f *stmt.For) bool {
-	gg := g.WithContext(&f.Ctx)
+// emitLoop emits the loop, which is labeled if the label is not
+// empty, and removes the label from the labels of the loops.
+func (g *Generator) emitLoop(label string, loop ast.Stmt) {
+	g.loopLabels = g.loopLabels[:len(g.loopLabels)-1]
 
-	gg.GenerateIndents()
-	gg.Write("for ")
+	if label == "" {
+		g.emit(loop)
+		return
+	}
+	g.emit(&ast.LabeledStmt{Label: goast.Ident(label), Stmt: loop})
+}
 
-	for i, init := range f.Init {
-		init.Walk(&gg)
-		if i < len(f.Init)-1 {
-			gg.Write(", ")
-		}
+// GenerateLoopJump generates break or continue, the jump out of the
+// enclosing loops, like break 2, uses the label of the target loop.
+func (g *Generator) GenerateLoopJump(j *ir.Jump) {
+	st := &ast.BranchStmt{Tok: token.BREAK}
+	if j.Continue {
+		st.Tok = token.CONTINUE
 	}
-	gg.Write("; ")
 
-	for i, cond := range f.Cond {
-		cond.Walk(&gg)
-		if i < len(f.Cond)-1 {
-			gg.Write(", ")
-		}
+	if n := j.Level; n > 1 && n <= len(g.loopLabels) {
+		st.Label = goast.Ident(g.loopLabels[len(g.loopLabels)-n])
 	}
-	gg.Write("; ")
 
-	for i, aftereffect := range f.Loop {
-		aftereffect.Walk(&gg)
-		if i < len(f.Loop)-1 {
-			gg.Write(", ")
+	g.emit(st)
+}
+
+func (g *Generator) GenerateFor(f *ir.For) {
+	label := g.enterLoop(f.Labeled)
+	loop := &ast.ForStmt{}
+
+	// Go has the single init statement, so the init
+	// of several statements precedes the loop.
+	init := g.generateStmts(func() {
+		for _, st := range f.Init {
+			g.generateStmt(st)
 		}
+	})
+	if len(init) == 1 {
+		loop.Init = init[0]
+	} else {
+		g.emit(init...)
 	}
 
-	gg.Write(" {\n")
-	gg.indents++
+	if f.Cond != nil {
+		loop.Cond = g.generateExpr(f.Cond)
+	}
 
-	f.Stmt.Walk(&gg)
+	post := g.generateStmts(func() {
+		for _, st := range f.Post {
+			g.generateStmt(st)
+		}
+	})
+	switch len(post) {
+	case 0:
+	case 1:
+		loop.Post = post[0]
+	default:
+		g.pos = f.Position()
+		g.fail(fmt.Errorf("for with several loop expressions is not supported"))
+	}
 
-	gg.indents--
-	gg.GenerateIndents()
-	gg.Write("}\n")
+	loop.Body = g.generateBlock(f.Body)
 
-	return false
+	g.emitLoop(label, loop)
 }
 
-func (g *GeneratorWalker) GenerateForeach(f *stmt.Foreach) bool {
-	gg := g.WithContext(&f.Ctx)
+func (g *Generator) GenerateForeach(f *ir.Foreach) {
+	switch f.Kind {
+	case ir.RangeOrdered:
+		g.GenerateOrderedArrayForeach(f)
+		return
+	case ir.RangeGenerator:
+		g.GenerateGeneratorForeach(f)
+		return
+	}
 
-	gg.GenerateIndents()
-	gg.Write("for ")
+	label := g.enterLoop(f.Labeled)
+	loop := &ast.RangeStmt{Tok: token.DEFINE}
 
 	if f.Key != nil {
-		f.Key.Walk(&gg)
+		loop.Key = g.generateExpr(f.Key)
 	} else {
-		gg.Write("_")
+		loop.Key = goast.Ident("_")
 	}
 
-	if f.Variable != nil {
-		gg.Write(", ")
-		f.Variable.Walk(&gg)
+	if f.List != nil {
+		tmp := g.TempVarName()
+		g.destructured[f.List] = tmp
+		loop.Value = goast.Ident(tmp)
+	} else if f.Value != nil {
+		loop.Value = g.generateExpr(f.Value)
 	}
 
-	gg.Write(" := range ")
+	loop.X = g.generateExpr(f.X)
 
-	f.Expr.Walk(&gg)
+	elemType := f.X.Type().ElementType()
 
-	gg.Write(" {\n")
+	loop.Body = goast.Block(g.generateStmts(func() {
+		if f.List != nil {
+			g.generateListItems(f.List)
+		}
 
-	gg.indents++
+		// The value is copied if it is modified in the loop,
+		// otherwise the modification changes the iterated array.
+		if f.CopyValue {
+			lhs := g.generateExpr(f.Value)
+			g.emit(goast.Assign(lhs, g.generateArrayCopy(elemType, g.generateExpr(f.Value))))
+		}
 
-	f.Stmt.Walk(&gg)
+		g.generateBlockStmts(f.Body)
+	})...)
 
-	gg.indents--
-	gg.GenerateIndents()
-	gg.Write("}\n")
+	g.emitLoop(label, loop)
+}
 
-	return false
+func (g *Generator) GenerateWhile(wl *ir.While) {
+	label := g.enterLoop(wl.Labeled)
+
+	cond := g.generateExpr(wl.Cond)
+	body := g.generateBlock(wl.Body)
+
+	g.emitLoop(label, &ast.ForStmt{Cond: cond, Body: body})
 }
 
-func (g *GeneratorWalker) GenerateWhile(wl *stmt.While) bool {
-	gg := g.WithContext(&wl.Ctx)
+func (g *Generator) GenerateIf(i *ir.If) {
+	// The variables assigned in the branches are declared before the if.
+	for _, v := range i.Vars {
+		g.emit(goast.VarDecl(v.GoName(), g.goType(v.Type)))
+	}
 
-	gg.GenerateIndents()
-	gg.Write("for ")
+	// The union value is converted to bool like PHP does.
+	condType := i.Cond.Type()
+	g.flags.inBoolean = !condType.SingleType()
+	cond := g.generateExpr(i.Cond)
+	g.flags.inBoolean = false
 
-	wl.Cond.Walk(&gg)
+	ifStmt := &ast.IfStmt{Cond: cond}
+	ifStmt.Body = g.generateBlock(i.Then)
+	if i.Else != nil {
+		ifStmt.Else = g.generateBlock(i.Else)
+	}
 
-	gg.Write(" {\n")
-	gg.indents++
+	g.emit(ifStmt)
+}
 
-	wl.Stmt.Walk(&gg)
+// GenerateInstanceOf generates instanceof, only generators are objects,
+// so the check of other classes is always false.
+func (g *Generator) GenerateInstanceOf(n *ir.InstanceOf) ast.Expr {
+	tp := n.X.Type()
 
-	gg.indents--
-	gg.GenerateIndents()
-	gg.Write("}\n")
+	switch {
+	case !n.Known:
+		return goast.Bool(false)
+	case tp.SingleType():
+		return goast.Bool(tp.Is(n.Class.BaseType))
+	}
 
-	return false
+	g.varInfo.AddTypes(tp)
+	valueType := goast.Sel(g.generateOperand(n.X), "Type")
+	return goast.Binary(valueType, token.EQL, goast.Ident("Constant"+utils.TransformType(n.Class.String())))
 }
 
-func (g *GeneratorWalker) GenerateIf(i *stmt.If) bool {
-	gg := g.WithContext(&i.IfCtx)
+// generateIdenticalOp generates === and !==, which compare the types of
+// the operands before their values, so the values of different types
+// are never identical, like 1 and 1.0, unlike for == and !=, which
+// convert them. The value of union type is compared by its type first.
+func (g *Generator) generateIdenticalOp(left ir.Expr, right ir.Expr, not bool) ast.Expr {
+	if isNull(left) {
+		left, right = right, left
+	}
+	if isNull(right) {
+		tp := left.Type()
+		if tp.SingleType() {
+			return goast.Bool(tp.Is(types.Null) != not)
+		}
 
-	gg.ctx.InBranching = true
+		isNull := goast.Method(g.generateOperand(left), "IsNull")
+		if not {
+			return goast.Unary(token.NOT, isNull)
+		}
+		return isNull
+	}
 
-	for _, v := range g.ctx.Variables.Vars {
-		if v.FromIfElse && !v.WasInitialize {
-			gg.GenerateIndents()
-			gg.Write(fmt.Sprintf("var %s %s\n", v.Name, v.Type.GenerateName()))
-			v.WasInitialize = true
+	leftType, rightType := left.Type(), right.Type()
+	if leftType.SingleType() && !rightType.SingleType() {
+		left, right = right, left
+		leftType, rightType = rightType, leftType
+	}
+
+	var res ast.Expr
+	switch {
+	case leftType.SingleType():
+		if leftType.GenerateName() != rightType.GenerateName() {
+			return goast.Bool(not)
+		}
+		if not {
+			return g.generateBinaryComparisonOp(left, right, token.NEQ, "NotEqual")
 		}
+		return g.generateBinaryComparisonOp(left, right, token.EQL, "Equal")
+
+	case !rightType.SingleType():
+		res = g.generateRuntimeOp(left, right, "Identical")
+
+	case !leftType.Contains(rightType.Types[0]):
+		return goast.Bool(not)
+
+	default:
+		res = g.generateIdenticalTo(left, right)
 	}
 
-	gg.GenerateIndents()
-	gg.Write("if ")
-	gg.ctx.InCondition = true
-	i.Cond.Walk(&gg)
-	gg.ctx.InCondition = false
-	gg.Write(" {\n")
-	gg.indents++
+	if not {
+		return goast.Unary(token.NOT, res)
+	}
+	return res
+}
 
-	i.Stmt.Walk(&gg)
+// generateIdenticalTo generates the check that the value of union type
+// holds the value of the single type, its type is checked first. The
+// union value other than the variable is passed to the runtime, so it is
+// evaluated once.
+func (g *Generator) generateIdenticalTo(union ir.Expr, value ir.Expr) ast.Expr {
+	unionType, valueType := union.Type(), value.Type()
+	if _, ok := union.(*ir.Var); !ok || !valueType.Types[0].IsScalar() {
+		return g.generateRuntimeOp(union, value, "Identical")
+	}
 
-	gg.indents--
-	gg.GenerateIndents()
-	gg.Write("}")
+	g.varInfo.AddTypes(unionType)
 
-	if i.Else != nil {
-		gg := g.WithContext(&i.ElseCtx)
-
-		gg.Write(" else {\n")
-		gg.indents++
-		i.Else.Walk(&gg)
-		gg.indents--
-		gg.GenerateIndents()
-		gg.Write("}\n")
+	var hasType ast.Expr
+	if unionType.IsNullable() {
+		hasType = goast.Unary(token.NOT, goast.Method(g.generateOperand(union), "IsNull"))
 	} else {
-		gg.Write("\n")
+		typeName := goast.Ident("Constant" + utils.TransformType(valueType.String()))
+		hasType = goast.Binary(goast.Sel(g.generateOperand(union), "Type"), token.EQL, typeName)
 	}
 
-	gg.ctx.InBranching = false
+	equal := goast.Binary(g.getter(valueType, g.generateOperand(union)), token.EQL, g.generateOperand(value))
+	return goast.Binary(hasType, token.LAND, equal)
+}
 
-	return false
+// isNull reports whether n is the null constant.
+func isNull(n ir.Expr) bool {
+	_, ok := n.(*ir.Null)
+	return ok
 }
 
-func (g *GeneratorWalker) generateBinaryOp(left node.Node, right node.Node, op string) {
-	leftIsFloat := solver.ExprType(g.ctx, left).Is(types.Float)
-	rightIsFloat := solver.ExprType(g.ctx, right).Is(types.Float)
+// runtimeOperators are the functions of Var for the operators
+// whose operands are converted at runtime.
+var runtimeOperators = map[token.Token]string{
+	token.ADD: "Add",
+	token.SUB: "Sub",
+	token.MUL: "Mul",
+	token.QUO: "Div",
+}
 
-	needCastLeftToFloat := !leftIsFloat && rightIsFloat
-	needCastRightToFloat := leftIsFloat && !rightIsFloat
+// generateVarOperand generates the operand of the runtime operator,
+// which is converted to Var if it has a single type.
+func (g *Generator) generateVarOperand(n ir.Expr) ast.Expr {
+	tp := n.Type()
+	if tp.GenerateName() == "Var" {
+		return g.generateOperand(n)
+	}
 
-	utils.WithTypeCast("float64", needCastLeftToFloat, g.Write, func() {
-		left.Walk(g)
-	})
+	if tp.SingleType() && tp.Types[0].IsScalar() {
+		return goast.CallName(tp.VarConstructor(), g.generateOperand(n))
+	}
 
-	g.Write(" " + op + " ")
+	return goast.CallName("NewVarFromInterface", g.generateOperand(n))
+}
 
-	utils.WithTypeCast("float64", needCastRightToFloat, g.Write, func() {
-		right.Walk(g)
-	})
+// generateRuntimeOp generates the call of the runtime operator fn.
+func (g *Generator) generateRuntimeOp(left ir.Expr, right ir.Expr, fn string) ast.Expr {
+	g.varInfo.NeedGenerate = true
+
+	x := g.generateVarOperand(left)
+	return goast.CallName(fn, x, g.generateVarOperand(right))
 }
 
-func (g *GeneratorWalker) generateBinaryComparisonOp(left node.Node, right node.Node, op, fullopname string) {
-	g.ctx.InCompare = true
-	leftType := solver.ExprType(g.ctx, left)
-	rightType := solver.ExprType(g.ctx, right)
-	if !leftType.SingleType() {
-		left.Walk(g)
-		g.Write(fmt.Sprintf(".CompareWith%s(", utils.TransformType(rightType.String())))
-		right.Walk(g)
-		g.Write(", " + fullopname + ")")
+// generateBinaryOp generates the arithmetic operator, the result of
+// the runtime operator is converted if it is used as a condition.
+func (g *Generator) generateBinaryOp(left ir.Expr, right ir.Expr, op token.Token) ast.Expr {
+	if solver.IsJuggledArithmetic(left.Type(), right.Type()) {
+		return g.generateRuntimeArithmeticOp(left, right, op)
+	}
+
+	return g.generateCastBinaryOp(left, right, op)
+}
+
+// generateRuntimeArithmeticOp generates the runtime operator for op,
+// the result is converted if it is used as a condition.
+func (g *Generator) generateRuntimeArithmeticOp(left ir.Expr, right ir.Expr, op token.Token) ast.Expr {
+	res := g.generateRuntimeOp(left, right, runtimeOperators[op])
+	if g.flags.inBoolean {
+		return goast.Method(res, "Bool")
+	}
+	return res
+}
+
+// generateDivision generates the division, the integers are divided
+// as floats if they are not evenly divisible, and by the runtime
+// operator if this is not known at translation time.
+func (g *Generator) generateDivision(n *ir.Binary) ast.Expr {
+	if !n.X.Type().Is(types.Integer) || !n.Y.Type().Is(types.Integer) || n.T.Is(types.Integer) {
+		return g.generateBinaryOp(n.X, n.Y, token.QUO)
+	}
+
+	if n.T.Is(types.Float) {
+		x := goast.CallName("float64", g.generateExpr(n.X))
+		return goast.Binary(x, token.QUO, goast.CallName("float64", g.generateExpr(n.Y)))
+	}
+	return g.generateRuntimeArithmeticOp(n.X, n.Y, token.QUO)
+}
+
+// generateCastBinaryOp generates the operator of Go, the integer
+// operand is converted to float64 if the other one is float64.
+func (g *Generator) generateCastBinaryOp(left ir.Expr, right ir.Expr, op token.Token) ast.Expr {
+	leftIsFloat := left.Type().Is(types.Float)
+	rightIsFloat := right.Type().Is(types.Float)
+
+	x := g.generateExpr(left)
+	if !leftIsFloat && rightIsFloat {
+		x = goast.CallName("float64", x)
+	}
+
+	y := g.generateExpr(right)
+	if leftIsFloat && !rightIsFloat {
+		y = goast.CallName("float64", y)
+	}
+
+	return goast.Binary(x, op, y)
+}
+
+func (g *Generator) generateBinaryComparisonOp(left ir.Expr, right ir.Expr, op token.Token, fullopname string) ast.Expr {
+	// The operands of the comparison in a condition are not booleans,
+	// and the operands of the comparison in echo are not printed.
+	inBoolean, inPrint := g.flags.inBoolean, g.flags.inPrint
+	g.flags.inBoolean, g.flags.inPrint = false, false
+	defer func() { g.flags.inBoolean, g.flags.inPrint = inBoolean, inPrint }()
+
+	g.flags.inCompare = true
+	leftType := left.Type()
+	rightType := right.Type()
+
+	var res ast.Expr
+	if !leftType.SingleType() && rightType.SingleType() && !rightType.Types[0].IsScalar() && !rightType.Is(types.Null) {
+		// Arrays and objects are not compared with the values of Var.
+		res = goast.Bool(false)
+	} else if !leftType.SingleType() && rightType.SingleType() {
+		x := g.generateExpr(left)
+		res = goast.Method(x, "CompareWith"+utils.TransformType(rightType.String()), g.generateExpr(right), goast.Ident(fullopname))
+	} else if leftType.Is(types.Null) || rightType.Is(types.Null) {
+		res = g.generateNullComparison(left, right, leftType, rightType, op)
+	} else if solver.IsJuggledComparison(leftType, rightType) {
+		res = goast.Binary(g.generateRuntimeOp(left, right, "Compare"), op, goast.Int(0))
 	} else {
-		left.Walk(g)
-		g.Write(" " + op + " ")
-		right.Walk(g)
+		res = g.generateCastBinaryOp(left, right, op)
 	}
-	g.ctx.InCompare = false
+	g.flags.inCompare = false
+
+	return res
 }
 
-func (g *GeneratorWalker) generateBinaryLogicalOp(left node.Node, right node.Node, op string) {
-	g.ctx.InBoolean = true
-	left.Walk(g)
-	g.Write(" " + op + " ")
-	g.ctx.InBoolean = true
-	right.Walk(g)
-	g.ctx.InBoolean = false
+// generateNullComparison generates the comparison with null, which
+// is converted to the zero value of the type of the other operand.
+func (g *Generator) generateNullComparison(left ir.Expr, right ir.Expr, leftType, rightType types.Types, op token.Token) ast.Expr {
+	switch {
+	case leftType.Is(types.Null) && rightType.Is(types.Null):
+		return goast.Bool(op == token.EQL || op == token.LEQ || op == token.GEQ)
+	case leftType.Is(types.Null):
+		return goast.Binary(g.zeroValue(rightType), op, g.generateExpr(right))
+	}
+
+	return goast.Binary(g.generateExpr(left), op, g.zeroValue(leftType))
 }
 
-func (g *GeneratorWalker) GenerateBinaryOps(n node.Node) bool {
-	switch n := n.(type) {
-	case *binary.Plus:
-		g.generateBinaryOp(n.Left, n.Right, "+")
-	case *binary.Minus:
-		g.generateBinaryOp(n.Left, n.Right, "-")
-	case *binary.Mul:
-		g.generateBinaryOp(n.Left, n.Right, "*")
-	case *binary.Div:
-		g.generateBinaryOp(n.Left, n.Right, "/")
-
-	case *binary.Concat:
-		n.Left.Walk(g)
-		g.Write(" + ")
-		n.Right.Walk(g)
-
-	case *binary.Equal:
-		g.generateBinaryComparisonOp(n.Left, n.Right, "==", "Equal")
-	case *binary.NotEqual:
-		g.generateBinaryComparisonOp(n.Left, n.Right, "!=", "NotEqual")
-	case *binary.Smaller:
-		g.generateBinaryComparisonOp(n.Left, n.Right, "<", "Smaller")
-	case *binary.SmallerOrEqual:
-		g.generateBinaryComparisonOp(n.Left, n.Right, "<=", "SmallerEqual")
-	case *binary.Greater:
-		g.generateBinaryComparisonOp(n.Left, n.Right, ">", "Greater")
-	case *binary.GreaterOrEqual:
-		g.generateBinaryComparisonOp(n.Left, n.Right, ">=", "GreaterEqual")
-
-	case *binary.BooleanAnd:
-		g.generateBinaryLogicalOp(n.Left, n.Right, "&&")
-	case *binary.BooleanOr:
-		g.generateBinaryLogicalOp(n.Left, n.Right, "||")
+func (g *Generator) generateBinaryLogicalOp(left ir.Expr, right ir.Expr, op token.Token) ast.Expr {
+	g.flags.inBoolean = true
+	x := g.generateExpr(left)
+	g.flags.inBoolean = true
+	y := g.generateExpr(right)
+	g.flags.inBoolean = false
+
+	return goast.Binary(x, op, y)
+}
+
+func (g *Generator) GenerateBinaryOps(n *ir.Binary) ast.Expr {
+	switch n.Op {
+	case ir.Add:
+		return g.generateBinaryOp(n.X, n.Y, token.ADD)
+	case ir.Sub:
+		return g.generateBinaryOp(n.X, n.Y, token.SUB)
+	case ir.Mul:
+		return g.generateBinaryOp(n.X, n.Y, token.MUL)
+	case ir.Div:
+		return g.generateDivision(n)
+
+	case ir.Concat:
+		if !n.X.Type().Is(types.String) || !n.Y.Type().Is(types.String) {
+			return g.generateRuntimeOp(n.X, n.Y, "Concat")
+		}
+		x := g.generateExpr(n.X)
+		return goast.Binary(x, token.ADD, g.generateExpr(n.Y))
+
+	case ir.Equal:
+		return g.generateBinaryComparisonOp(n.X, n.Y, token.EQL, "Equal")
+	case ir.NotEqual:
+		return g.generateBinaryComparisonOp(n.X, n.Y, token.NEQ, "NotEqual")
+	case ir.Identical:
+		return g.generateIdenticalOp(n.X, n.Y, false)
+	case ir.NotIdentical:
+		return g.generateIdenticalOp(n.X, n.Y, true)
+	case ir.Less:
+		return g.generateBinaryComparisonOp(n.X, n.Y, token.LSS, "Smaller")
+	case ir.LessEqual:
+		return g.generateBinaryComparisonOp(n.X, n.Y, token.LEQ, "SmallerEqual")
+	case ir.Greater:
+		return g.generateBinaryComparisonOp(n.X, n.Y, token.GTR, "Greater")
+	case ir.GreaterEqual:
+		return g.generateBinaryComparisonOp(n.X, n.Y, token.GEQ, "GreaterEqual")
+
+	case ir.And:
+		return g.generateBinaryLogicalOp(n.X, n.Y, token.LAND)
+	case ir.Or:
+		return g.generateBinaryLogicalOp(n.X, n.Y, token.LOR)
 	}
 
-	return false
+	panic(fmt.Sprintf("unsupported binary operator %d", n.Op))
 }
 
-func (g *GeneratorWalker) GenerateReturn(r *stmt.Return) bool {
-	g.GenerateIndents()
-	g.Write("return ")
+func (g *Generator) GenerateReturn(r *ir.Return) {
+	fn := g.fn.Function
+
+	if fn.IsGenerator {
+		if r.Value != nil {
+			g.emit(goast.Return(g.generateExpr(r.Value)))
+		} else {
+			g.emit(goast.Return(goast.Nil()))
+		}
+		return
+	}
 
-	tp := solver.ExprType(g.ctx, r.Expr)
+	var tp types.Types
+	if r.Value != nil {
+		tp = r.Value.Type()
+	}
 	g.varInfo.AddTypes(tp)
 
-	fn, need := g.ctx.CurrentFunction.ReturnType.GenerateCreation(tp)
+	if r.Value != nil && fn.ReturnType.Len() == 0 {
+		g.fail(fmt.Errorf("the type of the result of %s is not inferred", fn.Name))
+		g.emit(goast.Return())
+		return
+	}
 
-	if need {
-		g.Write(fn + "{ Val: ")
+	if rt := fn.ReturnType; rt.IsNullable() && !tp.IsNullable() {
+		g.varInfo.AddTypes(rt)
+		value := goast.Nil()
+		if r.Value != nil {
+			value = g.generateExpr(r.Value)
+		}
+		g.emit(goast.Return(goast.CallName("New"+rt.GenerateName()+"FromInterface", value)))
+		return
 	}
 
-	if r.Expr != nil {
-		r.Expr.Walk(g)
+	// The value of a union type is already stored in Var or Null<T>,
+	// which is converted if the return type is another union.
+	if rt := fn.ReturnType; !tp.SingleType() && tp.Len() != 0 {
+		if tp.GenerateName() == rt.GenerateName() {
+			g.emit(goast.Return(g.generateExpr(r.Value)))
+		} else {
+			g.varInfo.AddTypes(rt)
+			g.emit(goast.Return(g.generateFromInterface(rt, g.generateExpr(r.Value))))
+		}
+		return
 	}
 
-	if need {
-		g.Write(", Type: ")
-		g.Write(fmt.Sprintf("Constant%s", solver.ExprType(g.ctx, r.Expr).String()))
-		g.Write(" }")
+	creation, need := fn.ReturnType.GenerateCreation(tp)
+
+	switch {
+	case fn.ReturnType.Is(types.Null):
+		g.varInfo.AddTypes(fn.ReturnType)
+		g.emit(goast.Return(g.generateNullValue(r.Value)))
+	case r.Value == nil && fn.ReturnType.Len() != 0:
+		// The return without a value gives null.
+		g.varInfo.AddTypes(fn.ReturnType)
+		g.emit(goast.Return(goast.CallName("New" + fn.ReturnType.GenerateName())))
+	case need && tp.Is(types.Null):
+		g.emit(goast.Return(goast.CallName("New" + creation)))
+	case need:
+		g.emit(goast.Return(goast.CallName(tp.VarConstructor(), g.generateExpr(r.Value))))
+	case r.Value != nil:
+		g.emit(goast.Return(g.generateExpr(r.Value)))
+	default:
+		g.emit(goast.Return())
 	}
+}
 
-	g.Write("\n")
+func (g *Generator) GenerateFunctionIsT(fn *ir.IsT) ast.Expr {
+	argType := fn.X.Type()
+	callFunctionName := fn.Func
+
+	g.flags.inIsT = true
+	defer func() { g.flags.inIsT = false }()
+
+	switch {
+	case argType.IsNullable():
+		// Nullable values hold either null or the values of one type.
+		switch callFunctionName {
+		case "Isnull":
+			return goast.Method(g.generateOperand(fn.X), "IsNull")
+		case "Is" + argType.NonNullType().String():
+			return goast.Unary(token.NOT, goast.Method(g.generateOperand(fn.X), "IsNull"))
+		}
+		return goast.Bool(false)
+	case argType.SingleType() && !argType.Is(types.Null):
+		return goast.CallName(callFunctionName+"Simple", g.generateOperand(fn.X))
+	}
 
-	return false
+	return goast.CallName(callFunctionName, g.generateOperand(fn.X))
 }
 
-var isTFunctions = map[string]struct{}{
-	"is_int":    {},
-	"is_float":  {},
-	"is_bool":   {},
-	"is_string": {},
-	"is_null":   {},
-	"is_array":  {},
-}
-
-func (g *GeneratorWalker) GenerateFunctionIsT(fn *expr.FunctionCall, fnName string) bool {
-	argType := solver.ExprType(g.ctx, fn.ArgumentList.Arguments[0])
-
-	g.ctx.InIsTFunction = true
-	var callFunctionName string
-	switch fnName {
-	case "is_int":
-		callFunctionName = "Isint64"
-	case "is_float":
-		callFunctionName = "Isfloat64"
-	case "is_bool":
-		callFunctionName = "Isbool"
-	case "is_string":
-		callFunctionName = "Isstring"
-	case "is_null":
-		callFunctionName = "Isnull"
-	case "is_array":
-		callFunctionName = "Isarray"
+// GenerateFunctionCall generates the function call, the argument is
+// wrapped into Var if the parameter has another union type.
+func (g *Generator) GenerateFunctionCall(fn *ir.Call) ast.Expr {
+	args := g.generateArgs(fn.Args)
 
-	default:
-		g.ctx.InIsTFunction = false
-		return false
+	name := fn.Name
+	if fn.Func != nil {
+		name = fn.Func.GoName()
 	}
 
-	if argType.SingleType() {
-		g.Write(callFunctionName + "Simple(")
-		fn.ArgumentList.Walk(g)
-		g.Write(")")
-	} else {
-		g.Write(callFunctionName + "(")
-		fn.ArgumentList.Walk(g)
-		g.Write(")")
+	call := goast.CallName(name, args...)
+	if fn.Spread {
+		call = goast.Spread(call)
 	}
+	return call
+}
 
-	g.ctx.InIsTFunction = false
+// generateArgs generates the arguments converted to the types of the parameters.
+func (g *Generator) generateArgs(fnArgs []ir.Arg) []ast.Expr {
+	var args []ast.Expr
+	for _, arg := range fnArgs {
+		if arg.Convert.Len() != 0 {
+			g.varInfo.AddTypes(arg.Convert)
+			value := g.generateExpr(arg.Value)
+			args = append(args, goast.CallName("New"+arg.Convert.GenerateName()+"FromInterface", value))
+			continue
+		}
 
-	return false
+		// The null argument without conversion is
+		// passed to the parameter that is only null.
+		args = append(args, g.generateNullValue(arg.Value))
+	}
+	return args
 }
 
-func (g *GeneratorWalker) GenerateFunctionCall(fn *expr.FunctionCall) bool {
-	fnName := utils.NamePartsToString(fn.Function.(*name.Name).Parts)
+// generateAppend generates the slice of the arguments and the spread
+// arrays passed to the variadic parameter, like append([]int64{1}, xs...).
+func (g *Generator) generateAppend(a *ir.Append) ast.Expr {
+	var res ast.Expr = goast.Composite(goast.SliceType(g.paramType(a.T.ElementType())), g.generateArgs(a.Values)...)
+	for _, s := range a.Slices {
+		res = goast.Spread(goast.CallName("append", res, g.generateExpr(s)))
+	}
+	return res
+}
 
-	if _, ok := isTFunctions[fnName]; ok {
-		return g.GenerateFunctionIsT(fn, fnName)
+// generateArrayCopy generates the copy of the array value. Slices
+// of scalars are copied with append, other arrays with CopyArray.
+func (g *Generator) generateArrayCopy(tp types.Types, value ast.Expr) ast.Expr {
+	if tp.Is(types.Arr) && !tp.IsOrderedArray() {
+		elem := tp.ElementType()
+		if elem.SingleType() && !elem.Is(types.Arr) {
+			return goast.Spread(goast.CallName("append", goast.Convert(g.goType(tp), goast.Nil()), value))
+		}
 	}
 
-	g.Write(fnName + "(")
-	fn.ArgumentList.Walk(g)
-	g.Write(")")
+	g.varInfo.NeedArrayCopy = true
+	return goast.TypeAssert(goast.CallName("CopyArray", value), g.goType(tp))
+}
 
-	return false
+// GenerateAssign generates the assignment to the variable. The value
+// of a single type is stored in the variable of union type by the
+// setter of the type.
+func (g *Generator) GenerateAssign(a *ir.Assign) {
+	vr := a.Var.Var
+	g.varInfo.AddTypes(vr.Type)
+
+	singleType := a.ValueType.SingleType()
+
+	g.flags.inAssignLvalue = true
+	lhs := g.generateExpr(a.Var)
+	g.flags.inAssignLvalue = false
+
+	g.flags.inAssignRvalue = true
+
+	value := g.generateExpr
+	if vr.Type.Is(types.Null) {
+		value = g.generateNullValue
+	}
+
+	var st ast.Stmt
+	switch {
+	case singleType && !vr.Type.SingleType():
+		st = goast.ExprStmt(goast.Call(lhs, value(a.Value)))
+	case a.Define:
+		st = goast.Define(lhs, value(a.Value))
+	default:
+		st = goast.Assign(lhs, value(a.Value))
+	}
+
+	g.flags.inAssignRvalue = false
+
+	g.emit(st)
 }
 
-func (g *GeneratorWalker) GenerateAssign(a *assign.Assign) bool {
-	e := a.Expression
-	expressionType := solver.ExprType(g.ctx, e)
+// generateNullValue generates the value of the variable that is only
+// null, which is stored in Var. The null constant becomes the null Var.
+func (g *Generator) generateNullValue(x ir.Expr) ast.Expr {
+	if _, ok := x.(*ir.Null); ok || x == nil {
+		g.varInfo.NeedGenerate = true
+		return goast.CallName("NewVar")
+	}
+	return g.generateExpr(x)
+}
 
-	switch a := a.Variable.(type) {
-	case *expr.Variable:
-		vr := a.Var
-		if !vr.Type.Resolved() {
-			vr.Type = solver.ResolveTypes(g.ctx, vr.Type)
+// GenerateIndexAssign generates the assignment to the array element.
+func (g *Generator) GenerateIndexAssign(a *ir.IndexAssign) {
+	isAddingElement := a.Key == nil
+
+	if a.Container.Type().IsOrderedArray() {
+		arr := g.generateContainer(a.Container)
+		if isAddingElement {
+			g.emit(goast.ExprStmt(goast.Method(arr, "Append", g.generateExpr(a.Value))))
+		} else {
+			key := g.generateExpr(a.Key)
+			g.emit(goast.ExprStmt(goast.Method(arr, "Set", key, g.generateExpr(a.Value))))
 		}
+		return
+	}
 
-		if !vr.Type.ContainsMap(expressionType) {
-			vr.Type.Merge(expressionType)
+	if isAddingElement {
+		arr := g.generateContainer(a.Container)
+		g.emit(goast.Assign(arr, goast.CallName("append", arr, g.generateExpr(a.Value))))
+		return
+	}
+
+	arr := g.generateContainer(a.Container)
+	lhs := goast.Index(arr, g.generateExpr(a.Key))
+	g.emit(goast.Assign(lhs, g.generateExpr(a.Value)))
+}
+
+// generateContainer generates the array modified by the assignment to
+// its element, see ir.NestedRef and ir.IndexRef. If the slice is the element of another slice, like $g[2]
+// in $g[2][] = 7, the missing element with the key equal to the length
+// of the outer slice is appended first, since PHP creates it. The keys
+// other than literals and variables are evaluated once into temporary
+// variables.
+func (g *Generator) generateContainer(container ir.Expr) ast.Expr {
+	var ref *ir.IndexRef
+	switch c := container.(type) {
+	case *ir.NestedRef:
+		return goast.Method(g.generateContainer(c.X), "Nested", g.generateExpr(c.Key))
+	case *ir.IndexRef:
+		ref = c
+	default:
+		return g.generateExpr(container)
+	}
+
+	arr := g.generateContainer(ref.X)
+
+	key := g.generateExpr(ref.Key)
+	switch ref.Key.(type) {
+	case *ir.Lit, *ir.Var:
+	default:
+		tmp := g.TempVarName()
+		g.emit(goast.Define(goast.Ident(tmp), key))
+		key = goast.Ident(tmp)
+	}
+
+	var zero ast.Expr = goast.Nil()
+	switch {
+	case ref.T.IsOrderedArray():
+		zero = goast.CallName("NewOrderedArray")
+	case !ref.T.SingleType():
+		g.varInfo.AddTypes(ref.T)
+		zero = goast.CallName("New" + ref.T.GenerateName())
+	}
+
+	g.emit(&ast.IfStmt{
+		Cond: goast.Binary(goast.CallName("int64", goast.CallName("len", arr)), token.EQL, key),
+		Body: goast.Block(goast.Assign(arr, goast.CallName("append", arr, zero))),
+	})
+
+	return goast.Index(arr, key)
+}
+
+// GenerateIsset generates isset($a, $b['k']) as the checks of all
+// the values, the undefined variables are never set.
+func (g *Generator) GenerateIsset(i *ir.Isset) ast.Expr {
+	var res ast.Expr
+	for _, item := range i.Items {
+		check := g.generateLookupCall("Isset", item, false)
+		if res == nil {
+			res = check
+		} else {
+			res = goast.Binary(res, token.LAND, check)
 		}
-		g.varInfo.AddTypes(vr.Type)
+	}
+
+	return res
+}
+
+// generateLookupCall generates the call of the runtime function fn with
+// the array and the keys of the element, so the missing elements are
+// checked without the access to them. For the undefined variable the
+// result is known and is returned instead.
+func (g *Generator) generateLookupCall(fn string, l *ir.Lookup, undefined bool) ast.Expr {
+	if l.Undefined {
+		return goast.Bool(undefined)
+	}
+
+	g.varInfo.NeedIsset = true
+
+	args := []ast.Expr{g.generateOperand(l.X)}
+	for _, key := range l.Keys {
+		args = append(args, g.generateOperand(key))
+	}
+
+	return goast.CallName(fn, args...)
+}
 
-		vr.CurrentType = expressionType
-		singleType := expressionType.SingleType()
+// GenerateCoalesce generates $x ?? $y as the function literal called in
+// place, so $y is evaluated only if $x is null or is not set. The value
+// of union type is checked by IsNull, the element of the array by Isset,
+// $x of a single type other than null is always read.
+func (g *Generator) GenerateCoalesce(c *ir.Coalesce) ast.Expr {
+	var check ast.Expr
+	var xType types.Types
+	if c.X != nil {
+		xType = c.X.Type()
+	}
 
-		g.ctx.InAssignLvalue = true
-		a.Walk(g)
-		g.ctx.InAssignLvalue = false
+	switch {
+	case c.X == nil:
+		return g.generateConverted(c.Y, c.T)
+	case len(c.Check.Keys) != 0:
+		check = g.generateLookupCall("Isset", c.Check, false)
+	case xType.SingleType() && xType.Is(types.Null):
+		return g.generateConverted(c.Y, c.T)
+	case xType.SingleType():
+		return g.generateConverted(c.X, c.T)
+	case xType.Len() == 0:
+		check = goast.Binary(g.generateOperand(c.X), token.NEQ, goast.Nil())
+	default:
+		check = goast.Unary(token.NOT, goast.Method(g.generateOperand(c.X), "IsNull"))
+	}
 
-		g.ctx.InAssignRvalue = true
+	body := goast.Block(
+		&ast.IfStmt{
+			Cond: check,
+			Body: goast.Block(goast.Return(g.generateConverted(c.X, c.T))),
+		},
+		goast.Return(g.generateConverted(c.Y, c.T)),
+	)
+
+	// The types of the parameters of the functions that
+	// are never called are unknown.
+	result := goast.InterfaceType()
+	if c.T.Len() != 0 {
+		result = g.goType(c.T)
+	}
+
+	return goast.Call(goast.FuncLit(nil, []*ast.Field{goast.Field("", result)}, body))
+}
+
+// generateConverted generates the value x converted to the types tp,
+// the value of union type is read by the getter of the single type,
+// and the value of other types is stored in the union type.
+func (g *Generator) generateConverted(x ir.Expr, tp types.Types) ast.Expr {
+	xType := x.Type()
+
+	switch {
+	case xType.GenerateName() == tp.GenerateName():
+		return g.generateOperand(x)
+	case tp.SingleType():
+		return g.getter(tp, g.generateOperand(x))
+	}
+
+	g.varInfo.AddTypes(tp)
+	if xType.SingleType() && xType.Types[0].IsScalar() && tp.GenerateName() == "Var" {
+		return goast.CallName(xType.VarConstructor(), g.generateOperand(x))
+	}
+	return goast.CallName("New"+tp.GenerateName()+"FromInterface", g.generateOperand(x))
+}
 
-		if vr.WasInitialize && !(singleType && !vr.Type.SingleType()) {
-			g.Write(" = ")
-		} else if !(singleType && !vr.Type.SingleType()) {
-			g.Write(" := ")
-			vr.WasInitialize = true
+// GenerateUnset generates unset() of the variable of union type,
+// which becomes null.
+func (g *Generator) GenerateUnset(u *ir.UnsetVar) {
+	v := u.Var
+
+	g.varInfo.AddTypes(v.Type)
+	null := goast.CallName("New" + v.Type.GenerateName())
+	if u.Define {
+		g.emit(goast.Define(goast.Ident(v.GoName()), null))
+	} else {
+		g.emit(goast.Assign(goast.Ident(v.GoName()), null))
+	}
+}
+
+// generateNestedAppend generates $a['x'][] = $value, where $a is the
+// OrderedArray and $a['x'] is the slice. The slice is taken from the
+// array, possibly missing, and is stored back after the append.
+func (g *Generator) generateNestedAppend(a *ir.NestedAppend) {
+	tmp := g.TempVarName()
+
+	arr := g.generateContainer(a.Container)
+	slice := goast.TypeAssert(goast.Method(arr, "Get", g.generateExpr(a.Key)), g.goType(a.T))
+	g.emit(&ast.AssignStmt{
+		Lhs: []ast.Expr{goast.Ident(tmp), goast.Ident("_")},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{slice},
+	})
+
+	arr = g.generateExpr(a.Container)
+	key := g.generateExpr(a.Key)
+	g.emit(goast.ExprStmt(goast.Method(arr, "Set", key, goast.CallName("append", goast.Ident(tmp), g.generateExpr(a.Value)))))
+}
+
+// GenerateListAssign generates the destructuring assignment.
+// The value is stored in a temporary variable first, so the
+// right-hand side is evaluated before any target is assigned,
+// which keeps the swap idiom [$a, $b] = [$b, $a] correct.
+func (g *Generator) GenerateListAssign(d *ir.Destructure) {
+	tmp := g.TempVarName()
+	g.destructured[d] = tmp
+
+	g.emit(goast.Define(goast.Ident(tmp), g.generateExpr(d.Value)))
+
+	g.generateListItems(d)
+}
+
+// generateListItems generates assignments of the elements
+// of the destructured array to the list targets.
+func (g *Generator) generateListItems(d *ir.Destructure) {
+	for _, st := range d.Items {
+		g.generateStmt(st)
+	}
+}
+
+// generateListElement generates the access to the element
+// of the destructured array.
+func (g *Generator) generateListElement(e *ir.ListElem) ast.Expr {
+	from := goast.Ident(g.destructured[e.List])
+	arrType := e.List.T
+	elemType := e.T
+
+	key := func() ast.Expr {
+		if e.Key == nil {
+			return goast.Int64(strconv.FormatInt(e.Index, 10))
 		}
+		return g.generateExpr(e.Key)
+	}
 
-		e.Walk(g)
+	switch {
+	case arrType.IsOrderedArray():
+		fetch := goast.Method(from, "Fetch", key(), g.zeroValue(elemType), g.position(e), types.ArrayKeysPolicy(g.strictArrayKeys))
+		return g.generateFromInterface(elemType, fetch)
+	case arrType.Is(types.Arr):
+		g.varInfo.AddIndexType(arrType.Types[0])
+		res := goast.CallName("Index"+utils.TransformType(arrType.String()), from, key(), g.position(e))
+
+		// The element of Var slice with the type known by its key.
+		if arrElem := arrType.ElementType(); !arrElem.SingleType() && elemType.SingleType() {
+			return g.getter(elemType, res)
+		}
+		return res
+	}
 
-		if singleType && !vr.Type.SingleType() {
-			g.Write(")")
+	return goast.Index(from, goast.Int(e.Index))
+}
+
+// TempVarName returns a new unique name for a temporary variable.
+func (g *Generator) TempVarName() string {
+	g.tempVars++
+	return fmt.Sprintf("_tmp%d", g.tempVars)
+}
+
+// GenerateVariable generates the access to the variable, the variable
+// of union type is declared by the first access, which holds null.
+func (g *Generator) GenerateVariable(v *ir.Var) ast.Expr {
+	vr := v.Var
+	if v.Declare {
+		if vr.Type.Len() == 0 {
+			g.fail(fmt.Errorf("the type of $%s is not inferred", vr.Name))
 		}
+		g.emit(vr.GenerateDefinition())
+	}
 
-		g.ctx.InAssignRvalue = false
+	// The read gets the type of the variable at this point.
+	current := v.Current
+	if v.Flow.Len() != 0 && !g.flags.inAssignLvalue {
+		current = v.Flow
+	}
 
-	case *expr.ArrayDimFetch:
-		isAddingElement := a.Dim == nil
+	g.varInfo.AddTypes(vr.Type)
+	access, err := vr.GenerateAccess(current, g.flags.inAssignLvalue, g.flags.inAssignRvalue, g.flags.inPrint, g.flags.inCompare, g.flags.inBoolean, g.flags.inIsT)
+	if err != nil {
+		g.fail(err)
+		return goast.Ident(vr.GoName())
+	}
+	return access
+}
 
-		if isAddingElement {
-			a.Variable.Walk(g)
-			g.Write(" = append(")
-			a.Variable.Walk(g)
-			g.Write(", ")
-			e.Walk(g)
-			g.Write(")")
-		} else {
-			a.Variable.Walk(g)
-			g.Write("[")
-			a.Dim.Walk(g)
-			g.Write("]")
+// GenerateConstantDeclaration generates the package level declaration
+// of the constant, constants with a scalar value known at translation
+// time become Go constants, all others become variables.
+func (g *Generator) GenerateConstantDeclaration(d *ir.ConstDecl) {
+	c := d.Const
+	if _, ok := g.declaredConsts[c.Name]; ok {
+		return
+	}
+	g.declaredConsts[c.Name] = struct{}{}
+
+	if c.Runtime {
+		g.consts = append(g.consts,
+			valueDecl(token.VAR, c.GoName(), g.goType(c.Type), nil),
+			valueDecl(token.VAR, c.GoDefinedName(), goast.Ident("bool"), nil),
+		)
+		return
+	}
 
-			g.Write(" = ")
+	isScalar := c.Type.Is(types.Integer) || c.Type.Is(types.Float) ||
+		c.Type.Is(types.String) || c.Type.Is(types.Bool)
 
-			e.Walk(g)
-		}
+	tok := token.VAR
+	if isScalar {
+		tok = token.CONST
 	}
 
-	return false
+	g.consts = append(g.consts, valueDecl(tok, c.GoName(), nil, g.generateExpr(d.Value)))
 }
 
-func (g *GeneratorWalker) GenerateVariable(v *expr.Variable) bool {
-	if !v.Var.WasInitialize && !v.Var.Type.SingleType() {
-		g.Write(v.Var.GenerateDefinition())
-		v.Var.WasInitialize = true
-		g.GenerateIndents()
+// valueDecl returns the declaration of the constant or the variable,
+// either the type or the value can be nil.
+func valueDecl(tok token.Token, name string, typ ast.Expr, value ast.Expr) *ast.GenDecl {
+	spec := &ast.ValueSpec{Names: []*ast.Ident{goast.Ident(name)}, Type: typ}
+	if value != nil {
+		spec.Values = []ast.Expr{value}
 	}
+	return &ast.GenDecl{Tok: tok, Specs: []ast.Spec{spec}}
+}
 
-	g.varInfo.AddTypes(v.Var.Type)
-	g.Write(v.Var.GenerateAccess(g.ctx.InAssignLvalue, g.ctx.InAssignRvalue, g.ctx.InPrintFunctionCall, g.ctx.InCompare, g.ctx.InBoolean, g.ctx.InIsTFunction))
+// GenerateDefine generates the define() call used as a statement.
+func (g *Generator) GenerateDefine(d *ir.Define) {
+	g.GenerateConstantDeclaration(d.Decl)
 
-	return false
+	if d.Value == nil {
+		return
+	}
+
+	g.emit(g.generateRuntimeDefine(d.Decl.Const, d.Value)...)
+}
+
+// generateRuntimeDefine generates the assignment of the value of the
+// constant defined at runtime, and marks the constant as defined.
+func (g *Generator) generateRuntimeDefine(c *constant.Constant, value ir.Expr) []ast.Stmt {
+	return []ast.Stmt{
+		goast.Assign(goast.Ident(c.GoName()), g.generateExpr(value)),
+		goast.Assign(goast.Ident(c.GoDefinedName()), goast.Bool(true)),
+	}
 }
 
-func (g *GeneratorWalker) GenerateFunction(f *stmt.Function) bool {
-	g.ctx = &ctx.Context{
-		Variables:       f.Func.Variables,
-		CurrentFunction: f.Func,
+// GenerateDefineCall generates define() and defined() calls
+// used inside expressions.
+func (g *Generator) GenerateDefineCall(d *ir.DefineExpr) ast.Expr {
+	g.GenerateConstantDeclaration(d.Decl)
+
+	if d.Value == nil {
+		return goast.Bool(true)
 	}
 
-	if f.Func.ReturnType.Len() == 0 {
-		g.Write(fmt.Sprintf("func %s() {\n", f.Func.Name))
+	body := append(g.generateRuntimeDefine(d.Decl.Const, d.Value), goast.Return(goast.Bool(true)))
+	results := []*ast.Field{goast.Field("", goast.Ident("bool"))}
+	return goast.Call(goast.FuncLit(nil, results, goast.Block(body...)))
+}
+
+func (g *Generator) GenerateFunction(f *ir.Func) {
+	g.fn = f
+	g.pos = f.Position()
+	fn := f.Function
+
+	params := g.generateParams(f)
+
+	var results []*ast.Field
+	if fn.ReturnType.Len() != 0 {
+		results = append(results, goast.Field("", g.goType(fn.ReturnType)))
+	}
+
+	var body *ast.BlockStmt
+	if fn.IsGenerator {
+		body = goast.Block(g.generateStmts(func() {
+			g.generateGeneratorBody(f)
+		})...)
 	} else {
-		if !f.Func.ReturnType.Resolved() {
-			f.Func.ReturnType = solver.ResolveTypes(g.ctx, f.Func.ReturnType)
+		body = g.generateBlock(f.Body)
+	}
+
+	g.decls = append(g.decls, &ast.FuncDecl{
+		Name: goast.Ident(fn.GoName()),
+		Type: goast.FuncType(params, results),
+		Body: body,
+	})
+}
+
+// generateParams returns the parameter list of the function,
+// the variadic parameter becomes the Go variadic parameter.
+func (g *Generator) generateParams(f *ir.Func) []*ast.Field {
+	var params []*ast.Field
+
+	for _, p := range f.Params {
+		v := p.Var
+		g.varInfo.AddTypes(v.Type)
+
+		if p.Variadic {
+			params = append(params, goast.Field(v.GoName(), &ast.Ellipsis{Elt: g.paramType(v.Type.ElementType())}))
+			continue
 		}
-		g.Write(fmt.Sprintf("func %s() %s {\n", f.Func.Name, f.Func.ReturnType.GenerateName()))
+
+		params = append(params, goast.Field(v.GoName(), g.paramType(v.Type)))
 	}
 
-	g.indents++
+	return params
+}
 
-	for _, st := range f.Stmts {
-		st.Walk(g)
+func (g *Generator) paramType(tp types.Types) ast.Expr {
+	if tp.Len() == 0 {
+		// The function is never called and the parameter has no type.
+		return goast.InterfaceType()
 	}
+	return g.goType(tp)
+}
 
-	g.Write("}\n\n")
+// generateGeneratorBody wraps the body of the generator
+// function into the closure that is run by the Generator.
+func (g *Generator) generateGeneratorBody(f *ir.Func) {
+	g.varInfo.NeedGenerator = true
 
-	g.indents--
+	body := goast.Block(g.generateStmts(func() {
+		g.generateBlockStmts(f.Body)
 
-	return false
+		stmts := f.Body.Stmts
+		if len(stmts) == 0 {
+			g.emit(goast.Return(goast.Nil()))
+		} else if _, ok := stmts[len(stmts)-1].(*ir.Return); !ok {
+			g.emit(goast.Return(goast.Nil()))
+		}
+	})...)
+
+	params := []*ast.Field{goast.Field("_gen", goast.Pointer(goast.Ident("Yielder")))}
+	results := []*ast.Field{goast.Field("", goast.InterfaceType())}
+
+	g.emit(goast.Return(goast.CallName("NewGenerator", goast.FuncLit(params, results, body))))
+}
+
+// generateFromInterface converts the interface{} value
+// coming from the runtime to the Go type for types tp.
+func (g *Generator) generateFromInterface(tp types.Types, value ast.Expr) ast.Expr {
+	switch {
+	case tp.Len() == 0 || tp.Is(types.Null):
+		return value
+	case tp.SingleType():
+		return goast.TypeAssert(value, g.goType(tp))
+	}
+
+	g.varInfo.AddTypes(tp)
+	return goast.CallName("New"+tp.GenerateName()+"FromInterface", value)
+}
+
+func (g *Generator) generateYieldCall(y *ir.Yield) ast.Expr {
+	gen := goast.Ident("_gen")
+
+	switch {
+	case y.Key != nil:
+		key := g.generateExpr(y.Key)
+		return goast.Method(gen, "YieldWithKey", key, g.generateExpr(y.Value))
+	case y.Value != nil:
+		return goast.Method(gen, "Yield", g.generateExpr(y.Value))
+	}
+
+	return goast.Method(gen, "Yield", goast.Nil())
+}
+
+func (g *Generator) GenerateYieldFrom(y *ir.YieldFrom) ast.Expr {
+	if !y.X.Type().Is(types.Generator) {
+		// Only generators can be delegated to inside expressions,
+		// arrays are handled when yield from is used as a statement.
+		body := goast.Block(g.generateStmts(func() {
+			g.generateYieldFromArray(y)
+			g.emit(goast.Return(goast.Nil()))
+		})...)
+		results := []*ast.Field{goast.Field("", goast.InterfaceType())}
+		return goast.Call(goast.FuncLit(nil, results, body))
+	}
+
+	return g.generateFromInterface(y.T, goast.Method(goast.Ident("_gen"), "YieldFrom", g.generateExpr(y.X)))
+}
+
+// generateYieldFromArray generates the loop that yields
+// the elements of the array with their keys.
+func (g *Generator) generateYieldFromArray(y *ir.YieldFrom) {
+	gen := goast.Ident("_gen")
+
+	if y.X.Type().IsOrderedArray() {
+		e := goast.Ident("e")
+		g.emit(&ast.RangeStmt{
+			Key:   goast.Ident("_"),
+			Value: e,
+			Tok:   token.DEFINE,
+			X:     goast.Method(g.generateExpr(y.X), "Entries"),
+			Body:  goast.Block(goast.ExprStmt(goast.Method(gen, "YieldElement", goast.Sel(e, "Key"), goast.Sel(e, "Value")))),
+		})
+		return
+	}
+
+	k, v := goast.Ident("k"), goast.Ident("v")
+	g.emit(&ast.RangeStmt{
+		Key:   k,
+		Value: v,
+		Tok:   token.DEFINE,
+		X:     g.generateExpr(y.X),
+		Body:  goast.Block(goast.ExprStmt(goast.Method(gen, "YieldElement", goast.CallName("int64", k), v))),
+	})
+}
+
+// generateGeneratorMethodCall generates the call of the Generator method,
+// if the result is used, it is converted to the yielded Go type.
+func (g *Generator) generateGeneratorMethodCall(m *ir.GeneratorMethod, used bool) ast.Expr {
+	gen := g.generateExpr(m.X)
+
+	var args []ast.Expr
+	for _, arg := range m.Args {
+		args = append(args, g.generateExpr(arg))
+	}
+	call := goast.Method(gen, m.Method, args...)
+
+	if m.Convert && used {
+		return g.generateFromInterface(m.T, call)
+	}
+	return call
+}
+
+// GenerateOrderedArrayForeach generates the iteration over the OrderedArray,
+// the elements are iterated in the order in which they were added.
+func (g *Generator) GenerateOrderedArrayForeach(f *ir.Foreach) {
+	entry := g.TempVarName()
+	label := g.enterLoop(f.Labeled)
+
+	entries := goast.Method(g.generateExpr(f.X), "Entries")
+
+	body := g.generateIterationBody(f.Body, goast.Sel(goast.Ident(entry), "Key"), goast.Sel(goast.Ident(entry), "Value"))
+
+	g.emitLoop(label, &ast.RangeStmt{
+		Key:   goast.Ident("_"),
+		Value: goast.Ident(entry),
+		Tok:   token.DEFINE,
+		X:     entries,
+		Body:  body,
+	})
+}
+
+// GenerateGeneratorForeach generates the iteration over the generator.
+func (g *Generator) GenerateGeneratorForeach(f *ir.Foreach) {
+	it := g.TempVarName()
+	label := g.enterLoop(f.Labeled)
+
+	var init ast.Stmt = goast.Define(goast.Ident(it), g.generateExpr(f.X))
+
+	// The generator created for the loop, which is not used after it,
+	// is closed, so the loop left early does not leave its goroutine.
+	// The loop nested in another one closes it after the loop instead
+	// of the deferred call, which waits for the end of the function.
+	_, stored := f.X.(*ir.Var)
+	closeGen := goast.Method(goast.Ident(it), "Close")
+	nested := len(g.loopLabels) > 1
+	if !stored {
+		g.emit(init)
+		init = nil
+		if !nested {
+			g.emit(&ast.DeferStmt{Call: closeGen})
+		}
+	}
+
+	body := g.generateIterationBody(f.Body, goast.Method(goast.Ident(it), "Key"), goast.Method(goast.Ident(it), "Current"))
+
+	g.emitLoop(label, &ast.ForStmt{
+		Init: init,
+		Cond: goast.Method(goast.Ident(it), "Valid"),
+		Post: goast.ExprStmt(goast.Method(goast.Ident(it), "Next")),
+		Body: body,
+	})
+
+	if !stored && nested {
+		g.emit(goast.ExprStmt(closeGen))
+	}
+}
+
+// generateIterationBody generates the body of the foreach over the
+// ordered array or the generator, the key and the value of the current
+// element, which are stored as interface{} at runtime, are key and value.
+func (g *Generator) generateIterationBody(b *ir.Block, key, value ast.Expr) *ast.BlockStmt {
+	prevKey, prevValue := g.loopKey, g.loopValue
+	g.loopKey, g.loopValue = key, value
+
+	body := g.generateBlock(b)
+
+	g.loopKey, g.loopValue = prevKey, prevValue
+	return body
 }
@@ -0,0 +1,208 @@
+// Package goast provides the constructors of the Go syntax tree nodes
+// the translated code is built of. The constructors of the expressions
+// add the parentheses required by the precedence of the operators, so
+// the tree is printed as the code with the same meaning.
+package goast
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+func Ident(name string) *ast.Ident {
+	return ast.NewIdent(name)
+}
+
+// Nil returns the nil identifier.
+func Nil() ast.Expr {
+	return Ident("nil")
+}
+
+// Bool returns the true or false identifier.
+func Bool(b bool) ast.Expr {
+	return Ident(strconv.FormatBool(b))
+}
+
+// String returns the string literal of the value s.
+func String(s string) *ast.BasicLit {
+	return &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(s)}
+}
+
+// Int returns the untyped integer literal of the value i.
+func Int(i int64) *ast.BasicLit {
+	return &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(i, 10)}
+}
+
+// Int64 returns the integer literal converted to int64, like int64(1),
+// value is the literal as it is written in the source.
+func Int64(value string) ast.Expr {
+	return CallName("int64", &ast.BasicLit{Kind: token.INT, Value: value})
+}
+
+// Float returns the float literal written as value in the source.
+func Float(value string) *ast.BasicLit {
+	return &ast.BasicLit{Kind: token.FLOAT, Value: value}
+}
+
+// InterfaceType returns the empty interface type.
+func InterfaceType() ast.Expr {
+	// The braces on the same line make the printer keep interface{} on one line.
+	return &ast.InterfaceType{Methods: &ast.FieldList{Opening: 1, Closing: 1}}
+}
+
+// SliceType returns the type of slices of elements of type elem.
+func SliceType(elem ast.Expr) ast.Expr {
+	return &ast.ArrayType{Elt: elem}
+}
+
+// Pointer returns the type of pointers to values of type typ.
+func Pointer(typ ast.Expr) ast.Expr {
+	return &ast.StarExpr{X: typ}
+}
+
+// Sel returns the selector x.name.
+func Sel(x ast.Expr, name string) *ast.SelectorExpr {
+	return &ast.SelectorExpr{X: operand(x), Sel: Ident(name)}
+}
+
+// Call returns the call of fun with the arguments args.
+func Call(fun ast.Expr, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{Fun: operand(fun), Args: args}
+}
+
+// CallName returns the call of the function with the name.
+func CallName(name string, args ...ast.Expr) *ast.CallExpr {
+	return Call(Ident(name), args...)
+}
+
+// Method returns the call of the method name of x.
+func Method(x ast.Expr, name string, args ...ast.Expr) *ast.CallExpr {
+	return Call(Sel(x, name), args...)
+}
+
+// Spread returns the call whose last argument is spread with ...
+func Spread(call *ast.CallExpr) *ast.CallExpr {
+	call.Ellipsis = 1
+	return call
+}
+
+// Convert returns the conversion of x to the type typ, the pointer
+// types are parenthesized, like (*OrderedArray)(nil).
+func Convert(typ ast.Expr, x ast.Expr) *ast.CallExpr {
+	if _, ok := typ.(*ast.StarExpr); ok {
+		typ = &ast.ParenExpr{X: typ}
+	}
+	return &ast.CallExpr{Fun: typ, Args: []ast.Expr{x}}
+}
+
+// TypeAssert returns the type assertion x.(typ).
+func TypeAssert(x ast.Expr, typ ast.Expr) ast.Expr {
+	return &ast.TypeAssertExpr{X: operand(x), Type: typ}
+}
+
+// Index returns the index expression x[i].
+func Index(x ast.Expr, i ast.Expr) ast.Expr {
+	return &ast.IndexExpr{X: operand(x), Index: i}
+}
+
+// SliceFrom returns the slice expression x[low:].
+func SliceFrom(x ast.Expr, low ast.Expr) ast.Expr {
+	return &ast.SliceExpr{X: operand(x), Low: low}
+}
+
+// Composite returns the composite literal of the type typ.
+func Composite(typ ast.Expr, elts ...ast.Expr) *ast.CompositeLit {
+	return &ast.CompositeLit{Type: typ, Elts: elts}
+}
+
+// Binary returns the binary expression, the operands of lower
+// precedence than op are parenthesized.
+func Binary(x ast.Expr, op token.Token, y ast.Expr) ast.Expr {
+	prec := op.Precedence()
+	return &ast.BinaryExpr{X: parenthesize(x, prec), Op: op, Y: parenthesize(y, prec+1)}
+}
+
+// Unary returns the unary expression, the operand
+// is parenthesized unless it is a primary expression.
+func Unary(op token.Token, x ast.Expr) ast.Expr {
+	return &ast.UnaryExpr{Op: op, X: operand(x)}
+}
+
+// FuncLit returns the function literal with the body.
+func FuncLit(params []*ast.Field, results []*ast.Field, body *ast.BlockStmt) *ast.FuncLit {
+	return &ast.FuncLit{Type: FuncType(params, results), Body: body}
+}
+
+// FuncType returns the type of functions with the parameters and results.
+func FuncType(params []*ast.Field, results []*ast.Field) *ast.FuncType {
+	tp := &ast.FuncType{Params: &ast.FieldList{List: params}}
+	if len(results) != 0 {
+		tp.Results = &ast.FieldList{List: results}
+	}
+	return tp
+}
+
+// Field returns the field of the parameter list, the name can be empty.
+func Field(name string, typ ast.Expr) *ast.Field {
+	f := &ast.Field{Type: typ}
+	if name != "" {
+		f.Names = []*ast.Ident{Ident(name)}
+	}
+	return f
+}
+
+func Block(stmts ...ast.Stmt) *ast.BlockStmt {
+	return &ast.BlockStmt{List: stmts}
+}
+
+// Define returns the short variable declaration lhs := rhs.
+func Define(lhs ast.Expr, rhs ast.Expr) *ast.AssignStmt {
+	return &ast.AssignStmt{Lhs: []ast.Expr{lhs}, Tok: token.DEFINE, Rhs: []ast.Expr{rhs}}
+}
+
+// Assign returns the assignment lhs = rhs.
+func Assign(lhs ast.Expr, rhs ast.Expr) *ast.AssignStmt {
+	return &ast.AssignStmt{Lhs: []ast.Expr{lhs}, Tok: token.ASSIGN, Rhs: []ast.Expr{rhs}}
+}
+
+func ExprStmt(x ast.Expr) *ast.ExprStmt {
+	return &ast.ExprStmt{X: x}
+}
+
+func Return(results ...ast.Expr) *ast.ReturnStmt {
+	return &ast.ReturnStmt{Results: results}
+}
+
+// VarDecl returns the declaration var name typ.
+func VarDecl(name string, typ ast.Expr) *ast.DeclStmt {
+	return &ast.DeclStmt{Decl: &ast.GenDecl{
+		Tok:   token.VAR,
+		Specs: []ast.Spec{&ast.ValueSpec{Names: []*ast.Ident{Ident(name)}, Type: typ}},
+	}}
+}
+
+// operand parenthesizes x unless it is a primary expression,
+// which can be selected, called, indexed or asserted.
+func operand(x ast.Expr) ast.Expr {
+	return parenthesize(x, token.HighestPrec)
+}
+
+func parenthesize(x ast.Expr, prec int) ast.Expr {
+	if precedence(x) < prec {
+		return &ast.ParenExpr{X: x}
+	}
+	return x
+}
+
+// precedence returns the precedence of the operator of x,
+// primary expressions have the highest precedence.
+func precedence(x ast.Expr) int {
+	switch x := x.(type) {
+	case *ast.BinaryExpr:
+		return x.Op.Precedence()
+	case *ast.UnaryExpr, *ast.StarExpr:
+		return token.UnaryPrec
+	}
+	return token.HighestPrec
+}
@@ -0,0 +1,20 @@
+// The gen command writes the code of the runtime package to source.go.
+package main
+
+import (
+	"io/ioutil"
+	"log"
+
+	"github.com/i582/php2go/src/inline"
+)
+
+func main() {
+	src, err := inline.Render("../../runtime")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("source.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
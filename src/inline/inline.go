@@ -0,0 +1,127 @@
+// Package inline provides the runtime package as the source code that
+// is written to the core file next to the translated code, so that the
+// output has no dependencies.
+package inline
+
+//go:generate go run ./gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source returns the runtime package as the file of the package pkg.
+func Source(pkg string) string {
+	var b strings.Builder
+
+	b.WriteString("// Core file\n")
+	b.WriteString("// Code generated by php2go. PLEASE DO NOT EDIT.\n")
+	b.WriteString("package " + pkg + "\n\n")
+
+	b.WriteString("import (\n")
+	for _, imp := range runtimeImports {
+		b.WriteString("\t\"" + imp + "\"\n")
+	}
+	b.WriteString(")\n")
+
+	b.WriteString(runtimeCode)
+
+	return b.String()
+}
+
+// Render returns the file that holds the code of the runtime package
+// in the directory dir, it is run by go generate.
+func Render(dir string) ([]byte, error) {
+	imports, code, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by go generate; DO NOT EDIT.\n\n")
+	b.WriteString("package inline\n\n")
+
+	b.WriteString("var runtimeImports = []string{\n")
+	for _, imp := range imports {
+		b.WriteString("\t" + strconv.Quote(imp) + ",\n")
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("const runtimeCode = " + quote(code) + "\n")
+
+	return format.Source(b.Bytes())
+}
+
+// load returns the imports and the code without the package clauses
+// and imports of the files of the package in the directory dir.
+func load(dir string) ([]string, string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]bool)
+	var imports []string
+	var code strings.Builder
+
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, imp := range f.Imports {
+			p, _ := strconv.Unquote(imp.Path.Value)
+			if !seen[p] {
+				seen[p] = true
+				imports = append(imports, p)
+			}
+		}
+
+		start := fset.Position(f.Name.End()).Offset
+		for _, decl := range f.Decls {
+			if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+				start = fset.Position(d.End()).Offset
+			}
+		}
+
+		rest := strings.TrimSpace(string(src[start:]))
+		if rest == "" {
+			continue
+		}
+		code.WriteString("\n" + rest + "\n")
+	}
+
+	sort.Strings(imports)
+
+	if code.Len() == 0 {
+		return nil, "", fmt.Errorf("no code in %s", dir)
+	}
+	return imports, code.String(), nil
+}
+
+// quote returns the string as the raw string literal, the backquotes
+// are concatenated as the interpreted string literals.
+func quote(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "` + \"`\" + `") + "`"
+}
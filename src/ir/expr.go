@@ -0,0 +1,332 @@
+package ir
+
+import (
+	"github.com/i582/php2go/src/constant"
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/variable"
+)
+
+// Expr is the expression of the IR.
+type Expr interface {
+	Node
+	Type() types.Types
+}
+
+// Typed is embedded into the expressions to hold their position and type.
+type Typed struct {
+	Pos Pos
+	T   types.Types
+}
+
+func (t Typed) Position() Pos {
+	return t.Pos
+}
+
+func (t Typed) Type() types.Types {
+	return t.T
+}
+
+// LitKind is the kind of the literal.
+type LitKind uint8
+
+const (
+	IntLit LitKind = iota
+	FloatLit
+	StringLit
+)
+
+// Lit is the literal, Value holds the numbers as they are written
+// in the source, and the value of the string.
+type Lit struct {
+	Typed
+	Kind  LitKind
+	Value string
+}
+
+// Name is the named constant translated as is, like true.
+type Name struct {
+	Typed
+	Name string
+}
+
+// Null is the null constant.
+type Null struct {
+	Typed
+}
+
+// Bool is the boolean value known at translation time,
+// like the result of defined() for the constant known
+// at translation time.
+type Bool struct {
+	Typed
+	Value bool
+}
+
+// Var is the access to the variable. Current holds the type the
+// variable is known to have at this point, Flow holds the type found
+// by the dataflow analysis, which is used for reads, it is empty if
+// the analysis knows nothing more than the variable type. Declare is
+// set for the first access to the variable of union type, which
+// declares the variable holding null.
+type Var struct {
+	Typed
+	Var     *variable.Variable
+	Current types.Types
+	Flow    types.Types
+	Declare bool
+}
+
+// ConstRef is the access to the constant. Const is nil for constants
+// that are not declared, then Name holds the name of the constant.
+type ConstRef struct {
+	Typed
+	Const *constant.Constant
+	Name  string
+}
+
+// Defined is the defined() check of the constant defined at runtime.
+type Defined struct {
+	Typed
+	Const *constant.Constant
+}
+
+// DefineExpr is the define() call used inside an expression, or the
+// defined() check of the constant known at translation time, it is true
+// and declares the constant. Value is the value of the constant defined
+// at runtime, which is assigned by the call.
+type DefineExpr struct {
+	Typed
+	Decl  *ConstDecl
+	Value Expr
+}
+
+// BinaryOp is the operator of Binary.
+type BinaryOp uint8
+
+const (
+	Add BinaryOp = iota
+	Sub
+	Mul
+	Div
+	Concat
+	Equal
+	NotEqual
+	Identical
+	NotIdentical
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+	And
+	Or
+)
+
+// Binary is the binary operator.
+type Binary struct {
+	Typed
+	Op BinaryOp
+	X  Expr
+	Y  Expr
+}
+
+// Not is the boolean negation.
+type Not struct {
+	Typed
+	X Expr
+}
+
+// Neg is the unary minus.
+type Neg struct {
+	Typed
+	X Expr
+}
+
+// InstanceOf is the instanceof check of the Class,
+// Known is false for classes that do not exist.
+type InstanceOf struct {
+	Typed
+	X     Expr
+	Class types.Type
+	Known bool
+}
+
+// Isset is isset() of all the Items.
+type Isset struct {
+	Typed
+	Items []*Lookup
+}
+
+// Empty is empty() of the Item.
+type Empty struct {
+	Typed
+	Item *Lookup
+}
+
+// Coalesce is X ?? Y, the value X is read if the Check is set,
+// otherwise Y is evaluated. X is nil if its variable is undefined.
+type Coalesce struct {
+	Typed
+	Check *Lookup
+	X     Expr
+	Y     Expr
+}
+
+// Lookup is the element of the array X checked by isset() and empty(),
+// which is found by the Keys. Undefined is set if the variable is not
+// defined, the element is never set then.
+type Lookup struct {
+	At
+	Undefined bool
+	X         Expr
+	Keys      []Expr
+}
+
+// Index is the read of the element of the array.
+type Index struct {
+	Typed
+	X   Expr
+	Key Expr
+}
+
+// NestedRef is the element of the ordered array that holds the nested
+// ordered array, which is created if it is missing, so the elements are
+// assigned to it, like $a['x']['y'] = 1.
+type NestedRef struct {
+	Typed
+	X   Expr
+	Key Expr
+}
+
+// IndexRef is the element of the slice assigned to.
+type IndexRef struct {
+	Typed
+	X   Expr
+	Key Expr
+}
+
+// ArrayLit is the array literal.
+type ArrayLit struct {
+	Typed
+	Items []ArrayItem
+}
+
+// ArrayItem is the item of the array literal, the Value of the spread
+// array, like ...$a, is not converted to the element type.
+type ArrayItem struct {
+	Key    Expr
+	Value  Expr
+	Unpack bool
+}
+
+// Element is the value X stored as the element of the array of type Array.
+type Element struct {
+	Typed
+	X     Expr
+	Array types.Types
+}
+
+// Copy is the copy of the array X, arrays are values in PHP, so the
+// array is copied where it would be shared otherwise.
+type Copy struct {
+	Typed
+	X Expr
+}
+
+// ElemAt is the element of the array spread into the fixed parameter.
+type ElemAt struct {
+	Typed
+	X     Expr
+	Index int64
+}
+
+// Rest is the rest of the array starting at From,
+// which is spread into the variadic parameter.
+type Rest struct {
+	Typed
+	X    Expr
+	From int64
+}
+
+// Append is the slice of type T of the Values followed by the elements
+// of the Slices, which is spread into the variadic parameter, like
+// append([]int64{1}, xs...) for f(1, ...$xs).
+type Append struct {
+	Typed
+	Values []Arg
+	Slices []Expr
+}
+
+// Call is the function call. Func is nil for the functions that are not
+// declared in the file, like the builtin ones. Spread is set if the last
+// argument is spread into the variadic parameter.
+type Call struct {
+	Typed
+	Func   *function.Function
+	Name   string
+	Args   []Arg
+	Spread bool
+}
+
+// Arg is the argument of the call. Convert holds the union type of the
+// parameter the value is converted to, it is empty if the value has the
+// type of the parameter.
+type Arg struct {
+	Value   Expr
+	Convert types.Types
+}
+
+// IsT is the call of the is_T function, like is_int(). The Func is
+// the runtime function of the check, like Isint64.
+type IsT struct {
+	Typed
+	Func string
+	X    Expr
+}
+
+// GeneratorMethod is the call of the method of the generator X. The
+// Method is the method of the runtime Generator, like Current, if
+// Convert is set, its result is converted to the type of the call.
+type GeneratorMethod struct {
+	Typed
+	X       Expr
+	Method  string
+	Convert bool
+	Args    []Expr
+}
+
+// Yield is the yield expression, the Key is nil for yield
+// without the key, and the Value is nil for yield without
+// the value.
+type Yield struct {
+	Typed
+	Key   Expr
+	Value Expr
+}
+
+// YieldFrom is the yield from of the generator or the array X.
+type YieldFrom struct {
+	Typed
+	X Expr
+}
+
+// LoopKey is the key of the current element of the enclosing foreach
+// over the ordered array or the generator.
+type LoopKey struct {
+	Typed
+}
+
+// LoopValue is the current element of the enclosing foreach
+// over the ordered array or the generator.
+type LoopValue struct {
+	Typed
+}
+
+// ListElem is the element of the destructured array, found by the Key,
+// or by the Index for items without keys.
+type ListElem struct {
+	Typed
+	List  *Destructure
+	Key   Expr
+	Index int64
+}
@@ -0,0 +1,102 @@
+// Package ir defines the intermediate representation of the translated
+// code. The PHP syntax tree is lowered to it by the lower package, and
+// the Go code is generated from it by the generator package.
+//
+// The expressions of the IR are annotated with their types, which are
+// resolved during the lowering, and the variables, functions and
+// constants are referenced by their symbols, so the passes over the IR
+// do not depend on the shapes of the parser nodes.
+package ir
+
+import (
+	"github.com/i582/php2go/src/constant"
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/variable"
+)
+
+// Pos is the position of the node in the PHP source.
+type Pos struct {
+	Line int
+}
+
+// Node is the node of the IR.
+type Node interface {
+	Position() Pos
+}
+
+// At is embedded into the nodes to hold their position.
+type At struct {
+	Pos Pos
+}
+
+func (a At) Position() Pos {
+	return a.Pos
+}
+
+// File is the lowered PHP file.
+type File struct {
+	// Name is the name of the PHP file.
+	Name string
+
+	// Decls holds the functions and constants declared at the top level
+	// in the order of their declaration.
+	Decls []Decl
+
+	// Main holds the top-level code of the file, it is nil
+	// unless the file is lowered in the executable mode.
+	Main *Func
+}
+
+// Decl is the top-level declaration, either *Func or *ConstDecl.
+type Decl interface {
+	Node
+	declNode()
+}
+
+// Func is the function declaration.
+type Func struct {
+	At
+	Function *function.Function
+	Params   []Param
+	Body     *Block
+}
+
+// Param is the parameter of the function.
+type Param struct {
+	Var      *variable.Variable
+	Variadic bool
+}
+
+// ConstDecl is the declaration of the constant. Value is nil for
+// constants whose value is computed at runtime, it is assigned by
+// Define.
+type ConstDecl struct {
+	At
+	Const *constant.Constant
+	Value Expr
+}
+
+func (*Func) declNode()      {}
+func (*ConstDecl) declNode() {}
+
+// Block is the list of statements with its own scope.
+type Block struct {
+	At
+
+	// Vars holds the variables of the scope of the block.
+	Vars variable.Table
+
+	// Locals holds the variables of union types that are read in the
+	// block with their types narrowed, like in the branch guarded by
+	// is_int(). They are shadowed by the locals of the narrowed types.
+	Locals []Local
+
+	Stmts []Stmt
+}
+
+// Local is the variable narrowed to the type T.
+type Local struct {
+	Var *variable.Variable
+	T   types.Types
+}
@@ -0,0 +1,184 @@
+package ir
+
+import (
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/variable"
+)
+
+// Stmt is the statement of the IR.
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+// ExprStmt is the expression used as a statement.
+type ExprStmt struct {
+	At
+	X Expr
+}
+
+// Assign is the assignment of the Value of type ValueType to the variable.
+// Define is set if the assignment declares the variable.
+type Assign struct {
+	At
+	Var       *Var
+	Value     Expr
+	ValueType types.Types
+	Define    bool
+}
+
+// IndexAssign is the assignment to the element of the array Container
+// by the Key, the Value is appended to the array if the Key is nil.
+type IndexAssign struct {
+	At
+	Container Expr
+	Key       Expr
+	Value     Expr
+}
+
+// NestedAppend appends the Value to the array of type T, which is
+// the element of the ordered array Container by the Key, like
+// $a['x'][] = 1. The missing array is created.
+type NestedAppend struct {
+	At
+	Container Expr
+	Key       Expr
+	T         types.Types
+	Value     Expr
+}
+
+// Destructure assigns the elements of the array Value of type T, like
+// list($a, $b) = $arr. The Items assign the elements, which are ListElem.
+// Value is nil in the foreach, which destructures the loop value.
+type Destructure struct {
+	At
+	Value Expr
+	T     types.Types
+	Items []Stmt
+}
+
+// IncDec is the increment or the decrement of X.
+type IncDec struct {
+	At
+	X   Expr
+	Inc bool
+}
+
+// Echo prints the Args.
+type Echo struct {
+	At
+	Args []Expr
+}
+
+// Return returns from the function, the Value is nil for return without value.
+type Return struct {
+	At
+	Value Expr
+}
+
+// If is the if statement, the Else is nil if there is no else branch.
+// Vars holds the variables assigned in the branches that are not declared
+// yet, they are declared before the if.
+type If struct {
+	At
+	Vars []*variable.Variable
+	Cond Expr
+	Then *Block
+	Else *Block
+}
+
+// For is the for loop, Labeled is set if break or continue of a nested
+// loop leaves the loop, so the loop needs the label.
+type For struct {
+	At
+	Init    []Stmt
+	Cond    Expr
+	Post    []Stmt
+	Labeled bool
+	Body    *Block
+}
+
+// While is the while loop.
+type While struct {
+	At
+	Cond    Expr
+	Labeled bool
+	Body    *Block
+}
+
+// ForeachKind is the kind of the iterated value.
+type ForeachKind uint8
+
+const (
+	RangeSlice ForeachKind = iota
+	RangeOrdered
+	RangeGenerator
+)
+
+// Foreach is the foreach loop over X.
+//
+// The loop over the slice assigns the key and the value to the Key and
+// the Value variables, or destructures the value by the List. CopyValue
+// is set if the array value is modified in the loop, so it is copied.
+//
+// The loops over the ordered array and the generator assign the key and
+// the value at the start of the Body, the assigned values are LoopKey
+// and LoopValue.
+type Foreach struct {
+	At
+	Kind      ForeachKind
+	X         Expr
+	Key       Expr
+	Value     Expr
+	List      *Destructure
+	CopyValue bool
+	Labeled   bool
+	Body      *Block
+}
+
+// Jump is break or continue, Level is the number of the loops it leaves.
+type Jump struct {
+	At
+	Continue bool
+	Level    int
+}
+
+// UnsetVar makes the variable of union type null. Define is set if
+// the unset declares the variable.
+type UnsetVar struct {
+	At
+	Var    *variable.Variable
+	Define bool
+}
+
+// DeleteKey deletes the Key of the ordered array Container.
+type DeleteKey struct {
+	At
+	Container Expr
+	Key       Expr
+}
+
+// Define is the define() call used as a statement. Value is the value
+// of the constant defined at runtime.
+type Define struct {
+	At
+	Decl  *ConstDecl
+	Value Expr
+}
+
+func (*ExprStmt) stmtNode()     {}
+func (*Assign) stmtNode()       {}
+func (*IndexAssign) stmtNode()  {}
+func (*NestedAppend) stmtNode() {}
+func (*Destructure) stmtNode()  {}
+func (*IncDec) stmtNode()       {}
+func (*Echo) stmtNode()         {}
+func (*Return) stmtNode()       {}
+func (*If) stmtNode()           {}
+func (*For) stmtNode()          {}
+func (*While) stmtNode()        {}
+func (*Foreach) stmtNode()      {}
+func (*Jump) stmtNode()         {}
+func (*UnsetVar) stmtNode()     {}
+func (*DeleteKey) stmtNode()    {}
+func (*Define) stmtNode()       {}
@@ -0,0 +1,467 @@
+package lower
+
+import (
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/ir"
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/expr/binary"
+	"github.com/i582/php2go/src/php/node/name"
+	"github.com/i582/php2go/src/php/node/scalar"
+	"github.com/i582/php2go/src/solver"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/utils"
+)
+
+// isTFunctions are the type checks lowered to ir.IsT
+// with the runtime functions implementing them.
+var isTFunctions = map[string]string{
+	"is_int":    "Isint64",
+	"is_float":  "Isfloat64",
+	"is_bool":   "Isbool",
+	"is_string": "Isstring",
+	"is_null":   "Isnull",
+	"is_array":  "Isarray",
+}
+
+// generatorMethods are the methods of generators with the methods of
+// the runtime Generator, the results of the methods marked as converted
+// are converted to the yielded Go type.
+var generatorMethods = map[string]struct {
+	name    string
+	convert bool
+}{
+	"current":   {"Current", true},
+	"key":       {"Key", true},
+	"send":      {"Send", true},
+	"getReturn": {"GetReturn", true},
+	"next":      {"Next", false},
+	"valid":     {"Valid", false},
+	"rewind":    {"Rewind", false},
+}
+
+// expr lowers the expression n, the type of the expression is found
+// with the current types of the variables.
+func (l *lowerer) expr(n node.Node) ir.Expr {
+	switch n := n.(type) {
+	case *node.Argument:
+		return l.expr(n.Expr)
+
+	case *expr.ShortArray:
+		return l.arrayLiteral(n, solver.ExprType(l.ctx, n))
+	case *expr.ArrayDimFetch:
+		res := &ir.Index{Typed: l.typed(n)}
+		res.X = l.expr(n.Variable)
+		res.Key = l.expr(n.Dim)
+		return res
+
+	case *expr.FunctionCall:
+		return l.call(n)
+	case *expr.MethodCall:
+		return l.methodCall(n)
+	case *expr.Yield:
+		res := &ir.Yield{Typed: l.typed(n)}
+		if n.Key != nil {
+			res.Key = l.expr(n.Key)
+		}
+		if n.Value != nil {
+			res.Value = l.expr(n.Value)
+		}
+		return res
+	case *expr.YieldFrom:
+		return &ir.YieldFrom{Typed: l.typed(n), X: l.expr(n.Expr)}
+
+	case *expr.Variable:
+		return l.variable(n)
+	case *expr.ConstFetch:
+		return l.constFetch(n)
+
+	case *scalar.Lnumber:
+		return &ir.Lit{Typed: l.typed(n), Kind: ir.IntLit, Value: n.Value}
+	case *scalar.Dnumber:
+		return &ir.Lit{Typed: l.typed(n), Kind: ir.FloatLit, Value: n.Value}
+	case *scalar.String:
+		return &ir.Lit{Typed: l.typed(n), Kind: ir.StringLit, Value: utils.StringLiteralValue(n.Value)}
+	case *name.Name:
+		nm := utils.NamePartsToString(n.Parts)
+		if isNull(nm) {
+			return &ir.Null{Typed: l.typed(n)}
+		}
+		return &ir.Name{Typed: l.typed(n), Name: nm}
+
+	case *binary.Plus:
+		return l.binary(n, ir.Add, n.Left, n.Right)
+	case *binary.Minus:
+		return l.binary(n, ir.Sub, n.Left, n.Right)
+	case *binary.Mul:
+		return l.binary(n, ir.Mul, n.Left, n.Right)
+	case *binary.Div:
+		return l.binary(n, ir.Div, n.Left, n.Right)
+	case *binary.Concat:
+		return l.binary(n, ir.Concat, n.Left, n.Right)
+	case *binary.Equal:
+		return l.binary(n, ir.Equal, n.Left, n.Right)
+	case *binary.NotEqual:
+		return l.binary(n, ir.NotEqual, n.Left, n.Right)
+	case *binary.Identical:
+		if res, ok := l.elementIsNull(n, n.Left, n.Right, false); ok {
+			return res
+		}
+		return l.binary(n, ir.Identical, n.Left, n.Right)
+	case *binary.NotIdentical:
+		if res, ok := l.elementIsNull(n, n.Left, n.Right, true); ok {
+			return res
+		}
+		return l.binary(n, ir.NotIdentical, n.Left, n.Right)
+	case *binary.Smaller:
+		return l.binary(n, ir.Less, n.Left, n.Right)
+	case *binary.SmallerOrEqual:
+		return l.binary(n, ir.LessEqual, n.Left, n.Right)
+	case *binary.Greater:
+		return l.binary(n, ir.Greater, n.Left, n.Right)
+	case *binary.GreaterOrEqual:
+		return l.binary(n, ir.GreaterEqual, n.Left, n.Right)
+	case *binary.BooleanAnd:
+		return l.binary(n, ir.And, n.Left, n.Right)
+	case *binary.BooleanOr:
+		return l.binary(n, ir.Or, n.Left, n.Right)
+
+	case *expr.InstanceOf:
+		class, ok := solver.InstanceOfType(n)
+		return &ir.InstanceOf{Typed: l.typed(n), X: l.expr(n.Expr), Class: class, Known: ok}
+
+	case *expr.BooleanNot:
+		return &ir.Not{Typed: l.typed(n), X: l.expr(n.Expr)}
+
+	case *expr.Isset:
+		res := &ir.Isset{Typed: l.typed(n)}
+		for _, v := range n.Variables {
+			res.Items = append(res.Items, l.lookup(v))
+		}
+		return res
+	case *expr.Empty:
+		return &ir.Empty{Typed: l.typed(n), Item: l.lookup(n.Expr)}
+	case *binary.Coalesce:
+		res := &ir.Coalesce{Typed: l.typed(n), Check: l.lookup(n.Left), Y: l.expr(n.Right)}
+		if !res.Check.Undefined {
+			res.X = l.expr(n.Left)
+		}
+		return res
+
+	case *expr.UnaryMinus:
+		return &ir.Neg{Typed: l.typed(n), X: l.expr(n.Expr)}
+	case *expr.UnaryPlus:
+		return l.expr(n.Expr)
+	}
+
+	l.unsupported(n, "the expression %s is not supported", nodeName(n))
+	return &ir.Null{Typed: typed(n, types.Types{})}
+}
+
+// typed returns the position and the type of n.
+func (l *lowerer) typed(n node.Node) ir.Typed {
+	return typed(n, solver.ExprType(l.ctx, n))
+}
+
+// variable lowers the access to the variable with its types at this point,
+// the first access declares the variable of union type.
+func (l *lowerer) variable(v *expr.Variable) *ir.Var {
+	vr := l.ctx.Session.Variable(v)
+	res := &ir.Var{Typed: l.typed(v), Var: vr, Current: vr.CurrentType}
+	res.Declare = !vr.Type.SingleType() && l.declare(vr)
+	if tp, ok := solver.FlowType(l.ctx, v, vr); ok {
+		res.Flow = tp
+	}
+	return res
+}
+
+func (l *lowerer) constFetch(n *expr.ConstFetch) ir.Expr {
+	if solver.IsBuiltinConstant(n.Constant) {
+		return l.expr(n.Constant)
+	}
+
+	c, ok := solver.ResolveConstant(l.ctx, n)
+	if !ok {
+		names := solver.ConstantNames(l.ctx, n.Constant)
+		return &ir.ConstRef{Typed: l.typed(n), Name: names[len(names)-1]}
+	}
+
+	return &ir.ConstRef{Typed: l.typed(n), Const: c, Name: c.Name}
+}
+
+// binary lowers the binary operator. The right operand of && is evaluated
+// only if the left one is true, and the right operand of || only if it is
+// false, so the types of the variables are narrowed by the left one.
+func (l *lowerer) binary(n node.Node, op ir.BinaryOp, left, right node.Node) ir.Expr {
+	res := &ir.Binary{Typed: l.typed(n), Op: op}
+	res.X = l.expr(left)
+
+	if op == ir.And || op == ir.Or {
+		restore := solver.ApplyNarrowings(solver.Narrowings(l.ctx, left, op == ir.Or))
+		res.Y = l.expr(right)
+		restore()
+		return res
+	}
+
+	res.Y = l.expr(right)
+	return res
+}
+
+// elementIsNull lowers the comparison n of the element read x of the
+// array with null, given as y unless it is is_null(), to the check of
+// the key like isset() does. The read of an undefined key is null, but
+// the element read in Go returns the zero value of the element type.
+func (l *lowerer) elementIsNull(n node.Node, x, y node.Node, not bool) (ir.Expr, bool) {
+	if y != nil && solver.IsNullLiteral(x) {
+		x, y = y, x
+	}
+	if y != nil && !solver.IsNullLiteral(y) {
+		return nil, false
+	}
+	f, ok := x.(*expr.ArrayDimFetch)
+	if !ok || f.Dim == nil || !solver.ExprType(l.ctx, f.Variable).Is(types.Arr) {
+		return nil, false
+	}
+
+	var res ir.Expr = &ir.Isset{Typed: l.typed(n), Items: []*ir.Lookup{l.lookup(x)}}
+	if !not {
+		res = &ir.Not{Typed: l.typed(n), X: res}
+	}
+	return res, true
+}
+
+// lookup lowers the element checked by isset() and empty().
+func (l *lowerer) lookup(n node.Node) *ir.Lookup {
+	res := &ir.Lookup{At: at(n)}
+	if v, ok := solver.RootVariable(n); ok && l.ctx.Session.Variable(v) == nil {
+		res.Undefined = true
+		return res
+	}
+
+	var keys []node.Node
+	for {
+		f, ok := n.(*expr.ArrayDimFetch)
+		if !ok || f.Dim == nil {
+			break
+		}
+		keys = append([]node.Node{f.Dim}, keys...)
+		n = f.Variable
+	}
+
+	res.X = l.expr(n)
+	for _, key := range keys {
+		res.Keys = append(res.Keys, l.expr(key))
+	}
+	return res
+}
+
+// arrayLiteral lowers the array literal of the type tp, which can differ
+// from the type of the literal itself if the literal is nested into
+// another array with elements of other types.
+func (l *lowerer) arrayLiteral(a *expr.ShortArray, tp types.Types) ir.Expr {
+	res := &ir.ArrayLit{Typed: typed(a, tp)}
+	ordered := tp.IsOrderedArray()
+	elemType := tp.ElementType()
+
+	// The spread arrays are appended to the slice of Var or to
+	// OrderedArray as is, the others must be of the element type.
+	for _, item := range a.Items {
+		item := item.(*expr.ArrayItem)
+		if !item.Unpack || ordered || !elemType.SingleType() {
+			continue
+		}
+		if valType := solver.ExprType(l.ctx, item.Val); !valType.Is(types.Arr) || !elemType.Equal(valType.ElementType()) {
+			l.unsupported(item, "unpacking array of other type is not supported")
+		}
+	}
+
+	for _, item := range a.Items {
+		item := item.(*expr.ArrayItem)
+
+		var it ir.ArrayItem
+		if item.Key != nil && ordered {
+			it.Key = l.expr(item.Key)
+		}
+		if item.Unpack {
+			it.Value = l.expr(item.Val)
+			it.Unpack = true
+		} else {
+			it.Value = l.element(item.Val, tp)
+		}
+		res.Items = append(res.Items, it)
+	}
+
+	return res
+}
+
+// call lowers the function call.
+func (l *lowerer) call(fn *expr.FunctionCall) ir.Expr {
+	fnName := utils.NamePartsToString(fn.Function.(*name.Name).Parts)
+
+	if check, ok := isTFunctions[fnName]; ok {
+		if fnName == "is_null" {
+			if res, ok := l.elementIsNull(fn, fn.ArgumentList.Arguments[0].(*node.Argument).Expr, nil, false); ok {
+				return res
+			}
+		}
+		return &ir.IsT{Typed: l.typed(fn), Func: check, X: l.expr(fn.ArgumentList.Arguments[0])}
+	}
+
+	if fnName == "define" || fnName == "defined" {
+		return l.defineCall(fn, fnName)
+	}
+
+	res := &ir.Call{Typed: l.typed(fn), Name: fnName}
+
+	f, ok := solver.CalledFunction(l.ctx, fn)
+	if !ok {
+		for _, arg := range fn.ArgumentList.Arguments {
+			res.Args = append(res.Args, ir.Arg{Value: l.expr(arg)})
+		}
+		return res
+	}
+
+	res.Func = f
+	res.Args, res.Spread = l.callArguments(f, fn.ArgumentList.Arguments)
+	return res
+}
+
+// callArguments lowers the arguments of the user function call. The
+// omitted arguments are replaced with the default values of parameters,
+// and the spread arrays are expanded to the parameters they fill. It also
+// reports whether the last argument is spread to the variadic parameter.
+func (l *lowerer) callArguments(fn *function.Function, args []node.Node) ([]ir.Arg, bool) {
+	var res []ir.Arg
+
+	// The arguments of the variadic parameter are collected apart,
+	// since the spread arrays are joined with the arguments before.
+	var variadic []ir.Arg
+	var spreads []ir.Expr
+	var spreadAt node.Node
+
+	idx := 0
+	for _, arg := range args {
+		a := arg.(*node.Argument)
+
+		if !a.Variadic {
+			p, ok := fn.ParamIndex(idx)
+			switch {
+			case !ok:
+				res = append(res, ir.Arg{Value: l.expr(a.Expr)})
+			case fn.Params[p].Variadic:
+				variadic = append(variadic, l.argument(fn, fn.Params[p], a.Expr))
+			default:
+				res = append(res, l.argument(fn, fn.Params[p], a.Expr))
+			}
+			idx++
+			continue
+		}
+
+		// The elements of the spread array fill the remaining
+		// fixed parameters and the rest goes to the variadic one.
+		tp := solver.ExprType(l.ctx, a.Expr)
+
+		var from int64
+		for ; idx < len(fn.Params) && !fn.Params[idx].Variadic; idx++ {
+			elem := &ir.ElemAt{Typed: typed(a, tp.ElementType()), X: l.expr(a.Expr), Index: from}
+			res = append(res, ir.Arg{Value: elem})
+			from++
+		}
+
+		// The rest of the array is ignored like
+		// the extra arguments if there is no variadic parameter.
+		if p, ok := fn.ParamIndex(idx); ok && fn.Params[p].Variadic {
+			spreads = append(spreads, &ir.Rest{Typed: typed(a, tp), X: l.expr(a.Expr), From: from})
+			if spreadAt == nil {
+				spreadAt = a
+			}
+		}
+	}
+
+	for ; idx < len(fn.Params); idx++ {
+		p := fn.Params[idx]
+		if p.Default == nil {
+			break
+		}
+		res = append(res, l.argument(fn, p, p.Default))
+	}
+
+	if len(spreads) == 0 {
+		return append(res, variadic...), false
+	}
+
+	// The single spread array is passed as is, otherwise the arguments
+	// and the arrays are appended to the new slice, which is not shared.
+	p := fn.Params[len(fn.Params)-1]
+	var rest ir.Expr = spreads[0]
+	if len(variadic) != 0 || len(spreads) > 1 {
+		tp := solver.ResolveTypes(l.ctx, solver.ParamVariableType(p))
+		rest = &ir.Append{Typed: typed(spreadAt, tp), Values: variadic, Slices: spreads}
+	} else if fn.IsMutated(p.Name) {
+		rest = &ir.Copy{Typed: typed(spreadAt, rest.Type()), X: rest}
+	}
+
+	return append(res, ir.Arg{Value: rest}), true
+}
+
+// argument lowers the value passed to the parameter p, the value is
+// converted if the parameter has another union type. The array is
+// copied if the function modifies the parameter.
+func (l *lowerer) argument(fn *function.Function, p function.Param, value node.Node) ir.Arg {
+	paramType := solver.ResolveTypes(l.ctx, p.Type)
+	valueType := solver.ExprType(l.ctx, value)
+
+	if fn.IsMutated(p.Name) && solver.IsAliasExpr(l.ctx, value) && valueType.Is(types.Arr) {
+		return ir.Arg{Value: &ir.Copy{Typed: typed(value, valueType), X: l.expr(value)}}
+	}
+
+	if paramType.SingleType() || paramType.GenerateName() == valueType.GenerateName() {
+		return ir.Arg{Value: l.expr(value)}
+	}
+
+	return ir.Arg{Value: l.expr(value), Convert: paramType}
+}
+
+// defineCall lowers define() and defined() calls used inside expressions.
+func (l *lowerer) defineCall(call *expr.FunctionCall, fnName string) ir.Expr {
+	nm, ok := solver.DefinedConstantName(call)
+	if !ok {
+		return &ir.Bool{Typed: l.typed(call)}
+	}
+
+	c, ok := l.ctx.Session.Constants.Get(nm)
+	if !ok {
+		return &ir.Bool{Typed: l.typed(call)}
+	}
+
+	switch {
+	case fnName == "defined" && c.Runtime:
+		return &ir.Defined{Typed: l.typed(call), Const: c}
+	case fnName == "define" && c.Runtime:
+		res := &ir.DefineExpr{Typed: l.typed(call), Decl: l.constDecl(c)}
+		res.Value = l.expr(call.ArgumentList.Arguments[1])
+		return res
+	}
+
+	return &ir.DefineExpr{Typed: l.typed(call), Decl: l.constDecl(c)}
+}
+
+// methodCall lowers the method call, only the methods
+// of generators are supported.
+func (l *lowerer) methodCall(m *expr.MethodCall) ir.Expr {
+	method := solver.MethodName(m)
+
+	if _, ok := solver.GeneratorType(l.ctx, m.Variable); ok {
+		if gm, ok := generatorMethods[method]; ok {
+			res := &ir.GeneratorMethod{Typed: l.typed(m), Method: gm.name, Convert: gm.convert}
+			res.X = l.expr(m.Variable)
+			for _, arg := range m.ArgumentList.Arguments {
+				res.Args = append(res.Args, l.expr(arg))
+			}
+			return res
+		}
+	}
+
+	l.unsupported(m, "the method %s is not supported", method)
+	return &ir.Null{Typed: typed(m, types.Types{})}
+}
@@ -0,0 +1,1010 @@
+// Package lower lowers the PHP syntax tree to the IR. The types of the
+// expressions are found by the solver in the order in which the code is
+// executed, so the types of the variables narrowed by the conditions and
+// changed by the assignments are known at each point of the code.
+package lower
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/i582/php2go/src/cfg"
+	"github.com/i582/php2go/src/constant"
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/ir"
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/expr/assign"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/solver"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/utils"
+	"github.com/i582/php2go/src/variable"
+)
+
+type lowerer struct {
+	ctx *ctx.Context
+
+	// stmts holds the statements of the block being lowered.
+	stmts *[]ir.Stmt
+
+	// locals holds the variables shadowed by the locals
+	// of their narrowed types in the enclosing blocks.
+	locals map[*variable.Variable]bool
+
+	// declared holds the variables of the function declared
+	// by the code lowered so far.
+	declared map[*variable.Variable]bool
+
+	// filename is the name of the file used in the errors.
+	filename string
+	// at holds the position of the statement being lowered,
+	// which is reported if the lowering fails unexpectedly.
+	at *ir.Pos
+	// err holds the first error of the file, it is shared
+	// by the lowerers of the functions and the scopes.
+	err *error
+}
+
+// File lowers the file, which is the whole program. The top-level code
+// is lowered to the main function if main is not nil, like in the
+// executable mode, otherwise only the functions and the constants
+// are lowered.
+func File(s *ctx.Session, r *node.Root, filename string, main *function.Function) (*ir.File, error) {
+	Analyze(s, []*node.Root{r})
+	return Lower(s, r, filename, main)
+}
+
+// Analyze runs the dataflow analysis of the functions declared in the
+// files of the program and resolves the types of their results and of
+// the constants. Lower only reads the types shared by the files, so the
+// files can then be lowered concurrently.
+func Analyze(s *ctx.Session, files []*node.Root) {
+	var funcs []*stmt.Function
+	for _, r := range files {
+		funcs = append(funcs, declaredFunctions(r.Stmts)...)
+	}
+	analyzeFunctions(s, funcs)
+
+	c := &ctx.Context{Session: s}
+	for _, f := range funcs {
+		fn := s.Function(f)
+		if fn.ReturnType.Len() != 0 && !fn.ReturnType.Resolved() {
+			fn.ReturnType = solver.ResolveTypes(c, fn.ReturnType)
+		}
+		if !fn.IsGenerator {
+			fn.ReturnType = resultType(fn.ReturnType)
+		}
+	}
+	for _, cnst := range s.Constants.Sorted() {
+		if !cnst.Type.Resolved() {
+			cnst.Type = solver.ResolveTypes(c, cnst.Type)
+		}
+	}
+}
+
+// resultType returns the type of the results of the function whose
+// returns give the types tp. The return without a value is void, the
+// function has no results if it returns nothing else, otherwise such
+// a return gives null.
+func resultType(tp types.Types) types.Types {
+	var res types.Types
+	for _, t := range tp.Types {
+		if !t.Is(types.Void) {
+			res.Add(t)
+		}
+	}
+
+	if res.Len() != 0 && res.Len() != tp.Len() {
+		res.Add(types.NewType(types.Null))
+	}
+	return res
+}
+
+// Lower lowers the file of the program analyzed by Analyze, like File.
+// The files of one program can be lowered concurrently. The constructs
+// that are not supported are reported by the error with the position
+// of the first of them, the panic of the lowering is reported by the
+// error with the position of the statement being lowered.
+func Lower(s *ctx.Session, r *node.Root, filename string, main *function.Function) (file *ir.File, err error) {
+	executable := main != nil
+	if main == nil {
+		main = function.NewFunction("main", types.Types{}, nil)
+	}
+
+	l := &lowerer{
+		ctx: &ctx.Context{
+			Variables:       main.Variables,
+			CurrentFunction: main,
+			Session:         s,
+		},
+		stmts:    new([]ir.Stmt),
+		locals:   make(map[*variable.Variable]bool),
+		declared: make(map[*variable.Variable]bool),
+		filename: filename,
+		at:       new(ir.Pos),
+		err:      new(error),
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			file, err = nil, fmt.Errorf("%s:%d: %v", filename, l.at.Line, r)
+		}
+	}()
+
+	if executable {
+		cfg.Analyze(l.ctx, main, r.Stmts)
+	}
+
+	file = &ir.File{Name: filename}
+	l.declarations(file, r.Stmts)
+	main.Namespace = ""
+
+	if executable {
+		file.Main = &ir.Func{
+			Function: main,
+			Body: l.block(main.Variables, func() {
+				for _, st := range r.Stmts {
+					l.stmt(st)
+				}
+			}),
+		}
+	}
+
+	return file, *l.err
+}
+
+// analyzeFunctions runs the dataflow analysis of the functions declared
+// at the top level, including namespace blocks, before any of them is
+// lowered, since the calls use the return types widened by the analysis.
+// The functions are analyzed again while the return types change.
+func analyzeFunctions(s *ctx.Session, funcs []*stmt.Function) {
+	for changed := true; changed; {
+		changed = false
+		for _, f := range funcs {
+			fn := s.Function(f)
+			c := &ctx.Context{
+				Variables:       fn.Variables,
+				CurrentFunction: fn,
+				Session:         s,
+			}
+			if cfg.Analyze(c, fn, f.Stmts) {
+				changed = true
+			}
+		}
+	}
+}
+
+func declaredFunctions(stmts []node.Node) []*stmt.Function {
+	var res []*stmt.Function
+	for _, st := range stmts {
+		switch st := st.(type) {
+		case *stmt.Function:
+			res = append(res, st)
+		case *stmt.Namespace:
+			res = append(res, declaredFunctions(st.Stmts)...)
+		}
+	}
+	return res
+}
+
+// declarations lowers the functions and constants declared
+// at the top level, including namespace blocks.
+func (l *lowerer) declarations(file *ir.File, stmts []node.Node) {
+	for _, st := range stmts {
+		switch st := st.(type) {
+		case *stmt.Function:
+			file.Decls = append(file.Decls, l.function(st))
+		case *stmt.ConstList:
+			for _, c := range st.Consts {
+				nm := c.(*stmt.Constant).ConstantName.(*node.Identifier).Value
+				if l.ctx.Namespace() != "" {
+					nm = l.ctx.Namespace() + `\` + nm
+				}
+				if cnst, ok := l.ctx.Session.Constants.Get(nm); ok {
+					file.Decls = append(file.Decls, l.constDecl(cnst))
+				}
+			}
+		case *stmt.Namespace:
+			l.ctx.CurrentFunction.Namespace = utils.NamespaceName(st.NamespaceName)
+			if st.Stmts != nil {
+				l.declarations(file, st.Stmts)
+				l.ctx.CurrentFunction.Namespace = ""
+			}
+		case *stmt.Expression:
+			if !solver.IsDefineCall(st.Expr) {
+				continue
+			}
+			nm, _ := solver.DefinedConstantName(st.Expr.(*expr.FunctionCall))
+			if cnst, ok := l.ctx.Session.Constants.Get(nm); ok {
+				file.Decls = append(file.Decls, l.constDecl(cnst))
+			}
+		}
+	}
+}
+
+func (l *lowerer) function(f *stmt.Function) *ir.Func {
+	fn := l.ctx.Session.Function(f)
+	fl := &lowerer{
+		ctx: &ctx.Context{
+			Variables:       fn.Variables,
+			CurrentFunction: fn,
+			Session:         l.ctx.Session,
+		},
+		stmts:    new([]ir.Stmt),
+		locals:   make(map[*variable.Variable]bool),
+		declared: make(map[*variable.Variable]bool),
+		filename: l.filename,
+		at:       l.at,
+		err:      l.err,
+	}
+
+	res := &ir.Func{At: at(f), Function: fn}
+
+	for _, p := range fn.Params {
+		v, ok := fn.Variables.Get(p.Name)
+		if !ok {
+			continue
+		}
+
+		if !v.Type.Resolved() {
+			v.Type = solver.ResolveTypes(fl.ctx, v.Type)
+		}
+		res.Params = append(res.Params, ir.Param{Var: v, Variadic: p.Variadic})
+	}
+
+	if fn.ReturnType.Len() != 0 && !fn.ReturnType.Resolved() {
+		fn.ReturnType = solver.ResolveTypes(fl.ctx, fn.ReturnType)
+	}
+
+	res.Body = fl.block(fn.Variables, func() {
+		for _, st := range f.Stmts {
+			fl.stmt(st)
+		}
+	})
+
+	return res
+}
+
+// constDecl returns the declaration of the constant.
+func (l *lowerer) constDecl(c *constant.Constant) *ir.ConstDecl {
+	if !c.Type.Resolved() {
+		c.Type = solver.ResolveTypes(l.ctx, c.Type)
+	}
+
+	d := &ir.ConstDecl{Const: c}
+	if !c.Runtime {
+		d.Value = l.expr(c.Value)
+	}
+	return d
+}
+
+// emit appends the statements to the block being lowered.
+func (l *lowerer) emit(stmts ...ir.Stmt) {
+	*l.stmts = append(*l.stmts, stmts...)
+}
+
+// stmtsOf returns the statements emitted by lower.
+func (l *lowerer) stmtsOf(lower func()) []ir.Stmt {
+	prev := l.stmts
+	var stmts []ir.Stmt
+	l.stmts = &stmts
+
+	lower()
+
+	l.stmts = prev
+	return stmts
+}
+
+// block returns the block with the scope vars of the statements emitted by lower.
+func (l *lowerer) block(vars variable.Table, lower func()) *ir.Block {
+	return &ir.Block{Vars: vars, Stmts: l.stmtsOf(lower)}
+}
+
+// declare marks the variable as declared and reports whether
+// it was not declared before, so the code declares it.
+func (l *lowerer) declare(v *variable.Variable) bool {
+	if v.WasInitialize || l.declared[v] {
+		return false
+	}
+	l.declared[v] = true
+	return true
+}
+
+// with returns the lowerer of the nested block with the context c.
+func (l *lowerer) with(c *ctx.Context) *lowerer {
+	ll := *l
+	ll.ctx = c
+	return &ll
+}
+
+func (l *lowerer) stmt(n node.Node) {
+	if p := pos(n); p.Line != 0 {
+		*l.at = p
+	}
+
+	switch n := n.(type) {
+	case *stmt.StmtList:
+		for _, st := range n.Stmts {
+			l.stmt(st)
+		}
+	case *stmt.Else:
+		l.stmt(n.Stmt)
+
+	case *stmt.Expression:
+		l.exprStmt(n)
+	case *stmt.Return:
+		ret := &ir.Return{At: at(n)}
+		if n.Expr != nil {
+			ret.Value = l.returnValue(n.Expr)
+		}
+		l.emit(ret)
+
+	case *stmt.Function, *stmt.ConstList, *stmt.Nop:
+	case *stmt.Namespace:
+		l.namespace(n)
+
+	case *stmt.Echo:
+		echo := &ir.Echo{At: at(n)}
+		for _, e := range n.Exprs {
+			echo.Args = append(echo.Args, l.expr(e))
+		}
+		l.emit(echo)
+
+	case *stmt.For:
+		l.forStmt(n)
+	case *stmt.Foreach:
+		l.foreach(n)
+	case *stmt.While:
+		l.while(n)
+	case *stmt.If:
+		l.ifStmt(n)
+	case *stmt.Break:
+		l.emit(&ir.Jump{At: at(n), Level: cfg.JumpLevel(n.Expr)})
+	case *stmt.Continue:
+		l.emit(&ir.Jump{At: at(n), Continue: true, Level: cfg.JumpLevel(n.Expr)})
+	case *stmt.Unset:
+		l.unset(n)
+
+	default:
+		l.unsupported(n, "the statement %s is not supported", nodeName(n))
+	}
+}
+
+func (l *lowerer) namespace(n *stmt.Namespace) {
+	l.ctx.CurrentFunction.Namespace = utils.NamespaceName(n.NamespaceName)
+
+	for _, st := range n.Stmts {
+		l.stmt(st)
+	}
+
+	if n.Stmts != nil {
+		l.ctx.CurrentFunction.Namespace = ""
+	}
+}
+
+func (l *lowerer) exprStmt(n *stmt.Expression) {
+	if solver.IsDefineCall(n.Expr) {
+		l.define(n.Expr.(*expr.FunctionCall))
+		return
+	}
+	if solver.IsArrayPushCall(n.Expr) {
+		l.arrayPush(n.Expr.(*expr.FunctionCall))
+		return
+	}
+
+	switch e := n.Expr.(type) {
+	case *expr.Yield, *expr.YieldFrom:
+		l.emit(&ir.ExprStmt{At: at(n), X: l.expr(e)})
+		return
+	case *expr.MethodCall:
+		if _, ok := solver.GeneratorType(l.ctx, e.Variable); ok {
+			// The calls of the unknown methods have no effect.
+			if _, ok := generatorMethods[solver.MethodName(e)]; ok {
+				l.emit(&ir.ExprStmt{At: at(n), X: l.expr(e)})
+			}
+			return
+		}
+	}
+
+	l.simpleStmt(n.Expr)
+}
+
+// simpleStmt lowers the expression used as a statement,
+// assignments, increments and decrements are statements.
+func (l *lowerer) simpleStmt(n node.Node) {
+	switch n := n.(type) {
+	case *assign.Assign:
+		l.assign(n)
+	case *expr.PostInc:
+		l.emit(&ir.IncDec{At: at(n), X: l.container(n.Variable), Inc: true})
+	case *expr.PreInc:
+		l.emit(&ir.IncDec{At: at(n), X: l.container(n.Variable), Inc: true})
+	case *expr.PostDec:
+		l.emit(&ir.IncDec{At: at(n), X: l.container(n.Variable)})
+	case *expr.PreDec:
+		l.emit(&ir.IncDec{At: at(n), X: l.container(n.Variable)})
+	default:
+		l.emit(&ir.ExprStmt{At: at(n), X: l.expr(n)})
+	}
+}
+
+// currentTypes returns the current types of the variables, which
+// are reset by resetCurrentTypes after a branch or a loop.
+func (l *lowerer) currentTypes() map[string]string {
+	vars := l.ctx.AllVariables()
+	res := make(map[string]string, len(vars))
+	for name, v := range vars {
+		res[name] = v.CurrentType.String()
+	}
+	return res
+}
+
+// resetCurrentTypes forgets the current types of the variables
+// assigned in a branch or a loop, since after it the variables
+// can hold the values of any of their types.
+func (l *lowerer) resetCurrentTypes(before map[string]string) {
+	for name, v := range l.ctx.AllVariables() {
+		if tp, ok := before[name]; !ok || tp != v.CurrentType.String() {
+			v.CurrentType = types.Types{}
+		}
+	}
+}
+
+func (l *lowerer) forStmt(f *stmt.For) {
+	scope := l.ctx.Session.Scope(f)
+	ll := l.with(scope)
+	defer l.resetCurrentTypes(l.currentTypes())
+
+	loop := &ir.For{At: at(f), Labeled: cfg.HasNestedJump(f.Stmt)}
+
+	loop.Init = ll.stmtsOf(func() {
+		for _, n := range f.Init {
+			ll.simpleStmt(n)
+		}
+	})
+
+	switch len(f.Cond) {
+	case 0:
+	case 1:
+		loop.Cond = ll.expr(f.Cond[0])
+	default:
+		l.unsupported(f, "for with several conditions is not supported")
+	}
+
+	loop.Post = ll.stmtsOf(func() {
+		for _, n := range f.Loop {
+			ll.simpleStmt(n)
+		}
+	})
+
+	loop.Body = ll.block(scope.Variables, func() {
+		ll.stmt(f.Stmt)
+	})
+
+	ll.emit(loop)
+}
+
+func (l *lowerer) foreach(f *stmt.Foreach) {
+	scope := l.ctx.Session.Scope(f)
+	if gen, ok := solver.GeneratorType(scope, f.Expr); ok {
+		l.iteration(f, ir.RangeGenerator, gen)
+		return
+	}
+
+	if tp := solver.ExprType(scope, f.Expr); tp.IsOrderedArray() {
+		l.iteration(f, ir.RangeOrdered, tp.Types[0])
+		return
+	}
+
+	ll := l.with(scope)
+	defer l.resetCurrentTypes(l.currentTypes())
+
+	loop := &ir.Foreach{At: at(f), Kind: ir.RangeSlice, Labeled: cfg.HasNestedJump(f.Stmt)}
+
+	// The key and the value are declared by the range clause.
+	for _, n := range []node.Node{f.Key, f.Variable} {
+		if v, ok := n.(*expr.Variable); ok {
+			ll.declare(ll.ctx.Session.Variable(v))
+		}
+	}
+
+	if f.Key != nil {
+		loop.Key = ll.expr(f.Key)
+	}
+
+	var list []node.Node
+	switch v := f.Variable.(type) {
+	case *expr.List:
+		list = v.Items
+	case *expr.ShortList:
+		list = v.Items
+	}
+
+	if list == nil && f.Variable != nil {
+		loop.Value = ll.expr(f.Variable)
+	}
+
+	loop.X = ll.expr(f.Expr)
+
+	exprType := solver.ExprType(ll.ctx, f.Expr)
+	elemType := exprType.ElementType()
+
+	loop.Body = ll.block(scope.Variables, func() {
+		if list != nil {
+			loop.List = &ir.Destructure{At: at(f.Variable), T: elemType}
+			loop.List.Items = ll.stmtsOf(func() {
+				ll.listItems(list, loop.List)
+			})
+		}
+
+		// The value is copied if it is modified in the loop,
+		// otherwise the modification changes the iterated array.
+		if v, ok := f.Variable.(*expr.Variable); ok && elemType.Is(types.Arr) &&
+			ll.ctx.CurrentFunction.IsMutated(v.VarName.(*node.Identifier).Value) {
+			loop.CopyValue = true
+		}
+
+		ll.stmt(f.Stmt)
+	})
+
+	ll.emit(loop)
+}
+
+// iteration lowers the foreach over the ordered array or the generator,
+// the key and the value are assigned to the targets at the start of the
+// body, the types of them are taken from the iterated type.
+func (l *lowerer) iteration(f *stmt.Foreach, kind ir.ForeachKind, iterated types.Type) {
+	scope := l.ctx.Session.Scope(f)
+	ll := l.with(scope)
+
+	loop := &ir.Foreach{At: at(f), Kind: kind, Labeled: cfg.HasNestedJump(f.Stmt)}
+	loop.X = ll.expr(f.Expr)
+
+	loop.Body = ll.block(scope.Variables, func() {
+		ll.foreachTargets(f, iterated.KeysTypes, iterated.ElemTypes)
+		ll.stmt(f.Stmt)
+	})
+
+	ll.emit(loop)
+}
+
+// foreachTargets assigns the key and the value of the current
+// element of the loop to the targets of the foreach.
+func (l *lowerer) foreachTargets(f *stmt.Foreach, keyTypes, elemTypes types.Types) {
+	if v, ok := f.Key.(*expr.Variable); ok {
+		l.assignVariable(v, keyTypes, func() ir.Expr {
+			return &ir.LoopKey{Typed: typed(f.Key, l.resolve(keyTypes))}
+		})
+	}
+
+	current := func() ir.Expr {
+		return &ir.LoopValue{Typed: typed(f.Variable, l.resolve(elemTypes))}
+	}
+
+	switch v := f.Variable.(type) {
+	case *expr.Variable:
+		if elemTypes.Is(types.Arr) && l.ctx.CurrentFunction.IsMutated(v.VarName.(*node.Identifier).Value) {
+			current = copied(f.Variable, elemTypes, current)
+		}
+		l.assignVariable(v, elemTypes, current)
+	case *expr.List:
+		l.listAssign(v, v.Items, elemTypes, current)
+	case *expr.ShortList:
+		l.listAssign(v, v.Items, elemTypes, current)
+	}
+}
+
+func (l *lowerer) while(wl *stmt.While) {
+	scope := l.ctx.Session.Scope(wl)
+	ll := l.with(scope)
+	defer l.resetCurrentTypes(l.currentTypes())
+
+	loop := &ir.While{At: at(wl), Labeled: cfg.HasNestedJump(wl.Stmt)}
+	loop.Cond = ll.expr(wl.Cond)
+	loop.Body = ll.block(scope.Variables, func() {
+		ll.stmt(wl.Stmt)
+	})
+
+	ll.emit(loop)
+}
+
+func (l *lowerer) ifStmt(i *stmt.If) {
+	scope, elseScope := l.ctx.Session.Scope(i), l.ctx.Session.ElseScope(i)
+	ll := l.with(scope)
+	before := l.currentTypes()
+
+	st := &ir.If{At: at(i)}
+
+	// The variables assigned in the branches are declared before the if.
+	var names []string
+	for name, v := range l.ctx.Variables.Vars {
+		if v.FromIfElse {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if v := l.ctx.Variables.Vars[name]; l.declare(v) {
+			st.Vars = append(st.Vars, v)
+		}
+	}
+
+	thenNarrowings := solver.Narrowings(l.ctx, i.Cond, false)
+	elseNarrowings := solver.Narrowings(l.ctx, i.Cond, true)
+
+	st.Cond = ll.expr(i.Cond)
+	st.Then = ll.branch(scope.Variables, thenNarrowings, i.Stmt)
+
+	if i.Else != nil {
+		st.Else = l.with(elseScope).branch(elseScope.Variables, elseNarrowings, i.Else)
+	}
+
+	ll.emit(st)
+
+	l.resetCurrentTypes(before)
+
+	// The code after the if that always exits
+	// runs only if the condition is false.
+	if i.Else == nil && solver.BranchExits(i.Stmt) {
+		solver.ApplyNarrowings(elseNarrowings)
+	}
+}
+
+// branch lowers the branch of the if with the types of the variables
+// narrowed. The variables that are only read in the branch are shadowed
+// by the locals of the narrowed types, see ir.Block.
+func (l *lowerer) branch(vars variable.Table, ns []solver.Narrowing, n node.Node) *ir.Block {
+	var locals []ir.Local
+
+	for _, nr := range ns {
+		v := nr.Var
+		if l.locals[v] || !nr.Type.SingleType() || nr.Type.Is(types.Null) {
+			continue
+		}
+		if read, written := solver.VariableUsage(n, v.Name); !read || written {
+			continue
+		}
+
+		locals = append(locals, ir.Local{Var: v, T: nr.Type})
+	}
+
+	restore := solver.ApplyNarrowings(ns)
+	for _, local := range locals {
+		l.locals[local.Var] = true
+	}
+
+	b := l.block(vars, func() {
+		l.stmt(n)
+	})
+	b.Locals = locals
+
+	for _, local := range locals {
+		delete(l.locals, local.Var)
+	}
+	restore()
+
+	return b
+}
+
+// unset lowers unset() of the variables of union type,
+// which become null, and of the elements of ordered arrays.
+func (l *lowerer) unset(u *stmt.Unset) {
+	for _, v := range u.Vars {
+		switch v := v.(type) {
+		case *expr.Variable:
+			vr := l.ctx.Session.Variable(v)
+			if vr == nil || vr.Type.SingleType() {
+				continue
+			}
+
+			l.emit(&ir.UnsetVar{At: at(v), Var: vr, Define: l.declare(vr)})
+			vr.CurrentType = types.Types{}
+		case *expr.ArrayDimFetch:
+			if v.Dim == nil || !solver.ExprType(l.ctx, v.Variable).IsOrderedArray() {
+				continue
+			}
+
+			l.emit(&ir.DeleteKey{At: at(v), Container: l.container(v.Variable), Key: l.expr(v.Dim)})
+		}
+	}
+}
+
+func (l *lowerer) assign(a *assign.Assign) {
+	e := a.Expression
+	expressionType := solver.ExprType(l.ctx, e)
+	value := func() ir.Expr { return l.expr(e) }
+
+	switch v := a.Variable.(type) {
+	case *expr.Variable:
+		// The array literal gets the type of the variable, which
+		// includes the types of elements written to it later.
+		vr := l.ctx.Session.Variable(v)
+		if arr, ok := e.(*expr.ShortArray); ok && vr.Type.Is(types.Arr) {
+			expressionType = vr.Type
+			value = func() ir.Expr { return l.arrayLiteral(arr, expressionType) }
+		}
+		if solver.NeedCopy(l.ctx, v.VarName.(*node.Identifier).Value, e) {
+			value = copied(e, expressionType, value)
+		}
+		l.assignVariable(v, expressionType, value)
+	case *expr.ArrayDimFetch:
+		arrType := solver.ExprType(l.ctx, v.Variable)
+		l.arrayDimAssign(v, func() ir.Expr {
+			return l.element(e, arrType)
+		})
+	case *expr.List:
+		if solver.NeedCopy(l.ctx, "", e) {
+			value = copied(e, expressionType, value)
+		}
+		l.listAssign(v, v.Items, expressionType, value)
+	case *expr.ShortList:
+		if solver.NeedCopy(l.ctx, "", e) {
+			value = copied(e, expressionType, value)
+		}
+		l.listAssign(v, v.Items, expressionType, value)
+	}
+}
+
+// assignVariable lowers the assignment of the value of the type
+// expressionType to the variable, which gets the type of the value.
+func (l *lowerer) assignVariable(a *expr.Variable, expressionType types.Types, value func() ir.Expr) {
+	vr := l.ctx.Session.Variable(a)
+	if !vr.Type.Resolved() {
+		vr.Type = solver.ResolveTypes(l.ctx, vr.Type)
+	}
+
+	if !vr.Type.ContainsMap(expressionType) {
+		vr.Type.Merge(expressionType)
+	}
+
+	vr.CurrentType = expressionType
+
+	// The value of the same union type declares the variable
+	// instead of the null declared by the first access.
+	define := !vr.Type.SingleType() && expressionType.Len() > 1 &&
+		expressionType.GenerateName() == vr.Type.GenerateName() && l.declare(vr)
+	target := l.variable(a)
+	if !define {
+		define = l.declare(vr)
+	}
+	l.emit(&ir.Assign{At: at(a), Var: target, Value: value(), ValueType: expressionType, Define: define})
+}
+
+// copied returns the copy of the array value.
+func copied(n node.Node, tp types.Types, value func() ir.Expr) func() ir.Expr {
+	return func() ir.Expr {
+		return &ir.Copy{Typed: typed(n, tp), X: value()}
+	}
+}
+
+// arrayValue lowers the value that is stored into another array. If the
+// value is an array that is referenced elsewhere, it is copied, since
+// the arrays would share the elements otherwise.
+func (l *lowerer) arrayValue(n node.Node) ir.Expr {
+	tp := solver.ExprType(l.ctx, n)
+	if solver.IsAliasExpr(l.ctx, n) && tp.Is(types.Arr) {
+		return &ir.Copy{Typed: typed(n, tp), X: l.expr(n)}
+	}
+
+	return l.expr(n)
+}
+
+// returnValue lowers the value returned from the current function. The
+// array literal gets the return type, which unites the returned arrays.
+func (l *lowerer) returnValue(n node.Node) ir.Expr {
+	fn := l.ctx.CurrentFunction
+	if a, ok := n.(*expr.ShortArray); ok && fn != nil && !fn.IsGenerator && fn.ReturnType.Is(types.Arr) {
+		return l.arrayLiteral(a, fn.ReturnType)
+	}
+	return l.expr(n)
+}
+
+// element lowers the value n stored as the element of the array of type
+// arrType. The nested array literal gets the element type of the array.
+func (l *lowerer) element(n node.Node, arrType types.Types) ir.Expr {
+	elemType := arrType.ElementType()
+
+	var x ir.Expr
+	if a, ok := n.(*expr.ShortArray); ok && elemType.Is(types.Arr) {
+		x = l.arrayLiteral(a, elemType)
+	} else {
+		x = l.arrayValue(n)
+	}
+
+	return &ir.Element{Typed: typed(n, elemType), X: x, Array: arrType}
+}
+
+func (l *lowerer) arrayDimAssign(a *expr.ArrayDimFetch, value func() ir.Expr) {
+	isAddingElement := a.Dim == nil
+
+	if solver.ExprType(l.ctx, a.Variable).IsOrderedArray() {
+		st := &ir.IndexAssign{At: at(a), Container: l.container(a.Variable)}
+		if !isAddingElement {
+			st.Key = l.expr(a.Dim)
+		}
+		st.Value = value()
+		l.emit(st)
+		return
+	}
+
+	if isAddingElement {
+		if parent, ok := a.Variable.(*expr.ArrayDimFetch); ok && solver.ExprType(l.ctx, parent.Variable).IsOrderedArray() {
+			l.nestedAppend(parent, value)
+			return
+		}
+
+		st := &ir.IndexAssign{At: at(a), Container: l.container(a.Variable)}
+		st.Value = value()
+		l.emit(st)
+		return
+	}
+
+	st := &ir.IndexAssign{At: at(a), Container: l.container(a.Variable), Key: l.expr(a.Dim)}
+	st.Value = value()
+	l.emit(st)
+}
+
+// container lowers the array that is modified by the assignment
+// to its element, see ir.NestedRef and ir.IndexRef.
+func (l *lowerer) container(n node.Node) ir.Expr {
+	f, ok := n.(*expr.ArrayDimFetch)
+	if !ok || f.Dim == nil {
+		return l.expr(n)
+	}
+
+	parentType := solver.ExprType(l.ctx, f.Variable)
+
+	switch {
+	case parentType.IsOrderedArray() && solver.ExprType(l.ctx, f).IsOrderedArray():
+		ref := &ir.NestedRef{Typed: typed(f, solver.ExprType(l.ctx, f))}
+		ref.X = l.container(f.Variable)
+		ref.Key = l.expr(f.Dim)
+		return ref
+	case parentType.Is(types.Arr) && !parentType.IsOrderedArray():
+		ref := &ir.IndexRef{Typed: typed(f, solver.ExprType(l.ctx, f))}
+		ref.X = l.container(f.Variable)
+		ref.Key = l.expr(f.Dim)
+		return ref
+	}
+
+	return l.expr(n)
+}
+
+// nestedAppend lowers $a['x'][] = $value, where $a is
+// the ordered array and $a['x'] is the slice.
+func (l *lowerer) nestedAppend(f *expr.ArrayDimFetch, value func() ir.Expr) {
+	st := &ir.NestedAppend{At: at(f), T: solver.ExprType(l.ctx, f)}
+	st.Container = l.container(f.Variable)
+	st.Key = l.expr(f.Dim)
+	st.Value = value()
+	l.emit(st)
+}
+
+// arrayPush lowers array_push($a, $x, $y) as the appends of all the values.
+func (l *lowerer) arrayPush(c *expr.FunctionCall) {
+	args := c.ArgumentList.Arguments
+	target := &expr.ArrayDimFetch{Variable: args[0].(*node.Argument).Expr}
+	arrType := solver.ExprType(l.ctx, target.Variable)
+
+	for _, arg := range args[1:] {
+		value := arg.(*node.Argument).Expr
+
+		l.arrayDimAssign(target, func() ir.Expr {
+			return l.element(value, arrType)
+		})
+	}
+}
+
+// listAssign lowers the destructuring assignment of the value of type valueType.
+func (l *lowerer) listAssign(n node.Node, items []node.Node, valueType types.Types, value func() ir.Expr) {
+	d := &ir.Destructure{At: at(n), T: valueType}
+	d.Value = value()
+	d.Items = l.stmtsOf(func() {
+		l.listItems(items, d)
+	})
+
+	l.emit(d)
+}
+
+// listItems lowers the assignments of the elements of the destructured
+// array to the list targets.
+func (l *lowerer) listItems(items []node.Node, d *ir.Destructure) {
+	var index int64
+	for _, item := range items {
+		item, ok := item.(*expr.ArrayItem)
+		if !ok || item.Val == nil {
+			index++
+			continue
+		}
+
+		elemType := solver.ListElementType(d.T, item, index)
+		elem := &ir.ListElem{Typed: typed(item, elemType), List: d, Index: index}
+		if item.Key != nil {
+			elem.Key = l.expr(item.Key)
+		} else {
+			index++
+		}
+
+		access := func() ir.Expr { return elem }
+
+		switch v := item.Val.(type) {
+		case *expr.Variable:
+			if elemType.Is(types.Arr) && l.ctx.CurrentFunction.IsMutated(v.VarName.(*node.Identifier).Value) {
+				access = copied(item, elemType, access)
+			}
+			l.assignVariable(v, elemType, access)
+		case *expr.List:
+			l.listAssign(v, v.Items, elemType, access)
+		case *expr.ShortList:
+			l.listAssign(v, v.Items, elemType, access)
+		case *expr.ShortArray:
+			l.listAssign(v, v.Items, elemType, access)
+		case *expr.ArrayDimFetch:
+			l.arrayDimAssign(v, access)
+		}
+	}
+}
+
+// define lowers the define() call used as a statement.
+func (l *lowerer) define(call *expr.FunctionCall) {
+	nm, _ := solver.DefinedConstantName(call)
+	c, ok := l.ctx.Session.Constants.Get(nm)
+	if !ok {
+		return
+	}
+
+	st := &ir.Define{At: at(call), Decl: l.constDecl(c)}
+	if c.Runtime {
+		st.Value = l.expr(call.ArgumentList.Arguments[1])
+	}
+	l.emit(st)
+}
+
+func (l *lowerer) resolve(tp types.Types) types.Types {
+	if tp.Resolved() {
+		return tp
+	}
+	return solver.ResolveTypes(l.ctx, tp)
+}
+
+// unsupported records the error of the construct n that is not
+// supported, only the first error of the file is reported.
+func (l *lowerer) unsupported(n node.Node, format string, args ...interface{}) {
+	if *l.err == nil {
+		*l.err = fmt.Errorf("%s:%d: %s", l.filename, pos(n).Line, fmt.Sprintf(format, args...))
+	}
+}
+
+// nodeName returns the name of the kind of the node n used
+// in the errors, like expr.Ternary.
+func nodeName(n node.Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", n), "*")
+}
+
+// at returns the position of n.
+func at(n node.Node) ir.At {
+	return ir.At{Pos: pos(n)}
+}
+
+// typed returns the position of n and the type tp.
+func typed(n node.Node, tp types.Types) ir.Typed {
+	return ir.Typed{Pos: pos(n), T: tp}
+}
+
+func pos(n node.Node) ir.Pos {
+	if n == nil {
+		return ir.Pos{}
+	}
+	if p := n.GetPosition(); p != nil {
+		return ir.Pos{Line: p.StartLine}
+	}
+	return ir.Pos{}
+}
+
+// isNull reports whether the name is the null constant.
+func isNull(nm string) bool {
+	return strings.EqualFold(nm, "null")
+}
@@ -1,23 +1,46 @@
 package meta
 
 import (
+	"github.com/i582/php2go/src/constant"
 	"github.com/i582/php2go/src/function"
-	"github.com/i582/php2go/src/variable"
 )
 
-var (
-	AllVariables = variable.NewTable()
-	AllFunctions = function.NewTable()
-)
+// Program holds the symbols declared by the translated program.
+// Each translation has its own, so the functions and the constants
+// of one program are not visible in another.
+type Program struct {
+	Functions function.Table
+	Constants constant.Table
+}
+
+func NewProgram() *Program {
+	return &Program{
+		Functions: function.NewTable(),
+		Constants: constant.NewTable(),
+	}
+}
 
-func AddVariable(v variable.Variable) {
-	AllVariables.Add(v.Name, v.Type)
+// AddFunction adds the function, it reports
+// false if the function is already declared.
+func (p *Program) AddFunction(f *function.Function) bool {
+	return p.Functions.Add(f)
 }
 
-func AddFunction(f *function.Function) {
-	AllFunctions.Add(f)
+func (p *Program) GetFunction(name string) (*function.Function, bool) {
+	return p.Functions.Get(name)
 }
 
-func GetFunction(name string) (*function.Function, bool) {
-	return AllFunctions.Get(name)
+func (p *Program) AddConstant(c *constant.Constant) bool {
+	return p.Constants.Add(c)
+}
+
+// ResolveConstant returns the first defined constant from names.
+func (p *Program) ResolveConstant(names []string) (*constant.Constant, bool) {
+	for _, name := range names {
+		if c, ok := p.Constants.Get(name); ok {
+			return c, true
+		}
+	}
+
+	return nil, false
 }
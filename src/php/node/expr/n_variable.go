@@ -5,7 +5,6 @@ import (
 	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/position"
 	"github.com/i582/php2go/src/php/walker"
-	"github.com/i582/php2go/src/variable"
 )
 
 // Variable node
@@ -13,8 +12,6 @@ type Variable struct {
 	FreeFloating freefloating.Collection
 	Position     *position.Position
 	VarName      node.Node
-
-	Var *variable.Variable
 }
 
 // NewVariable node constructor
@@ -1,7 +1,6 @@
 package stmt
 
 import (
-	"github.com/i582/php2go/src/function"
 	"github.com/i582/php2go/src/php/freefloating"
 	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/position"
@@ -18,8 +17,6 @@ type Function struct {
 	Params        []node.Node
 	ReturnType    node.Node
 	Stmts         []node.Node
-
-	Func *function.Function
 }
 
 // NewFunction node constructor
@@ -1,7 +1,6 @@
 package stmt
 
 import (
-	"github.com/i582/php2go/src/ctx"
 	"github.com/i582/php2go/src/php/freefloating"
 	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/position"
@@ -16,9 +15,6 @@ type If struct {
 	Stmt         node.Node
 	ElseIf       []node.Node
 	Else         node.Node
-
-	IfCtx   ctx.Context
-	ElseCtx ctx.Context
 }
 
 // NewIf node constructor
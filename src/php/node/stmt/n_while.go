@@ -1,7 +1,6 @@
 package stmt
 
 import (
-	"github.com/i582/php2go/src/ctx"
 	"github.com/i582/php2go/src/php/freefloating"
 	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/position"
@@ -14,8 +13,6 @@ type While struct {
 	Position     *position.Position
 	Cond         node.Node
 	Stmt         node.Node
-
-	Ctx ctx.Context
 }
 
 // NewWhile node constructor
@@ -0,0 +1,240 @@
+// Package pipeline translates the files of a PHP program into one Go
+// package. The files are parsed, lowered and generated by the worker
+// goroutines, only the declaration of the files, the inference of the
+// types and the dataflow analysis of the functions, which need the
+// whole program, run in one goroutine. This part takes about a half of
+// the time of one worker, which bounds the speedup of more workers.
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/generator"
+	"github.com/i582/php2go/src/lower"
+	"github.com/i582/php2go/src/php/errors"
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/php7"
+	"github.com/i582/php2go/src/root"
+)
+
+// CoreFile is the name of the file holding the code shared by the
+// translated files, like the functions reading the elements of slices.
+const CoreFile = "core.go"
+
+type Options struct {
+	// Package is the name of the Go package.
+	Package string
+
+	// Workers is the number of the files processed at once,
+	// the files are processed one by one if it is less than 2.
+	Workers int
+
+	// Specializations is the maximum number of
+	// the specialized copies of a function.
+	Specializations int
+
+	StrictArrayKeys bool
+	InlineRuntime   bool
+}
+
+// File is the file of the translated program.
+type File struct {
+	// Name is the name of the PHP file, like index.php,
+	// the generated Go file is named after it.
+	Name string
+	Src  []byte
+
+	// Errors holds the errors of the parser, the file
+	// is translated regardless of them.
+	Errors []*errors.Error
+
+	// Code is the generated Go code.
+	Code []byte
+
+	root *node.Root
+}
+
+// GoName returns the name of the generated Go file.
+func (f *File) GoName() string {
+	return strings.TrimSuffix(f.Name, filepath.Ext(f.Name)) + ".go"
+}
+
+// Translate translates the files of the library, the functions and
+// the constants of each file can be used by the others. The generated
+// code is stored in the files, the code shared by them is returned,
+// it is nil if there is none. The first error of the generated code
+// is returned in the order of the files.
+func Translate(files []*File, opts Options) (core []byte, err error) {
+	parallel(len(files), opts.Workers, func(i int) {
+		f := files[i]
+		parser := php7.NewParser(f.Src, "7.4")
+		parser.Parse()
+		f.Errors = parser.GetErrors()
+		f.root = parser.GetRootNode().(*node.Root)
+	})
+
+	// The types of the functions and the constants depend on the
+	// whole program, so the inference waits for all files and runs
+	// before any of them is lowered. The files are declared in the
+	// same order, so the output does not depend on the workers.
+	session := ctx.NewSession()
+	walkers := make([]*root.RootWalker, len(files))
+	roots := make([]*node.Root, len(files))
+	for i, f := range files {
+		walkers[i] = &root.RootWalker{Session: session, Filename: f.Name}
+		if err := walkers[i].Declare(f.root); err != nil {
+			return nil, err
+		}
+		roots[i] = f.root
+	}
+	if err := root.Infer(walkers, opts.Specializations); err != nil {
+		return nil, err
+	}
+	lower.Analyze(session, roots)
+
+	pkg := generator.NewPackage(opts.Package)
+	pkg.SetStrictArrayKeys(opts.StrictArrayKeys)
+	pkg.SetInlineRuntime(opts.InlineRuntime)
+
+	errs := make([]error, len(files))
+	parallel(len(files), opts.Workers, func(i int) {
+		errs[i] = translateFile(session, pkg, files[i])
+	})
+
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+
+	var shared strings.Builder
+	if err := pkg.Final(&shared); err != nil {
+		return nil, fmt.Errorf("%s: %v", CoreFile, err)
+	}
+	if shared.Len() != 0 {
+		core = []byte(shared.String())
+	}
+
+	return core, nil
+}
+
+// translateFile lowers and generates the file analyzed with the other
+// files of the package. The panic of the translation is reported by
+// the error, so it does not stop the translation of the other files.
+func translateFile(s *ctx.Session, pkg *generator.Package, f *File) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", f.Name, r)
+		}
+	}()
+
+	file, err := lower.Lower(s, f.root, f.Name, nil)
+	if err != nil {
+		return err
+	}
+
+	var main strings.Builder
+	gen := generator.NewGenerator(&main, ioutil.Discard, f.Name)
+	gen.SetPackage(pkg)
+	gen.Generate(file)
+	if err := gen.Final(); err != nil {
+		return fmt.Errorf("%s: %v", f.GoName(), err)
+	}
+	f.Code = []byte(main.String())
+	return nil
+}
+
+// TranslateDir translates the PHP files of the directory in, not
+// including the subdirectories, into the Go package in the directory
+// out, which is named after it. The files are read and written by
+// the workers as well.
+func TranslateDir(in, out string, opts Options) ([]*File, error) {
+	names, err := filepath.Glob(filepath.Join(in, "*.php"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	files := make([]*File, len(names))
+	errs := make([]error, len(names))
+	parallel(len(names), opts.Workers, func(i int) {
+		files[i] = &File{Name: filepath.Base(names[i])}
+		files[i].Src, errs[i] = ioutil.ReadFile(names[i])
+	})
+	if err := firstError(errs); err != nil {
+		return nil, err
+	}
+
+	if opts.Package == "" {
+		abs, err := filepath.Abs(out)
+		if err != nil {
+			return nil, err
+		}
+		opts.Package = filepath.Base(abs)
+	}
+
+	core, err := Translate(files, opts)
+	if err != nil {
+		return files, err
+	}
+
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return files, err
+	}
+
+	parallel(len(files), opts.Workers, func(i int) {
+		errs[i] = ioutil.WriteFile(filepath.Join(out, files[i].GoName()), files[i].Code, 0644)
+	})
+	if err := firstError(errs); err != nil {
+		return files, err
+	}
+
+	if core != nil {
+		return files, ioutil.WriteFile(filepath.Join(out, CoreFile), core, 0644)
+	}
+
+	return files, nil
+}
+
+// parallel calls fn for each index less than n, at most workers
+// calls run at once. It returns once all calls are done.
+func parallel(n int, workers int, fn func(i int)) {
+	if workers < 2 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
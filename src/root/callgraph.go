@@ -0,0 +1,288 @@
+package root
+
+import (
+	"strings"
+
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/php/walker"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/solver"
+	"github.com/i582/php2go/src/types"
+)
+
+// maxInferenceRounds limits the iterations of the inference, the types
+// of the recursive functions that build nested arrays never settle.
+const maxInferenceRounds = 10
+
+// program is the set of the files whose types are inferred together.
+type program struct {
+	files []*RootWalker
+
+	// maxSpecializations is the maximum number of the specialized
+	// copies of a function, 0 disables the specialization.
+	maxSpecializations int
+}
+
+// unit is the code whose types are inferred as a whole,
+// the function or the top-level code of the file.
+type unit struct {
+	fn   *function.Function
+	file *RootWalker
+
+	// decl is the declaration of the function,
+	// it is nil for the top-level code.
+	decl  *stmt.Function
+	stmts []node.Node
+
+	// calls holds the units called by the unit.
+	calls []*unit
+}
+
+// calleeFinder collects the user functions called in the code
+// without descending into nested functions and closures.
+type calleeFinder struct {
+	ctx *ctx.Context
+
+	callees []*function.Function
+	calls   []*expr.FunctionCall
+}
+
+func (c calleeFinder) EnterChildNode(key string, w walker.Walkable) {}
+func (c calleeFinder) LeaveChildNode(key string, w walker.Walkable) {}
+func (c calleeFinder) EnterChildList(key string, w walker.Walkable) {}
+func (c calleeFinder) LeaveChildList(key string, w walker.Walkable) {}
+func (c *calleeFinder) LeaveNode(w walker.Walkable)                 {}
+
+func (c *calleeFinder) EnterNode(w walker.Walkable) bool {
+	switch n := w.(type) {
+	case *stmt.Function, *expr.Closure, *expr.ArrowFunction:
+		return false
+	case *expr.FunctionCall:
+		if fn, ok := solver.CalledFunction(c.ctx, n); ok {
+			c.callees = append(c.callees, fn)
+			c.calls = append(c.calls, n)
+		}
+	}
+
+	return true
+}
+
+// callGraph returns the units of the functions and of the top-level
+// code of the files, which are the last ones, with the calls between them.
+func (p *program) callGraph() []*unit {
+	var units []*unit
+	byFunction := make(map[*function.Function]*unit)
+
+	for _, file := range p.files {
+		for _, f := range file.functions {
+			fn := file.Session.Function(f)
+			u := &unit{fn: fn, file: file, decl: f, stmts: f.Stmts}
+			units = append(units, u)
+			byFunction[fn] = u
+		}
+	}
+	for _, file := range p.files {
+		units = append(units, &unit{fn: file.Main, file: file, stmts: file.topLevel})
+	}
+
+	for _, u := range units {
+		c := &calleeFinder{ctx: &u.file.Ctx}
+		for _, st := range u.stmts {
+			st.Walk(c)
+		}
+
+		for _, fn := range c.callees {
+			if callee, ok := byFunction[fn]; ok {
+				u.calls = append(u.calls, callee)
+			}
+		}
+	}
+
+	return units
+}
+
+// components returns the strongly connected components of the call
+// graph, the mutually recursive functions are in one component. The
+// components are ordered so that the called functions go first.
+func components(units []*unit) [][]*unit {
+	t := &tarjan{
+		index:   make(map[*unit]int, len(units)),
+		lowlink: make(map[*unit]int, len(units)),
+		onStack: make(map[*unit]bool, len(units)),
+	}
+
+	for _, u := range units {
+		if _, ok := t.index[u]; !ok {
+			t.visit(u)
+		}
+	}
+
+	return t.components
+}
+
+// tarjan finds the strongly connected components with the Tarjan's
+// algorithm, which completes the components of the callees before the
+// components of the callers.
+type tarjan struct {
+	next    int
+	index   map[*unit]int
+	lowlink map[*unit]int
+	onStack map[*unit]bool
+	stack   []*unit
+
+	components [][]*unit
+}
+
+func (t *tarjan) visit(u *unit) {
+	t.index[u] = t.next
+	t.lowlink[u] = t.next
+	t.next++
+
+	t.stack = append(t.stack, u)
+	t.onStack[u] = true
+
+	for _, callee := range u.calls {
+		if _, ok := t.index[callee]; !ok {
+			t.visit(callee)
+			if t.lowlink[callee] < t.lowlink[u] {
+				t.lowlink[u] = t.lowlink[callee]
+			}
+		} else if t.onStack[callee] && t.index[callee] < t.lowlink[u] {
+			t.lowlink[u] = t.index[callee]
+		}
+	}
+
+	if t.lowlink[u] != t.index[u] {
+		return
+	}
+
+	var component []*unit
+	for {
+		last := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		t.onStack[last] = false
+
+		component = append(component, last)
+		if last == u {
+			break
+		}
+	}
+
+	t.components = append(t.components, component)
+}
+
+// handleUnit infers the types in the code of the unit again, the types
+// of the values returned and yielded are collected from scratch.
+func (r *RootWalker) handleUnit(u *unit) {
+	if u.decl == nil {
+		r.handleFunctionStmts(u.stmts, u.fn)
+		return
+	}
+
+	// The results of the widened functions stay mixed.
+	var result types.Types
+	if u.fn.Widened {
+		result = types.NewBaseTypes(types.Mixed)
+	}
+
+	u.fn.ReturnType = result
+	u.fn.YieldKeyTypes = result
+	u.fn.YieldTypes = result
+	u.fn.GeneratorReturnType = result
+
+	r.handleFunctionBody(u.decl)
+}
+
+// inferToFixpoint infers the types in the units again until the types
+// of the parameters and the results of all functions no longer change.
+// The types in the code of the functions depend on the types of the
+// results of the called functions, which can be declared later or call
+// the function back, and on the types of the arguments of the calls.
+// The components of the call graph are handled with the called ones
+// first, the mutually recursive functions of a component are handled
+// until their types settle. The components whose types do not settle
+// in maxInferenceRounds are widened to the mixed type.
+func (p *program) inferToFixpoint(units []*unit) {
+	comps := components(units)
+
+	for round := 0; round < maxInferenceRounds; round++ {
+		var changed []*unit
+
+		for _, comp := range comps {
+			settled := false
+			compStart := signatures(comp)
+
+			for i := 0; i < maxInferenceRounds && !settled; i++ {
+				compBefore := signatures(comp)
+				for _, u := range comp {
+					u.file.handleUnit(u)
+				}
+				settled = signatures(comp) == compBefore
+			}
+
+			switch {
+			case !settled:
+				widen(comp)
+				changed = append(changed, comp...)
+			case signatures(comp) != compStart:
+				changed = append(changed, comp...)
+			}
+		}
+
+		if len(changed) == 0 {
+			return
+		}
+		if round == maxInferenceRounds-1 {
+			widen(changed)
+		}
+	}
+}
+
+// widen gives the mixed type to the results and to the parameters
+// without declared types of the functions of the units, whose types
+// do not settle. The values of the mixed type are stored in Var.
+func widen(units []*unit) {
+	for _, u := range units {
+		if u.decl == nil || u.fn.Widened {
+			continue
+		}
+
+		u.fn.Widened = true
+		for i := range u.fn.Params {
+			if !u.fn.Params[i].Typed {
+				u.fn.Params[i].Type = types.NewBaseTypes(types.Mixed)
+			}
+		}
+	}
+
+	for _, u := range units {
+		u.file.handleUnit(u)
+	}
+}
+
+// signatures returns the resolved types of the parameters
+// and the results of the functions of the units.
+func signatures(units []*unit) string {
+	var b strings.Builder
+
+	for _, u := range units {
+		fn := u.fn
+		c := &u.file.Ctx
+		b.WriteString(fn.Name + "(")
+		for _, p := range fn.Params {
+			b.WriteString(solver.ResolveTypes(c, p.Type).String() + ",")
+		}
+		b.WriteString(")")
+
+		for _, tp := range []types.Types{fn.ReturnType, fn.YieldKeyTypes, fn.YieldTypes, fn.SendTypes, fn.GeneratorReturnType} {
+			b.WriteString(" " + solver.ResolveTypes(c, tp).String())
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
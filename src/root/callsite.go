@@ -0,0 +1,34 @@
+package root
+
+import (
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/walker"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/solver"
+)
+
+// callSiteWalker collects the types of function arguments
+// that are known at translation time from all call sites.
+type callSiteWalker struct {
+	Ctx *ctx.Context
+}
+
+func (c callSiteWalker) EnterChildNode(key string, w walker.Walkable) {}
+func (c callSiteWalker) LeaveChildNode(key string, w walker.Walkable) {}
+func (c callSiteWalker) EnterChildList(key string, w walker.Walkable) {}
+func (c callSiteWalker) LeaveChildList(key string, w walker.Walkable) {}
+func (c *callSiteWalker) LeaveNode(w walker.Walkable)                 {}
+
+func (c *callSiteWalker) EnterNode(w walker.Walkable) bool {
+	call, ok := w.(*expr.FunctionCall)
+	if !ok {
+		return true
+	}
+
+	if fn, ok := solver.CalledFunction(c.Ctx, call); ok {
+		solver.MergeArgumentTypes(c.Ctx, fn, call.ArgumentList.Arguments, true)
+	}
+
+	return true
+}
@@ -0,0 +1,95 @@
+package root
+
+import (
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/name"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/php/walker"
+	"github.com/i582/php2go/src/solver"
+	"github.com/i582/php2go/src/utils"
+)
+
+// constantUseFinder looks for the uses of the constant, the reads of it
+// and the define() and defined() calls with its name, in the code and
+// in the functions of the file called by the code.
+type constantUseFinder struct {
+	ctx   *ctx.Context
+	name  string
+	funcs map[string]*stmt.Function
+	seen  map[*stmt.Function]bool
+	found bool
+}
+
+func (f constantUseFinder) EnterChildNode(key string, w walker.Walkable) {}
+func (f constantUseFinder) LeaveChildNode(key string, w walker.Walkable) {}
+func (f constantUseFinder) EnterChildList(key string, w walker.Walkable) {}
+func (f constantUseFinder) LeaveChildList(key string, w walker.Walkable) {}
+func (f *constantUseFinder) LeaveNode(w walker.Walkable)                 {}
+
+func (f *constantUseFinder) EnterNode(w walker.Walkable) bool {
+	switch n := w.(type) {
+	case *expr.ConstFetch:
+		for _, nm := range solver.ConstantNames(f.ctx, n.Constant) {
+			if nm == f.name {
+				f.found = true
+			}
+		}
+	case *expr.FunctionCall:
+		nm, ok := n.Function.(*name.Name)
+		if !ok {
+			break
+		}
+		called := utils.NamePartsToString(nm.Parts)
+		if called == "define" || called == "defined" {
+			if c, ok := solver.DefinedConstantName(n); ok && c == f.name {
+				f.found = true
+			}
+			break
+		}
+		if fn, ok := f.funcs[called]; ok && !f.seen[fn] {
+			f.seen[fn] = true
+			for _, st := range fn.Stmts {
+				st.Walk(f)
+			}
+		}
+	case *stmt.Function, *expr.Closure, *expr.ArrowFunction:
+		return false
+	}
+
+	return !f.found
+}
+
+// constantUsed reports whether the constant is used by the top-level
+// code stmts, which runs before the define() of the constant. Then the
+// constant can not be declared as the Go constant, since it is not
+// defined yet at that point. The calls of the functions declared in
+// other files are not followed.
+func (r *RootWalker) constantUsed(stmts []node.Node, name string) bool {
+	f := &constantUseFinder{
+		ctx:   &r.Ctx,
+		name:  name,
+		funcs: make(map[string]*stmt.Function),
+		seen:  make(map[*stmt.Function]bool),
+	}
+	collectFunctions(r.root.Stmts, f.funcs)
+
+	for _, st := range stmts {
+		st.Walk(f)
+	}
+	return f.found
+}
+
+// collectFunctions adds the functions declared at the top level
+// of the file, including the namespace blocks, to funcs.
+func collectFunctions(stmts []node.Node, funcs map[string]*stmt.Function) {
+	for _, st := range stmts {
+		switch st := st.(type) {
+		case *stmt.Function:
+			funcs[st.FunctionName.(*node.Identifier).Value] = st
+		case *stmt.Namespace:
+			collectFunctions(st.Stmts, funcs)
+		}
+	}
+}
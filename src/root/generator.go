@@ -0,0 +1,39 @@
+package root
+
+import (
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/php/walker"
+)
+
+// yieldFinder looks for yield expressions in the function body
+// without descending into nested functions and closures.
+type yieldFinder struct {
+	found bool
+}
+
+func (y yieldFinder) EnterChildNode(key string, w walker.Walkable) {}
+func (y yieldFinder) LeaveChildNode(key string, w walker.Walkable) {}
+func (y yieldFinder) EnterChildList(key string, w walker.Walkable) {}
+func (y yieldFinder) LeaveChildList(key string, w walker.Walkable) {}
+func (y *yieldFinder) LeaveNode(w walker.Walkable)                 {}
+
+func (y *yieldFinder) EnterNode(w walker.Walkable) bool {
+	switch w.(type) {
+	case *expr.Yield, *expr.YieldFrom:
+		y.found = true
+	case *stmt.Function, *expr.Closure, *expr.ArrowFunction:
+		return false
+	}
+
+	return !y.found
+}
+
+func containsYield(stmts []node.Node) bool {
+	y := &yieldFinder{}
+	for _, st := range stmts {
+		st.Walk(y)
+	}
+	return y.found
+}
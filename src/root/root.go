@@ -1,6 +1,8 @@
 package root
 
 import (
+	"fmt"
+
 	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/node/expr"
 	"github.com/i582/php2go/src/php/node/expr/assign"
@@ -10,12 +12,39 @@ import (
 	"github.com/i582/php2go/src/block"
 	"github.com/i582/php2go/src/ctx"
 	"github.com/i582/php2go/src/function"
-	"github.com/i582/php2go/src/meta"
+	"github.com/i582/php2go/src/solver"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/utils"
 	"github.com/i582/php2go/src/variable"
 )
 
 type RootWalker struct {
 	Ctx ctx.Context
+
+	// Main holds the top-level code of the file,
+	// which becomes func main() in the executable mode.
+	Main *function.Function
+
+	// functions holds the declared functions whose
+	// bodies are analyzed after all declarations.
+	functions []*stmt.Function
+
+	// root is the file, topLevel holds the statements
+	// of it executed at the top level.
+	root     *node.Root
+	topLevel []node.Node
+
+	// MaxSpecializations is the maximum number of the specialized
+	// copies of a function, 0 disables the specialization.
+	MaxSpecializations int
+
+	// Session holds the information found about the nodes.
+	Session *ctx.Session
+
+	// Filename is the name of the file used in the errors.
+	Filename string
+	// err holds the first error of the declarations.
+	err error
 }
 
 func (r RootWalker) EnterChildNode(key string, w walker.Walkable) {}
@@ -29,6 +58,7 @@ func (r *RootWalker) EnterNode(w walker.Walkable) bool {
 
 	switch n := n.(type) {
 	case *node.Root:
+		return r.handleRoot(n)
 
 	case *stmt.Expression:
 
@@ -42,6 +72,145 @@ func (r *RootWalker) EnterNode(w walker.Walkable) bool {
 	return true
 }
 
+func (r *RootWalker) handleRoot(n *node.Root) bool {
+	if r.Declare(n) != nil {
+		return false
+	}
+	r.err = Infer([]*RootWalker{r}, r.MaxSpecializations)
+
+	return false
+}
+
+// Err returns the error of the declarations or the inference
+// of the file walked by the walker.
+func (r *RootWalker) Err() error {
+	return r.err
+}
+
+// Declare collects the functions and the constants declared in the file
+// n and its top-level code. The files of the program are declared in the
+// same order on every run, since the constants defined with define() can
+// use the constants declared before, then their types are inferred
+// together by Infer. The function declared twice, in the same file or
+// in another one, is reported by the error.
+func (r *RootWalker) Declare(n *node.Root) error {
+	r.root = n
+	r.Main = function.NewFunction("main", types.Types{}, nil)
+	r.Ctx.CurrentFunction = r.Main
+	r.Ctx.Session = r.Session
+
+	r.topLevel = r.handleTopLevelStmts(n.Stmts)
+	r.Main.Namespace = ""
+
+	return r.err
+}
+
+// Infer infers the types of the program made of the files declared by
+// Declare, the code of each file can call the functions of the others.
+// At most maxSpecializations specialized copies of a function are
+// created, 0 disables the specialization. The panic of the inference
+// is reported by the error with the position of the statement.
+func Infer(files []*RootWalker, maxSpecializations int) (err error) {
+	p := &program{files: files, maxSpecializations: maxSpecializations}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	// The types of arguments known at translation time are collected
+	// before the bodies are analyzed, so that the parameters get them
+	// regardless of the order in which the functions are declared.
+	for _, f := range files {
+		f.root.Walk(&callSiteWalker{Ctx: &f.Ctx})
+	}
+
+	units := p.callGraph()
+
+	// The called functions are analyzed first, so the callers get the
+	// types of their results. Functions with parameters whose types are
+	// still unknown are analyzed after the top-level code, which may
+	// call them.
+	var deferred []*unit
+	for _, comp := range components(units) {
+		for _, u := range comp {
+			if u.decl == nil || !paramsKnown(u.fn) {
+				deferred = append(deferred, u)
+				continue
+			}
+			u.file.handleUnit(u)
+		}
+	}
+
+	for _, f := range files {
+		f.handleFunctionStmts(f.topLevel, f.Main)
+	}
+
+	for _, u := range deferred {
+		if u.decl != nil {
+			u.file.handleUnit(u)
+		}
+	}
+
+	p.inferToFixpoint(units)
+
+	if p.maxSpecializations > 0 && p.specialize(units) {
+		units = p.callGraph()
+		p.inferToFixpoint(units)
+	}
+
+	return nil
+}
+
+// handleTopLevelStmts declares functions and constants
+// and returns the statements executed at the top level.
+func (r *RootWalker) handleTopLevelStmts(stmts []node.Node) []node.Node {
+	var topLevel []node.Node
+
+	for _, st := range stmts {
+		switch st := st.(type) {
+		case *stmt.Function:
+			st.Walk(r)
+		case *stmt.ConstList:
+			r.handleConstList(st)
+		case *stmt.Namespace:
+			r.Main.Namespace = utils.NamespaceName(st.NamespaceName)
+			topLevel = append(topLevel, st)
+
+			if st.Stmts != nil {
+				r.handleTopLevelStmts(st.Stmts)
+				r.Main.Namespace = ""
+			}
+		case *stmt.Nop, *stmt.InlineHtml:
+		case *stmt.Expression:
+			// Constants defined with a value known at translation time
+			// are declared before the functions are handled, so the
+			// functions that use them get their types. They become Go
+			// constants only if they are not used before the define(),
+			// the others are defined at runtime, see BlockWalker.
+			if solver.IsDefineCall(st.Expr) {
+				call := st.Expr.(*expr.FunctionCall)
+				nm, _ := solver.DefinedConstantName(call)
+				if solver.IsConstantExpr(&r.Ctx, solver.DefinedValue(call)) && !r.constantUsed(topLevel, nm) {
+					r.Session.AddConstant(solver.DefinedConstant(&r.Ctx, call))
+				}
+			}
+			topLevel = append(topLevel, st)
+		default:
+			topLevel = append(topLevel, st)
+		}
+	}
+
+	return topLevel
+}
+
+func (r *RootWalker) handleConstList(l *stmt.ConstList) {
+	for _, c := range l.Consts {
+		r.Session.AddConstant(solver.DeclaredConstant(&r.Ctx, c.(*stmt.Constant)))
+	}
+}
+
 func (r *RootWalker) handleAssign(a *assign.Assign) {
 
 }
@@ -50,38 +219,94 @@ func (r *RootWalker) handleFunction(f *stmt.Function) {
 	fn := function.Function{}
 
 	fn.Name = f.FunctionName.(*node.Identifier).Value
+	fn.Namespace = r.Ctx.Namespace()
 
 	for _, param := range f.Params {
-		fn.Params = append(fn.Params, r.handleFunctionParam(param.(*node.Parameter)))
+		fn.Params = append(fn.Params, solver.Param(&r.Ctx, param.(*node.Parameter)))
 	}
 
-	r.handleFunctionStmts(f.Stmts, &fn)
+	fn.IsGenerator = containsYield(f.Stmts)
+	fn.Declared = fmt.Sprintf("%s:%d", r.Filename, f.GetPosition().StartLine)
 
-	meta.AddFunction(&fn)
+	if !r.Session.AddFunction(&fn) {
+		if r.err == nil {
+			prev, _ := r.Session.GetFunction(fn.Name)
+			r.err = fmt.Errorf("%s: the function %s is already declared at %s", fn.Declared, fn.Name, prev.Declared)
+		}
+		return
+	}
 
-	f.Func = &fn
+	r.Session.SetFunction(f, &fn)
+	r.functions = append(r.functions, f)
 }
 
-func (r *RootWalker) handleFunctionParam(p *node.Parameter) function.Param {
-	name := p.Variable.(*expr.Variable).VarName.(*node.Identifier).Value
+func (r *RootWalker) handleFunctionBody(f *stmt.Function) {
+	fn := r.Session.Function(f)
+	r.handleFunctionStmts(f.Stmts, fn)
 
-	return function.Param{
-		Name: name,
+	if fn.IsGenerator {
+		fn.ReturnType = types.NewTypes(types.NewGeneratorType(fn.Name, fn.YieldKeyTypes, fn.YieldTypes))
 	}
 }
 
+func paramsKnown(fn *function.Function) bool {
+	for _, p := range fn.Params {
+		if p.Type.Len() == 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func (r *RootWalker) handleFunctionStmts(stmts []node.Node, fn *function.Function) {
 	w := &block.BlockWalker{
 		Ctx: ctx.Context{
 			Parent:          &r.Ctx,
 			Variables:       variable.NewTable(),
 			CurrentFunction: fn,
+			Session:         r.Session,
 		},
 	}
 
+	for i, p := range fn.Params {
+		tp := solver.ParamVariableType(p)
+		if !p.Typed {
+			tp = types.NewTypes(types.NewLazyParamType(fn.Name, i))
+		}
+
+		w.Ctx.Variables.Add(p.Name, tp)
+		v, _ := w.Ctx.Variables.Get(p.Name)
+		v.WasInitialize = true
+	}
+
 	for _, st := range stmts {
-		st.Walk(w)
+		r.inferStmt(w, st)
 	}
 
 	fn.Variables = w.Context().Variables
 }
+
+// inferError is the panic of the inference of the statement,
+// which holds the position of the statement.
+type inferError struct {
+	error
+}
+
+// inferStmt infers the types in the statement st. The panic of
+// the inference is raised again with the position of st.
+func (r *RootWalker) inferStmt(w *block.BlockWalker, st node.Node) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if _, ok := rec.(inferError); ok {
+				panic(rec)
+			}
+			line := 0
+			if p := st.GetPosition(); p != nil {
+				line = p.StartLine
+			}
+			panic(inferError{fmt.Errorf("%s:%d: %v", r.Filename, line, rec)})
+		}
+	}()
+
+	st.Walk(w)
+}
@@ -0,0 +1,267 @@
+package root
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/i582/php2go/src/cfg"
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/name"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/solver"
+	"github.com/i582/php2go/src/types"
+	"github.com/i582/php2go/src/utils"
+)
+
+// variant is the specialized copy of the function
+// for the calls with the same types of the arguments.
+type variant struct {
+	name   string
+	params []types.Types
+	calls  []*expr.FunctionCall
+}
+
+// specialize creates the copies of the functions whose parameters take
+// the values of different types, one for each set of the types of the
+// arguments, so that the parameters of the copies are single-typed and
+// are not represented by Var. Only the calls with all arguments of the
+// single scalar types are redirected to the copies, and at most
+// MaxSpecializations copies of each function are created, the other
+// calls use the original function. specialize reports whether any
+// copies have been created.
+func (p *program) specialize(units []*unit) bool {
+	byFunction := make(map[*function.Function]*unit, len(units))
+	for _, u := range units {
+		byFunction[u.fn] = u
+	}
+
+	var order []*unit
+	variants := make(map[*unit][]*variant)
+
+	for _, u := range units {
+		c := &ctx.Context{
+			Variables:       u.fn.Variables,
+			CurrentFunction: u.fn,
+			Session:         u.file.Session,
+		}
+		cfg.Analyze(c, u.fn, u.stmts)
+
+		finder := &calleeFinder{ctx: c}
+		for _, st := range u.stmts {
+			st.Walk(finder)
+		}
+
+		for _, call := range finder.calls {
+			fn, _ := solver.CalledFunction(c, call)
+			callee, ok := byFunction[fn]
+			if !ok || !callee.file.specializable(callee) {
+				continue
+			}
+
+			params, ok := callParamTypes(c, fn, call)
+			if !ok {
+				continue
+			}
+
+			v := findVariant(variants[callee], params)
+			if v == nil {
+				if len(variants[callee]) >= p.maxSpecializations {
+					continue
+				}
+				v = &variant{name: specializedName(fn.Name, params), params: params}
+				if _, exists := callee.file.Session.GetFunction(v.name); exists {
+					continue
+				}
+				if len(variants[callee]) == 0 {
+					order = append(order, callee)
+				}
+				variants[callee] = append(variants[callee], v)
+			}
+			v.calls = append(v.calls, call)
+		}
+	}
+
+	for _, callee := range order {
+		var copies []node.Node
+		file := callee.file
+		for _, v := range variants[callee] {
+			copies = append(copies, file.specializedCopy(callee, v))
+		}
+		file.root.Stmts, _ = insertAfter(file.root.Stmts, callee.decl, copies)
+	}
+
+	return len(order) != 0
+}
+
+// specializable reports whether the copies of the function can be
+// created, the function must have the parameter without the declared
+// type that takes the values of different types.
+func (r *RootWalker) specializable(u *unit) bool {
+	if u.decl == nil || u.fn.IsGenerator {
+		return false
+	}
+
+	mixed := false
+	for _, p := range u.fn.Params {
+		if p.Variadic {
+			return false
+		}
+		if tp := solver.ResolveTypes(&r.Ctx, p.Type); !p.Typed && tp.Len() > 1 {
+			mixed = true
+		}
+	}
+	return mixed
+}
+
+// callParamTypes returns the types of the parameters of the copy of
+// fn for the call, the declared types and the types of the arguments
+// for the other parameters.
+func callParamTypes(c *ctx.Context, fn *function.Function, call *expr.FunctionCall) ([]types.Types, bool) {
+	args := call.ArgumentList.Arguments
+	if len(args) != len(fn.Params) {
+		return nil, false
+	}
+
+	params := make([]types.Types, 0, len(args))
+	for i, arg := range args {
+		if solver.IsSpread(arg) {
+			return nil, false
+		}
+
+		tp := solver.ResolveTypes(c, solver.ExprType(c, arg))
+		if !tp.SingleType() || !tp.Types[0].IsScalar() {
+			return nil, false
+		}
+
+		if fn.Params[i].Typed {
+			params = append(params, fn.Params[i].Type)
+		} else {
+			params = append(params, tp)
+		}
+	}
+	return params, true
+}
+
+func findVariant(variants []*variant, params []types.Types) *variant {
+	for _, v := range variants {
+		same := true
+		for i := range params {
+			if !v.params[i].Equal(params[i]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return v
+		}
+	}
+	return nil
+}
+
+// specializedName returns the name of the copy of the function,
+// the types of the parameters are added to it, like max_int64_int64.
+func specializedName(fnName string, params []types.Types) string {
+	parts := make([]string, 0, len(params)+1)
+	parts = append(parts, fnName)
+	for _, p := range params {
+		parts = append(parts, utils.TransformType(p.String()))
+	}
+	return strings.Join(parts, "_")
+}
+
+// specializedCopy declares the copy of the function for the variant
+// and redirects the calls of the variant to it.
+func (r *RootWalker) specializedCopy(u *unit, v *variant) *stmt.Function {
+	decl := cloneNode(u.decl).(*stmt.Function)
+	decl.FunctionName.(*node.Identifier).Value = v.name
+
+	r.handleFunction(decl)
+	fn := r.Session.Function(decl)
+	fn.Namespace = u.fn.Namespace
+	for i := range fn.Params {
+		fn.Params[i].Type = v.params[i]
+		fn.Params[i].Typed = true
+	}
+
+	for _, call := range v.calls {
+		parts := call.Function.(*name.Name).Parts
+		parts[len(parts)-1].(*name.NamePart).Value = v.name
+	}
+
+	return decl
+}
+
+// insertAfter inserts the nodes after the function declaration,
+// which can be in the namespace block.
+func insertAfter(stmts []node.Node, decl *stmt.Function, nodes []node.Node) ([]node.Node, bool) {
+	for i, st := range stmts {
+		if st == decl {
+			res := make([]node.Node, 0, len(stmts)+len(nodes))
+			res = append(res, stmts[:i+1]...)
+			res = append(res, nodes...)
+			return append(res, stmts[i+1:]...), true
+		}
+
+		if ns, ok := st.(*stmt.Namespace); ok {
+			var inserted bool
+			if ns.Stmts, inserted = insertAfter(ns.Stmts, decl, nodes); inserted {
+				return stmts, true
+			}
+		}
+	}
+	return stmts, false
+}
+
+// cloneNode returns the deep copy of the syntax tree. The information
+// found by the analysis is stored in the session by node, so it is not
+// shared with the copy, the copy is analyzed on its own.
+func cloneNode(n node.Node) node.Node {
+	return cloneValue(reflect.ValueOf(n)).Interface().(node.Node)
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(cloneValue(v.Elem()))
+		return res
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type()).Elem()
+		res.Set(cloneValue(v.Elem()))
+		return res
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return res
+
+	case reflect.Struct:
+		res := reflect.New(v.Type()).Elem()
+		res.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			f := res.Field(i)
+			if !f.CanSet() {
+				continue
+			}
+			f.Set(cloneValue(v.Field(i)))
+		}
+		return res
+	}
+
+	return v
+}
@@ -0,0 +1,144 @@
+package solver
+
+import (
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/expr/assign"
+	"github.com/i582/php2go/src/php/node/name"
+	"github.com/i582/php2go/src/utils"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/types"
+)
+
+// mutatingFunctions are the functions that modify
+// the array passed as the first argument.
+var mutatingFunctions = map[string]struct{}{
+	"array_push":    {},
+	"array_pop":     {},
+	"array_shift":   {},
+	"array_unshift": {},
+	"array_splice":  {},
+	"sort":          {},
+	"rsort":         {},
+	"usort":         {},
+	"asort":         {},
+	"arsort":        {},
+	"ksort":         {},
+	"krsort":        {},
+	"shuffle":       {},
+}
+
+// RootVariable returns the variable whose element is accessed
+// by n, for $a[1][2] it is $a.
+func RootVariable(n node.Node) (*expr.Variable, bool) {
+	switch n := n.(type) {
+	case *expr.Variable:
+		return n, true
+	case *expr.ArrayDimFetch:
+		return RootVariable(n.Variable)
+	}
+
+	return nil, false
+}
+
+// MutatedVariable returns the array variable that
+// is modified by n, if n modifies any.
+func MutatedVariable(n node.Node) (*expr.Variable, bool) {
+	if call, ok := n.(*expr.FunctionCall); ok {
+		nm, ok := call.Function.(*name.Name)
+		if !ok || len(call.ArgumentList.Arguments) == 0 {
+			return nil, false
+		}
+		if _, ok := mutatingFunctions[utils.NamePartsToString(nm.Parts)]; !ok {
+			return nil, false
+		}
+		return RootVariable(call.ArgumentList.Arguments[0].(*node.Argument).Expr)
+	}
+
+	target, ok := AssignTarget(n)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := target.(*expr.ArrayDimFetch); !ok {
+		return nil, false
+	}
+
+	return RootVariable(target)
+}
+
+// AssignTarget returns the node written by the assignment,
+// the compound assignment or the increment n.
+func AssignTarget(n node.Node) (node.Node, bool) {
+	switch n := n.(type) {
+	case *assign.Assign:
+		return n.Variable, true
+	case *assign.Plus:
+		return n.Variable, true
+	case *assign.Minus:
+		return n.Variable, true
+	case *assign.Mul:
+		return n.Variable, true
+	case *assign.Div:
+		return n.Variable, true
+	case *assign.Mod:
+		return n.Variable, true
+	case *assign.Pow:
+		return n.Variable, true
+	case *assign.Concat:
+		return n.Variable, true
+	case *assign.Coalesce:
+		return n.Variable, true
+	case *expr.PreInc:
+		return n.Variable, true
+	case *expr.PostInc:
+		return n.Variable, true
+	case *expr.PreDec:
+		return n.Variable, true
+	case *expr.PostDec:
+		return n.Variable, true
+	}
+
+	return nil, false
+}
+
+// IsAliasExpr reports whether the value of n can be an array
+// that is also referenced from elsewhere, such value has to be
+// copied before it is modified.
+func IsAliasExpr(ctx *ctx.Context, n node.Node) bool {
+	switch n := n.(type) {
+	case *node.Argument:
+		return IsAliasExpr(ctx, n.Expr)
+	case *expr.Variable, *expr.ArrayDimFetch, *expr.ConstFetch:
+		return true
+	case *expr.FunctionCall:
+		fn, ok := CalledFunction(ctx, n)
+		return ok && fn.ReturnsAlias
+	}
+
+	return false
+}
+
+// NeedCopy reports whether the array value has to be copied when
+// it is assigned to target, that is, if the value or the target
+// is modified after the assignment in the current function.
+func NeedCopy(ctx *ctx.Context, target string, value node.Node) bool {
+	if !IsAliasExpr(ctx, value) || !ExprType(ctx, value).Is(types.Arr) {
+		return false
+	}
+
+	fn := ctx.CurrentFunction
+	if fn == nil {
+		return true
+	}
+
+	if fn.IsMutated(target) {
+		return true
+	}
+
+	if v, ok := RootVariable(value); ok {
+		return fn.IsMutated(v.VarName.(*node.Identifier).Value)
+	}
+
+	return false
+}
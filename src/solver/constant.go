@@ -0,0 +1,177 @@
+package solver
+
+import (
+	"strings"
+
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/expr/binary"
+	"github.com/i582/php2go/src/php/node/name"
+	"github.com/i582/php2go/src/php/node/scalar"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/utils"
+
+	"github.com/i582/php2go/src/constant"
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/types"
+)
+
+// ConstantNames returns the fully qualified names that are
+// tried in order to resolve the constant name n.
+func ConstantNames(ctx *ctx.Context, n node.Node) []string {
+	ns := ctx.Namespace()
+
+	switch n := n.(type) {
+	case *name.FullyQualified:
+		return []string{utils.NamePartsToString(n.Parts)}
+	case *name.Relative:
+		return []string{withNamespace(ns, utils.NamePartsToString(n.Parts))}
+	case *name.Name:
+		nm := utils.NamePartsToString(n.Parts)
+		if ns == "" {
+			return []string{nm}
+		}
+		// Unqualified constant names fall back to the global namespace.
+		if len(n.Parts) == 1 {
+			return []string{withNamespace(ns, nm), nm}
+		}
+		return []string{withNamespace(ns, nm)}
+	}
+
+	return nil
+}
+
+func withNamespace(ns string, name string) string {
+	if ns == "" {
+		return name
+	}
+	return ns + `\` + name
+}
+
+// ResolveConstant returns the constant that n refers to.
+func ResolveConstant(ctx *ctx.Context, n *expr.ConstFetch) (*constant.Constant, bool) {
+	return ctx.Session.ResolveConstant(ConstantNames(ctx, n.Constant))
+}
+
+func constantType(ctx *ctx.Context, n *expr.ConstFetch) types.Types {
+	names := ConstantNames(ctx, n.Constant)
+
+	if c, ok := ctx.Session.ResolveConstant(names); ok {
+		return c.Type
+	}
+
+	return types.NewTypes(types.NewLazyConstantType(names))
+}
+
+// IsBuiltinConstant reports whether n is one of true, false or null.
+func IsBuiltinConstant(n node.Node) bool {
+	nm, ok := n.(*name.Name)
+	if !ok {
+		return false
+	}
+
+	val := utils.NamePartsToString(nm.Parts)
+	return val == "true" || val == "false" || strings.EqualFold(val, "null")
+}
+
+// IsConstantExpr reports whether n can be evaluated at translation
+// time, that is, it does not depend on variables or function calls.
+func IsConstantExpr(ctx *ctx.Context, n node.Node) bool {
+	switch n := n.(type) {
+	case *scalar.Lnumber, *scalar.Dnumber, *scalar.String:
+		return true
+
+	case *expr.ConstFetch:
+		if IsBuiltinConstant(n.Constant) {
+			return true
+		}
+		c, ok := ResolveConstant(ctx, n)
+		return ok && !c.Runtime
+
+	case *expr.UnaryMinus:
+		return IsConstantExpr(ctx, n.Expr)
+	case *expr.UnaryPlus:
+		return IsConstantExpr(ctx, n.Expr)
+
+	case *expr.ShortArray:
+		for _, item := range n.Items {
+			if !IsConstantExpr(ctx, item) {
+				return false
+			}
+		}
+		return true
+	case *expr.ArrayItem:
+		if n.Key != nil && !IsConstantExpr(ctx, n.Key) {
+			return false
+		}
+		return IsConstantExpr(ctx, n.Val)
+
+	case *binary.Plus:
+		return IsConstantExpr(ctx, n.Left) && IsConstantExpr(ctx, n.Right)
+	case *binary.Minus:
+		return IsConstantExpr(ctx, n.Left) && IsConstantExpr(ctx, n.Right)
+	case *binary.Mul:
+		return IsConstantExpr(ctx, n.Left) && IsConstantExpr(ctx, n.Right)
+	case *binary.Div:
+		return IsConstantExpr(ctx, n.Left) && IsConstantExpr(ctx, n.Right)
+	case *binary.Concat:
+		return IsConstantExpr(ctx, n.Left) && IsConstantExpr(ctx, n.Right)
+	}
+
+	return false
+}
+
+// IsDefineCall reports whether n is a define() call with a literal name.
+func IsDefineCall(n node.Node) bool {
+	call, ok := n.(*expr.FunctionCall)
+	if !ok {
+		return false
+	}
+
+	nm, ok := call.Function.(*name.Name)
+	if !ok || utils.NamePartsToString(nm.Parts) != "define" {
+		return false
+	}
+
+	if len(call.ArgumentList.Arguments) < 2 {
+		return false
+	}
+
+	_, ok = call.ArgumentList.Arguments[0].(*node.Argument).Expr.(*scalar.String)
+	return ok
+}
+
+// DefinedConstantName returns the name of the constant from the define()
+// or defined() call, names in strings are always fully qualified.
+func DefinedConstantName(call *expr.FunctionCall) (string, bool) {
+	if len(call.ArgumentList.Arguments) == 0 {
+		return "", false
+	}
+
+	nameArg, ok := call.ArgumentList.Arguments[0].(*node.Argument).Expr.(*scalar.String)
+	if !ok {
+		return "", false
+	}
+
+	return strings.TrimPrefix(utils.StringLiteralValue(nameArg.Value), `\`), true
+}
+
+// DefinedValue returns the value expression of the define() call.
+func DefinedValue(call *expr.FunctionCall) node.Node {
+	return call.ArgumentList.Arguments[1].(*node.Argument).Expr
+}
+
+// DefinedConstant returns the constant declared by the define() call.
+func DefinedConstant(ctx *ctx.Context, call *expr.FunctionCall) *constant.Constant {
+	nm, _ := DefinedConstantName(call)
+	valueArg := DefinedValue(call)
+	runtime := !IsConstantExpr(ctx, valueArg)
+
+	return constant.NewConstant(nm, ExprTypeLocal(ctx, valueArg), valueArg, runtime)
+}
+
+// DeclaredConstant returns the constant declared by the const statement.
+func DeclaredConstant(ctx *ctx.Context, c *stmt.Constant) *constant.Constant {
+	nm := withNamespace(ctx.Namespace(), c.ConstantName.(*node.Identifier).Value)
+	return constant.NewConstant(nm, ExprTypeLocal(ctx, c.Expr), c.Expr, !IsConstantExpr(ctx, c.Expr))
+}
@@ -1,6 +1,7 @@
 package solver
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/i582/php2go/src/php/node"
@@ -9,27 +10,87 @@ import (
 	"github.com/i582/php2go/src/php/node/name"
 	"github.com/i582/php2go/src/php/node/scalar"
 	"github.com/i582/php2go/src/utils"
+	"github.com/i582/php2go/src/variable"
 
 	"github.com/i582/php2go/src/ctx"
 	"github.com/i582/php2go/src/types"
 )
 
 func binaryOpType(ctx *ctx.Context, left node.Node, right node.Node) types.Types {
-	lt := ExprTypeLocal(ctx, left)
-	rt := ExprTypeLocal(ctx, right)
+	lt := ExprType(ctx, left)
+	rt := ExprType(ctx, right)
 
+	// The type of the operand that is not known yet, like the result of
+	// the recursive call, is found by the later iterations of the inference.
 	switch {
+	case lt.Len() == 0 && rt.Len() == 0:
+		return types.Types{}
+	case lt.Len() == 0:
+		lt = rt
+	case rt.Len() == 0:
+		rt = lt
+	}
+
+	switch {
+	case IsJuggledArithmetic(lt, rt):
+		// The operands are converted at runtime, so the result
+		// is known only to be a number.
+		return types.NewBaseTypes(types.Integer, types.Float)
 	case lt.Is(types.Integer) && rt.Is(types.Integer):
 		return types.NewBaseTypes(types.Integer)
-	case lt.Is(types.Integer) && rt.Is(types.Float) || lt.Is(types.Float) && rt.Is(types.Integer):
-		return types.NewBaseTypes(types.Float)
-	case lt.Is(types.Float) && rt.Is(types.Float):
-		return types.NewBaseTypes(types.Float)
-	case lt.Is(types.String) && rt.Is(types.String):
-		return types.NewBaseTypes(types.String)
 	default:
-		panic("error operand types")
+		return types.NewBaseTypes(types.Float)
+	}
+}
+
+// divisionType returns the type of the result of the division, which
+// is float unless the integers are evenly divisible. The division of
+// integer literals is known at translation time, the result of other
+// integers is known only to be a number.
+func divisionType(ctx *ctx.Context, left node.Node, right node.Node) types.Types {
+	tp := binaryOpType(ctx, left, right)
+	if !tp.Is(types.Integer) {
+		return tp
+	}
+
+	x, xok := left.(*scalar.Lnumber)
+	y, yok := right.(*scalar.Lnumber)
+	if xok && yok {
+		a, aerr := strconv.ParseInt(x.Value, 0, 64)
+		b, berr := strconv.ParseInt(y.Value, 0, 64)
+		switch {
+		case aerr != nil || berr != nil || b == 0:
+		case a%b == 0:
+			return tp
+		default:
+			return types.NewBaseTypes(types.Float)
+		}
+	}
+
+	return types.NewBaseTypes(types.Integer, types.Float)
+}
+
+// IsJuggledArithmetic reports whether the operands of the arithmetic
+// operator are converted by the runtime functions, like PHP does,
+// since they are not both numbers of the single types.
+func IsJuggledArithmetic(lt, rt types.Types) bool {
+	isNumber := func(ts types.Types) bool {
+		return ts.Is(types.Integer) || ts.Is(types.Float)
+	}
+	return !isNumber(lt) || !isNumber(rt)
+}
+
+// IsJuggledComparison reports whether the operands of the comparison
+// are compared by the runtime function, since they are not of the
+// same single type or both numbers.
+func IsJuggledComparison(lt, rt types.Types) bool {
+	if !lt.SingleType() || !rt.SingleType() {
+		return true
 	}
+	if lt.Is(types.Integer) || lt.Is(types.Float) {
+		return !rt.Is(types.Integer) && !rt.Is(types.Float)
+	}
+	return lt.Types[0].BaseType != rt.Types[0].BaseType
 }
 
 func ExprType(ctx *ctx.Context, n node.Node) types.Types {
@@ -46,17 +107,40 @@ func ExprTypeLocal(ctx *ctx.Context, n node.Node) types.Types {
 	switch n := n.(type) {
 	case *expr.FunctionCall:
 		fnName := utils.NamePartsToString(n.Function.(*name.Name).Parts)
+		switch fnName {
+		case "define", "defined":
+			return types.NewBaseTypes(types.Bool)
+		}
 		return types.NewTypes(types.NewLazyFunctionCallType(fnName))
 	case *node.Argument:
 		return ExprTypeLocal(ctx, n.Expr)
 
+	case *expr.MethodCall:
+		return methodCallType(ctx, n)
+	case *expr.Yield:
+		return yieldType(ctx)
+	case *expr.YieldFrom:
+		return yieldFromType(ctx, n)
+
 	case *expr.Variable:
 		nm := n.VarName.(*node.Identifier).Value
 		v, ok := ctx.GetVariable(nm)
 		if !ok {
-			panic("variable not found")
+			// The variable of the nested block, like the loop body,
+			// is not visible in the context of the function.
+			v = ctx.Session.Variable(n)
+			if v == nil {
+				panic("variable not found")
+			}
 		}
-		if v.CurrentType.Len() != 0 {
+		if tp, ok := FlowType(ctx, n, v); ok {
+			return tp
+		}
+		// The current type narrows the union type of the variable,
+		// the variable of a single type is always of that type. The
+		// type is not known until it is resolved, like the type of
+		// the function call.
+		if v.CurrentType.Len() != 0 && (!v.Type.SingleType() || !v.Type.Resolved()) {
 			return v.CurrentType
 		}
 
@@ -64,7 +148,13 @@ func ExprTypeLocal(ctx *ctx.Context, n node.Node) types.Types {
 
 	case *expr.ShortArray:
 		return arrayType(ctx, n)
+	case *expr.ArrayDimFetch:
+		return arrayDimFetchType(ctx, n)
 	case *expr.ArrayItem:
+		if n.Unpack {
+			tp := ExprType(ctx, n.Val)
+			return tp.ElementType()
+		}
 		return ExprTypeLocal(ctx, n.Val)
 
 	case *scalar.Lnumber:
@@ -82,7 +172,15 @@ func ExprTypeLocal(ctx *ctx.Context, n node.Node) types.Types {
 		}
 
 	case *expr.ConstFetch:
-		return ExprTypeLocal(ctx, n.Constant)
+		if IsBuiltinConstant(n.Constant) {
+			return ExprTypeLocal(ctx, n.Constant)
+		}
+		return constantType(ctx, n)
+
+	case *expr.UnaryMinus:
+		return ExprTypeLocal(ctx, n.Expr)
+	case *expr.UnaryPlus:
+		return ExprTypeLocal(ctx, n.Expr)
 
 	case *binary.Plus:
 		return binaryOpType(ctx, n.Left, n.Right)
@@ -91,7 +189,7 @@ func ExprTypeLocal(ctx *ctx.Context, n node.Node) types.Types {
 	case *binary.Mul:
 		return binaryOpType(ctx, n.Left, n.Right)
 	case *binary.Div:
-		return binaryOpType(ctx, n.Left, n.Right)
+		return divisionType(ctx, n.Left, n.Right)
 	case *binary.Concat:
 		return types.NewBaseTypes(types.String)
 
@@ -99,6 +197,12 @@ func ExprTypeLocal(ctx *ctx.Context, n node.Node) types.Types {
 		return types.NewBaseTypes(types.Bool)
 	case *binary.NotEqual:
 		return types.NewBaseTypes(types.Bool)
+	case *binary.Identical:
+		return types.NewBaseTypes(types.Bool)
+	case *expr.InstanceOf:
+		return types.NewBaseTypes(types.Bool)
+	case *binary.NotIdentical:
+		return types.NewBaseTypes(types.Bool)
 	case *binary.Smaller:
 		return types.NewBaseTypes(types.Bool)
 	case *binary.SmallerOrEqual:
@@ -119,28 +223,282 @@ func ExprTypeLocal(ctx *ctx.Context, n node.Node) types.Types {
 		return types.NewBaseTypes(types.Bool)
 	case *binary.BooleanOr:
 		return types.NewBaseTypes(types.Bool)
+	case *expr.BooleanNot:
+		return types.NewBaseTypes(types.Bool)
+
+	case *expr.Isset:
+		return types.NewBaseTypes(types.Bool)
+	case *expr.Empty:
+		return types.NewBaseTypes(types.Bool)
+	case *binary.Coalesce:
+		return coalesceType(ctx, n)
 	}
 
 	return types.Types{}
 }
 
+// coalesceType returns the type of $x ?? $y, which is the type of $x
+// other than null or the type of $y. The undefined $x is never read.
+func coalesceType(ctx *ctx.Context, c *binary.Coalesce) types.Types {
+	var res types.Types
+	if IsDefined(ctx, c.Left) {
+		for _, t := range ExprType(ctx, c.Left).Types {
+			if !t.Is(types.Null) {
+				res.Add(t)
+			}
+		}
+	}
+	res.Merge(ExprType(ctx, c.Right))
+
+	return res
+}
+
+// IsDefined reports whether the variable accessed by n is defined,
+// n that does not access a variable is always considered defined.
+func IsDefined(ctx *ctx.Context, n node.Node) bool {
+	v, ok := RootVariable(n)
+	if !ok {
+		return true
+	}
+
+	if _, ok := ctx.GetVariable(v.VarName.(*node.Identifier).Value); ok {
+		return true
+	}
+	return ctx.Session.Variable(v) != nil
+}
+
 func arrayType(ctx *ctx.Context, a *expr.ShortArray) types.Types {
+	// The types of elements of the empty array are
+	// inferred from the writes to it, see BlockWalker.
 	if len(a.Items) == 0 {
-		return types.NewTypes(types.NewArrayType(types.Integer))
+		return types.NewTypes(types.NewPlainArrayType(types.Types{}, 1))
+	}
+
+	// The types of all items are merged, so the nested
+	// arrays get the types of all their elements.
+	var valType types.Types
+	for _, item := range a.Items {
+		valType.MergeDeep(ExprTypeLocal(ctx, item))
+	}
+
+	dim := uint8(1)
+	if valType.Is(types.Arr) {
+		dim += valType.Types[0].ArrayDim
+	}
+
+	shape := arrayShape(ctx, a)
+
+	if !IsAssociativeArray(a) {
+		t := types.NewPlainArrayType(valType, dim)
+		t.Shape = shape
+		return types.NewTypes(t)
+	}
+
+	var keyType types.Types
+	for _, item := range a.Items {
+		item := item.(*expr.ArrayItem)
+		if item.Key == nil {
+			keyType.Add(types.NewType(types.Integer))
+			continue
+		}
+		keyType.Merge(ArrayKeyType(ctx, item.Key))
+	}
+
+	t := types.NewAssociativeArrayType(keyType, valType, dim)
+	t.Shape = shape
+	return types.NewTypes(t)
+}
+
+// arrayShape returns the types of the elements of the array literal by keys,
+// if all keys are known at translation time and the types of elements differ.
+func arrayShape(ctx *ctx.Context, a *expr.ShortArray) map[string]types.Types {
+	shape := make(map[string]types.Types, len(a.Items))
+	var index int64
+	var valType types.Types
+
+	for _, item := range a.Items {
+		item := item.(*expr.ArrayItem)
+		if item.Unpack {
+			return nil
+		}
+
+		var key string
+		if item.Key == nil {
+			key = "i:" + strconv.FormatInt(index, 10)
+			index++
+		} else {
+			k, ok := LiteralKey(item.Key)
+			if !ok {
+				return nil
+			}
+			key = k
+			if strings.HasPrefix(k, "i:") {
+				if n, _ := strconv.ParseInt(k[2:], 10, 64); n >= index {
+					index = n + 1
+				}
+			}
+		}
+
+		tp := ExprTypeLocal(ctx, item)
+		shape[key] = tp
+		valType.Merge(tp)
+	}
+
+	if valType.SingleType() {
+		return nil
+	}
+
+	return shape
+}
+
+// LiteralKey returns the array key n converted like PHP does,
+// if it is known at translation time, "i:" and "s:" prefixes
+// distinguish integer and string keys.
+func LiteralKey(n node.Node) (string, bool) {
+	switch n := n.(type) {
+	case *scalar.Lnumber:
+		v, err := strconv.ParseInt(n.Value, 0, 64)
+		if err != nil {
+			return "", false
+		}
+		return "i:" + strconv.FormatInt(v, 10), true
+	case *scalar.String:
+		v := utils.StringLiteralValue(n.Value)
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil && strconv.FormatInt(i, 10) == v {
+			return "i:" + v, true
+		}
+		return "s:" + v, true
+	}
+
+	return "", false
+}
+
+// ArrayKeyType returns the types of the key after the conversion that
+// PHP applies to it. Strings containing decimal integers become integers,
+// so the key of string type that is not known at translation time can be
+// either of them.
+func ArrayKeyType(ctx *ctx.Context, key node.Node) types.Types {
+	if s, ok := key.(*scalar.String); ok {
+		v := utils.StringLiteralValue(s.Value)
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && strconv.FormatInt(n, 10) == v {
+			return types.NewBaseTypes(types.Integer)
+		}
+		return types.NewBaseTypes(types.String)
+	}
+
+	var res types.Types
+	for _, t := range ExprType(ctx, key).Types {
+		switch t.BaseType {
+		case types.String:
+			res.Add(types.NewType(types.Integer))
+			res.Add(types.NewType(types.String))
+		case types.Null:
+			res.Add(types.NewType(types.String))
+		default:
+			res.Add(types.NewType(types.Integer))
+		}
+	}
+	return res
+}
+
+// IsAssociativeArray reports whether any item of the array literal
+// has a key, such arrays are represented by OrderedArray.
+func IsAssociativeArray(a *expr.ShortArray) bool {
+	for _, item := range a.Items {
+		if item, ok := item.(*expr.ArrayItem); ok && item.Key != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IsArrayPushCall reports whether n is an array_push() call,
+// which is translated like the appends of all its values.
+func IsArrayPushCall(n node.Node) bool {
+	call, ok := n.(*expr.FunctionCall)
+	if !ok {
+		return false
+	}
+
+	nm, ok := call.Function.(*name.Name)
+	if !ok || utils.NamePartsToString(nm.Parts) != "array_push" {
+		return false
+	}
+
+	return len(call.ArgumentList.Arguments) != 0
+}
+
+func arrayDimFetchType(ctx *ctx.Context, f *expr.ArrayDimFetch) types.Types {
+	tp := ExprType(ctx, f.Variable)
+	if tp.Is(types.String) {
+		return tp
+	}
+
+	if tp.Is(types.Arr) && tp.Types[0].Shape != nil && f.Dim != nil {
+		if key, ok := LiteralKey(f.Dim); ok {
+			if elem, ok := tp.Types[0].Shape[key]; ok {
+				return elem
+			}
+		}
 	}
 
-	isAssoc := a.Items[0].(*expr.ArrayItem).Key != nil
+	return tp.ElementType()
+}
 
-	keyType := ExprTypeLocal(ctx, a.Items[0].(*expr.ArrayItem).Key)
-	valType := ExprTypeLocal(ctx, a.Items[0].(*expr.ArrayItem).Val)
+// ListElementType returns the type of the element of the destructured
+// array of type arrType that is assigned to the list item, index is the
+// position of the item without the key. The elements with the keys known
+// at translation time get their types from the shape of the array. The
+// nested list gets the array type if the element is of union type.
+func ListElementType(arrType types.Types, item *expr.ArrayItem, index int64) types.Types {
+	elemType := arrType.ElementType()
 
-	var t types.Types
+	if arrType.Is(types.Arr) && arrType.Types[0].Shape != nil {
+		key, ok := "i:"+strconv.FormatInt(index, 10), true
+		if item.Key != nil {
+			key, ok = LiteralKey(item.Key)
+		}
+		if tp, found := arrType.Types[0].Shape[key]; ok && found {
+			elemType = tp
+		}
+	}
 
-	if isAssoc {
-		t = types.NewTypes(types.NewAssociativeArrayType(keyType, valType, 1))
-	} else {
-		t = types.NewTypes(types.NewPlainArrayType(valType, 1))
+	switch item.Val.(type) {
+	case *expr.List, *expr.ShortList, *expr.ShortArray:
+		if i := indexOfBase(elemType, types.Arr); i != -1 && !elemType.SingleType() {
+			return types.NewTypes(elemType.Types[i])
+		}
 	}
 
-	return t
+	return elemType
+}
+
+// FlowType returns the type of the variable at the node found by the
+// dataflow analysis. The types are taken from the declared type of the
+// variable, which holds the element types of arrays. The variable of a
+// single type always has that type.
+func FlowType(ctx *ctx.Context, n *expr.Variable, v *variable.Variable) (types.Types, bool) {
+	flowType := ctx.Session.FlowType(n)
+	if flowType.Len() == 0 || v.Type.SingleType() && v.Type.ContainsMap(flowType) {
+		return types.Types{}, false
+	}
+
+	var res types.Types
+	for _, t := range flowType.Types {
+		if i := indexOfBase(v.Type, t.BaseType); i != -1 {
+			res.Add(v.Type.Types[i])
+		} else {
+			res.Add(t)
+		}
+	}
+	return res, true
+}
+
+func indexOfBase(ts types.Types, base types.Base) int {
+	for i, t := range ts.Types {
+		if t.Is(base) {
+			return i
+		}
+	}
+	return -1
 }
@@ -0,0 +1,73 @@
+package solver
+
+import (
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/types"
+)
+
+// GeneratorType returns the generator type of n if n is a generator.
+func GeneratorType(ctx *ctx.Context, n node.Node) (types.Type, bool) {
+	tp := ExprType(ctx, n)
+	if !tp.Is(types.Generator) {
+		return types.Type{}, false
+	}
+
+	return tp.Types[0], true
+}
+
+// MethodName returns the name of the called method.
+func MethodName(m *expr.MethodCall) string {
+	id, ok := m.Method.(*node.Identifier)
+	if !ok {
+		return ""
+	}
+
+	return id.Value
+}
+
+func yieldType(ctx *ctx.Context) types.Types {
+	if ctx.CurrentFunction == nil {
+		return types.Types{}
+	}
+
+	// The value of the yield expression is null
+	// when the generator is resumed with next().
+	return types.NewTypes(
+		types.NewLazyGeneratorSendType(ctx.CurrentFunction.Name),
+		types.NewType(types.Null),
+	)
+}
+
+func yieldFromType(ctx *ctx.Context, y *expr.YieldFrom) types.Types {
+	gen, ok := GeneratorType(ctx, y.Expr)
+	if !ok {
+		return types.NewBaseTypes(types.Null)
+	}
+
+	return types.NewTypes(types.NewLazyGeneratorReturnType(gen.FunctionName))
+}
+
+func methodCallType(ctx *ctx.Context, m *expr.MethodCall) types.Types {
+	gen, ok := GeneratorType(ctx, m.Variable)
+	if !ok {
+		return types.Types{}
+	}
+
+	switch MethodName(m) {
+	case "current", "send":
+		return gen.ElemTypes
+	case "key":
+		return gen.KeysTypes
+	case "valid":
+		return types.NewBaseTypes(types.Bool)
+	case "next", "rewind":
+		return types.NewBaseTypes(types.Void)
+	case "getReturn":
+		return types.NewTypes(types.NewLazyGeneratorReturnType(gen.FunctionName))
+	}
+
+	return types.Types{}
+}
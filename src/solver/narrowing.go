@@ -0,0 +1,321 @@
+package solver
+
+import (
+	"strings"
+
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/expr/binary"
+	"github.com/i582/php2go/src/php/node/name"
+	"github.com/i582/php2go/src/php/node/stmt"
+	"github.com/i582/php2go/src/php/walker"
+	"github.com/i582/php2go/src/utils"
+	"github.com/i582/php2go/src/variable"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/types"
+)
+
+// Narrowing is the type of the variable of a union type
+// in the code guarded by a condition.
+type Narrowing struct {
+	Var  *variable.Variable
+	Type types.Types
+}
+
+// isTTypes are the types checked by the is_* functions.
+var isTTypes = map[string]types.Base{
+	"is_int":    types.Integer,
+	"is_float":  types.Float,
+	"is_string": types.String,
+	"is_bool":   types.Bool,
+	"is_null":   types.Null,
+	"is_array":  types.Arr,
+}
+
+// instanceOfTypes are the types checked by instanceof,
+// only generators are objects at runtime.
+var instanceOfTypes = map[string]types.Base{
+	"Generator": types.Generator,
+}
+
+// InstanceOfType returns the type checked by instanceof,
+// if the class is the class of the values of any type.
+func InstanceOfType(n *expr.InstanceOf) (types.Type, bool) {
+	var nm string
+	switch class := n.Class.(type) {
+	case *name.Name:
+		nm = utils.NamePartsToString(class.Parts)
+	case *name.FullyQualified:
+		nm = utils.NamePartsToString(class.Parts)
+	default:
+		return types.Type{}, false
+	}
+
+	base, ok := instanceOfTypes[strings.TrimPrefix(nm, `\`)]
+	if !ok {
+		return types.Type{}, false
+	}
+	return types.NewType(base), true
+}
+
+// Narrowings returns the types of the variables in the code that runs
+// if cond is true, or if it is false when negated is set. Only the
+// variables of union types are narrowed.
+func Narrowings(ctx *ctx.Context, cond node.Node, negated bool) []Narrowing {
+	switch n := cond.(type) {
+	case *expr.BooleanNot:
+		return Narrowings(ctx, n.Expr, !negated)
+
+	case *binary.BooleanAnd:
+		return combineNarrowings(ctx, n.Left, n.Right, negated, !negated)
+	case *binary.LogicalAnd:
+		return combineNarrowings(ctx, n.Left, n.Right, negated, !negated)
+	case *binary.BooleanOr:
+		return combineNarrowings(ctx, n.Left, n.Right, negated, negated)
+	case *binary.LogicalOr:
+		return combineNarrowings(ctx, n.Left, n.Right, negated, negated)
+
+	case *binary.Identical:
+		return nullNarrowing(ctx, n.Left, n.Right, negated)
+	case *binary.NotIdentical:
+		return nullNarrowing(ctx, n.Left, n.Right, !negated)
+
+	case *expr.InstanceOf:
+		class, ok := InstanceOfType(n)
+		if !ok {
+			return nil
+		}
+		return narrowVariable(ctx, n.Expr, class.BaseType, negated)
+
+	case *expr.FunctionCall:
+		nm, ok := n.Function.(*name.Name)
+		if !ok || len(n.ArgumentList.Arguments) != 1 {
+			return nil
+		}
+		base, ok := isTTypes[utils.NamePartsToString(nm.Parts)]
+		if !ok {
+			return nil
+		}
+		return narrowVariable(ctx, n.ArgumentList.Arguments[0], base, negated)
+	}
+
+	return nil
+}
+
+// combineNarrowings returns the narrowings of both operands of && or ||.
+// Both operands hold if intersect is set, like for the true &&, so the
+// types of the variables narrowed by both are intersected. Otherwise,
+// like for the true ||, only one of them holds, so only the variables
+// narrowed by both operands are narrowed, to the union of the types.
+func combineNarrowings(ctx *ctx.Context, left, right node.Node, negated, intersect bool) []Narrowing {
+	ln := Narrowings(ctx, left, negated)
+	rn := Narrowings(ctx, right, negated)
+
+	var res []Narrowing
+	for _, l := range ln {
+		r, ok := findNarrowing(rn, l.Var)
+		switch {
+		case ok && intersect:
+			res = append(res, Narrowing{Var: l.Var, Type: intersectTypes(l.Type, r.Type)})
+		case ok:
+			tp := l.Type
+			tp.Merge(r.Type)
+			res = append(res, Narrowing{Var: l.Var, Type: tp})
+		case intersect:
+			res = append(res, l)
+		}
+	}
+
+	if intersect {
+		for _, r := range rn {
+			if _, ok := findNarrowing(ln, r.Var); !ok {
+				res = append(res, r)
+			}
+		}
+	}
+
+	return res
+}
+
+func findNarrowing(ns []Narrowing, v *variable.Variable) (Narrowing, bool) {
+	for _, n := range ns {
+		if n.Var == v {
+			return n, true
+		}
+	}
+	return Narrowing{}, false
+}
+
+func intersectTypes(a, b types.Types) types.Types {
+	var res types.Types
+	for _, t := range a.Types {
+		if b.Contains(t) {
+			res.Add(t)
+		}
+	}
+	return res
+}
+
+// nullNarrowing returns the narrowing by === null.
+func nullNarrowing(ctx *ctx.Context, left, right node.Node, negated bool) []Narrowing {
+	switch {
+	case IsNullLiteral(right):
+		return narrowVariable(ctx, left, types.Null, negated)
+	case IsNullLiteral(left):
+		return narrowVariable(ctx, right, types.Null, negated)
+	}
+	return nil
+}
+
+// IsNullLiteral reports whether n is the null constant.
+func IsNullLiteral(n node.Node) bool {
+	c, ok := n.(*expr.ConstFetch)
+	if !ok {
+		return false
+	}
+	nm, ok := c.Constant.(*name.Name)
+	return ok && strings.EqualFold(utils.NamePartsToString(nm.Parts), "null")
+}
+
+// narrowVariable returns the narrowing of the variable n to the types
+// of base, or to the other types if negated is set.
+func narrowVariable(ctx *ctx.Context, n node.Node, base types.Base, negated bool) []Narrowing {
+	if arg, ok := n.(*node.Argument); ok {
+		n = arg.Expr
+	}
+	v, ok := n.(*expr.Variable)
+	if !ok {
+		return nil
+	}
+	vr := ctx.Session.Variable(v)
+	if vr == nil {
+		return nil
+	}
+
+	tp := ExprType(ctx, v)
+	if tp.SingleType() {
+		return nil
+	}
+
+	var narrowed types.Types
+	for _, t := range tp.Types {
+		if t.Is(base) != negated {
+			narrowed.Add(t)
+		}
+	}
+
+	// The branch is never run.
+	if narrowed.Len() == 0 {
+		return nil
+	}
+
+	return []Narrowing{{Var: vr, Type: narrowed}}
+}
+
+// BranchExits reports whether the branch always leaves the enclosing
+// code, so the code after the if runs only if the condition is false.
+func BranchExits(n node.Node) bool {
+	if list, ok := n.(*stmt.StmtList); ok {
+		if len(list.Stmts) == 0 {
+			return false
+		}
+		n = list.Stmts[len(list.Stmts)-1]
+	}
+
+	switch n.(type) {
+	case *stmt.Return, *stmt.Break, *stmt.Continue:
+		return true
+	}
+	return false
+}
+
+// variableUsage finds reads and writes of the variable
+// without descending into nested functions and closures.
+type variableUsage struct {
+	name    string
+	read    bool
+	written bool
+}
+
+func (u variableUsage) EnterChildNode(key string, w walker.Walkable) {}
+func (u variableUsage) LeaveChildNode(key string, w walker.Walkable) {}
+func (u variableUsage) EnterChildList(key string, w walker.Walkable) {}
+func (u variableUsage) LeaveChildList(key string, w walker.Walkable) {}
+func (u *variableUsage) LeaveNode(w walker.Walkable)                 {}
+
+func (u *variableUsage) EnterNode(w walker.Walkable) bool {
+	switch n := w.(type) {
+	case *stmt.Function, *expr.Closure, *expr.ArrowFunction:
+		return false
+	case *expr.Variable:
+		if id, ok := n.VarName.(*node.Identifier); ok && id.Value == u.name {
+			u.read = true
+		}
+	case *stmt.Unset:
+		for _, v := range n.Vars {
+			u.markWritten(v)
+		}
+	case *stmt.Foreach:
+		u.markWritten(n.Key)
+		u.markWritten(n.Variable)
+	case node.Node:
+		if target, ok := AssignTarget(n); ok {
+			u.markWritten(target)
+		}
+	}
+
+	return true
+}
+
+// markWritten marks the variable written if n is the variable, its
+// element or the list containing it.
+func (u *variableUsage) markWritten(n node.Node) {
+	var items []node.Node
+	switch n := n.(type) {
+	case *expr.List:
+		items = n.Items
+	case *expr.ShortList:
+		items = n.Items
+	default:
+		if v, ok := RootVariable(n); ok {
+			if id, ok := v.VarName.(*node.Identifier); ok && id.Value == u.name {
+				u.written = true
+			}
+		}
+		return
+	}
+
+	for _, item := range items {
+		if item, ok := item.(*expr.ArrayItem); ok && item.Val != nil {
+			u.markWritten(item.Val)
+		}
+	}
+}
+
+// VariableUsage reports whether the variable is read
+// and whether it is written in n.
+func VariableUsage(n node.Node, name string) (read, written bool) {
+	u := &variableUsage{name: name}
+	n.Walk(u)
+	return u.read, u.written
+}
+
+// ApplyNarrowings sets the current types of the variables to the
+// narrowed types. The returned function restores the current types
+// of the variables that are not assigned since then.
+func ApplyNarrowings(ns []Narrowing) (restore func()) {
+	prev := make([]types.Types, len(ns))
+	for i, n := range ns {
+		prev[i] = n.Var.CurrentType
+		n.Var.CurrentType = n.Type
+	}
+
+	return func() {
+		for i, n := range ns {
+			if n.Var.CurrentType.Equal(n.Type) {
+				n.Var.CurrentType = prev[i]
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package solver
+
+import (
+	"strings"
+
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/node/expr"
+	"github.com/i582/php2go/src/php/node/name"
+	"github.com/i582/php2go/src/utils"
+
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/function"
+	"github.com/i582/php2go/src/types"
+)
+
+// HintType returns the type for the type hint n
+// if it is one of the supported scalar types.
+func HintType(n node.Node) (types.Types, bool) {
+	nm, ok := n.(*name.Name)
+	if !ok {
+		return types.Types{}, false
+	}
+
+	switch strings.ToLower(utils.NamePartsToString(nm.Parts)) {
+	case "int":
+		return types.NewBaseTypes(types.Integer), true
+	case "float":
+		return types.NewBaseTypes(types.Float), true
+	case "string":
+		return types.NewBaseTypes(types.String), true
+	case "bool":
+		return types.NewBaseTypes(types.Bool), true
+	}
+
+	return types.Types{}, false
+}
+
+// Param returns the description of the function parameter p,
+// its type is taken from the type hint or the default value.
+func Param(ctx *ctx.Context, p *node.Parameter) function.Param {
+	param := function.Param{
+		Name:     p.Variable.(*expr.Variable).VarName.(*node.Identifier).Value,
+		Default:  p.DefaultValue,
+		Variadic: p.Variadic,
+	}
+
+	if tp, ok := HintType(p.VariableType); ok {
+		param.Type = tp
+		param.Typed = true
+	} else if p.DefaultValue != nil {
+		param.Type = ExprType(ctx, p.DefaultValue)
+		param.Typed = true
+	}
+
+	return param
+}
+
+// ParamVariableType returns the type of the variable
+// that holds the parameter inside the function.
+func ParamVariableType(p function.Param) types.Types {
+	if p.Variadic {
+		return types.NewTypes(types.NewPlainArrayType(p.Type, 1))
+	}
+	return p.Type
+}
+
+// CalledFunction returns the user function called by c.
+func CalledFunction(ctx *ctx.Context, c *expr.FunctionCall) (*function.Function, bool) {
+	nm, ok := c.Function.(*name.Name)
+	if !ok {
+		return nil, false
+	}
+	return ctx.Session.GetFunction(utils.NamePartsToString(nm.Parts))
+}
+
+// IsSpread reports whether the argument is unpacked with "...".
+func IsSpread(arg node.Node) bool {
+	a, ok := arg.(*node.Argument)
+	return ok && a.Variadic
+}
+
+// MergeArgumentTypes adds the types of the arguments of the call
+// to the parameters of fn whose type is not declared. If constOnly
+// is set, only the arguments known at translation time are used.
+func MergeArgumentTypes(ctx *ctx.Context, fn *function.Function, args []node.Node, constOnly bool) {
+	for i, arg := range args {
+		if constOnly && !IsConstantExpr(ctx, arg.(*node.Argument).Expr) {
+			continue
+		}
+
+		tp := ExprType(ctx, arg)
+		if IsSpread(arg) {
+			tp = tp.ElementType()
+		}
+
+		idx, ok := fn.ParamIndex(i)
+		if !ok {
+			continue
+		}
+
+		// The spread array fills all the remaining parameters.
+		last := idx
+		if IsSpread(arg) {
+			last = len(fn.Params) - 1
+		}
+
+		for ; idx <= last; idx++ {
+			if !fn.Params[idx].Typed {
+				fn.Params[idx].Type.Merge(tp)
+			}
+		}
+	}
+}
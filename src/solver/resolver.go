@@ -2,27 +2,91 @@ package solver
 
 import (
 	"github.com/i582/php2go/src/ctx"
-	"github.com/i582/php2go/src/meta"
 	"github.com/i582/php2go/src/types"
 )
 
+// ResolveTypes replaces the lazy types with the types they stand for.
+// The types are resolved until no lazy types are left, the lazy type
+// that stands for itself, like the return type of the recursive call,
+// adds no types.
 func ResolveTypes(ctx *ctx.Context, tp types.Types) types.Types {
+	return resolveTypes(ctx, tp, make(map[string]int), 0)
+}
+
+func ResolveType(ctx *ctx.Context, t types.Type) types.Types {
+	return resolveType(ctx, t, make(map[string]int), 0)
+}
+
+// resolveTypes resolves the lazy types, the element types of arrays
+// included. The seen map holds the lazy types being resolved with the
+// depth of arrays they were met at. The lazy type met again at the same
+// depth adds nothing, while the one met in the elements of the arrays
+// it stands for is the array nested without end and becomes mixed.
+func resolveTypes(ctx *ctx.Context, tp types.Types, seen map[string]int, depth int) types.Types {
 	res := types.Types{}
 	for _, t := range tp.Types {
-		res.Merge(ResolveType(ctx, t))
+		res.Merge(resolveType(ctx, t, seen, depth))
 	}
 	return res
 }
 
-func ResolveType(ctx *ctx.Context, t types.Type) types.Types {
+func resolveType(ctx *ctx.Context, t types.Type, seen map[string]int, depth int) types.Types {
+	if t.Is(types.Arr) && !t.ElemTypes.Resolved() {
+		t.ElemTypes = resolveTypes(ctx, t.ElemTypes, seen, depth+1)
+		return types.NewTypes(t)
+	}
+	if !t.IsLazy() {
+		return types.NewTypes(t)
+	}
+
+	key := t.String()
+	if d, ok := seen[key]; ok {
+		if d < depth {
+			return types.NewBaseTypes(types.Mixed)
+		}
+		return types.Types{}
+	}
+	seen[key] = depth
+	defer delete(seen, key)
+
+	return resolveTypes(ctx, lazyTypeSource(ctx, t), seen, depth)
+}
+
+// lazyTypeSource returns the types the lazy type stands for,
+// which can be lazy as well.
+func lazyTypeSource(ctx *ctx.Context, t types.Type) types.Types {
 	switch t.LazyType {
 	case types.FunctionCall:
-		fnInfo, ok := meta.GetFunction(t.FunctionName)
+		fnInfo, ok := ctx.Session.GetFunction(t.FunctionName)
 		if !ok {
 			return types.Types{}
 		}
 		return fnInfo.ReturnType
+	case types.GeneratorSend:
+		fnInfo, ok := ctx.Session.GetFunction(t.FunctionName)
+		if !ok {
+			return types.Types{}
+		}
+		return fnInfo.SendTypes
+	case types.GeneratorReturn:
+		fnInfo, ok := ctx.Session.GetFunction(t.FunctionName)
+		if !ok {
+			return types.Types{}
+		}
+		return fnInfo.GeneratorReturnType
+	case types.FunctionParam:
+		fnInfo, ok := ctx.Session.GetFunction(t.FunctionName)
+		if !ok || t.ParamIndex >= len(fnInfo.Params) {
+			return types.Types{}
+		}
+		return ParamVariableType(fnInfo.Params[t.ParamIndex])
+	case types.ConstantFetch:
+		c, ok := ctx.Session.ResolveConstant(t.ConstantNames)
+		if !ok {
+			return types.Types{}
+		}
+		return c.Type
 	}
 
-	return types.NewTypes(t)
+	return types.Types{}
 }
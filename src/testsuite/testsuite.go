@@ -7,16 +7,31 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 
+	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/php7"
 	"github.com/i582/php2go/src/root"
 
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/function"
 	"github.com/i582/php2go/src/generator"
+	"github.com/i582/php2go/src/lower"
 )
 
 type Suite struct {
 	t        *testing.T
 	Content  []byte
 	Expected []byte
+
+	Executable bool
+	// Specializations is the maximum number of
+	// the specialized copies of a function.
+	Specializations int
+	// InlineRuntime makes the runtime written to the core
+	// file instead of the import of the runtime package.
+	InlineRuntime bool
+	// StrictArrayKeys makes reads of undefined
+	// array keys panic instead of the warning.
+	StrictArrayKeys bool
 }
 
 func NewSuite(t *testing.T) Suite {
@@ -41,20 +56,46 @@ func (s *Suite) RunTest() {
 		s.t.Error(e)
 	}
 
-	rw := root.RootWalker{}
+	session := ctx.NewSession()
+	rw := root.RootWalker{MaxSpecializations: s.Specializations, Session: session, Filename: "test.php"}
 
 	rootNode := parser.GetRootNode()
 	rootNode.Walk(&rw)
+	if err := rw.Err(); err != nil {
+		s.t.Fatal(err)
+	}
 
 	main := bytes.NewBuffer(nil)
 	core := bytes.NewBuffer(nil)
-	gw := generator.NewGeneratorWalker(main, core, "test.php")
-	rootNode.Walk(&gw)
-	gw.Final()
+	var entryPoint *function.Function
+	if s.Executable {
+		entryPoint = rw.Main
+	}
+	file, err := lower.File(session, rootNode.(*node.Root), "test.php", entryPoint)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	generate := func(main, core *bytes.Buffer) {
+		gen := generator.NewGenerator(main, core, "test.php")
+		gen.SetInlineRuntime(s.InlineRuntime)
+		gen.SetStrictArrayKeys(s.StrictArrayKeys)
+		gen.Generate(file)
+		if err := gen.Final(); err != nil {
+			s.t.Fatalf("%s\n%s", err, main.String())
+		}
+	}
+	generate(main, core)
+
+	// The generator only reads the IR, so it gives the same code again.
+	again := bytes.NewBuffer(nil)
+	generate(again, bytes.NewBuffer(nil))
+	if !cmp.Equal(again.String(), main.String()) {
+		s.t.Errorf("the second generation differs:\n%s", cmp.Diff(again.String(), main.String()))
+	}
 
 	want := main.String()
 	want = strings.TrimPrefix(main.String(), "// Code generated by php2go. PLEASE DO NOT EDIT.\n")
-	want = strings.TrimSuffix(want, "\n")
 
 	expected := string(s.Expected)
 	expected = strings.TrimPrefix(expected, "\n")
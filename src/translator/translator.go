@@ -7,10 +7,16 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/function"
 	"github.com/i582/php2go/src/generator"
+	"github.com/i582/php2go/src/lower"
+	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/php7"
+	"github.com/i582/php2go/src/pipeline"
 	"github.com/i582/php2go/src/root"
 )
 
@@ -23,13 +29,61 @@ func NewTranslator() Translator {
 
 func (t Translator) Run() {
 	var inputFile string
-	flag.StringVar(&inputFile, "i", "", "input file")
+	flag.StringVar(&inputFile, "i", "", "input file or directory")
 
 	var outputFile string
-	flag.StringVar(&outputFile, "o", "", "output file")
+	flag.StringVar(&outputFile, "o", "", "output file or directory")
+
+	var mode string
+	flag.StringVar(&mode, "mode", "library", "output mode: library or executable")
+
+	var arrayKeys string
+	flag.StringVar(&arrayKeys, "array-keys", "lenient", "reads of undefined array keys: lenient (warning and null) or strict (panic)")
+
+	var specializations int
+	flag.IntVar(&specializations, "specialize", 0, "the maximum number of specialized copies of a function for differently typed calls, 0 disables")
+
+	var inlineRuntime bool
+	flag.BoolVar(&inlineRuntime, "inline-runtime", false, "write the runtime to core.go instead of importing github.com/i582/php2go/runtime, so the output has no dependencies")
+
+	var workers int
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "the number of the files of the directory translated at once")
 
 	flag.Parse()
 
+	if mode != "library" && mode != "executable" {
+		log.Fatalf("unknown mode: %s", mode)
+	}
+
+	if arrayKeys != "lenient" && arrayKeys != "strict" {
+		log.Fatalf("unknown array keys policy: %s", arrayKeys)
+	}
+
+	if info, err := os.Stat(inputFile); err == nil && info.IsDir() {
+		if mode != "library" {
+			log.Fatalf("directory %s can be translated only in the library mode", inputFile)
+		}
+		if outputFile == "" {
+			outputFile = inputFile
+		}
+
+		files, err := pipeline.TranslateDir(inputFile, outputFile, pipeline.Options{
+			Workers:         workers,
+			Specializations: specializations,
+			StrictArrayKeys: arrayKeys == "strict",
+			InlineRuntime:   inlineRuntime,
+		})
+		for _, f := range files {
+			for _, e := range f.Errors {
+				fmt.Println(f.Name + ": " + e.String())
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	inputFolder, _ := filepath.Split(inputFile)
 
 	if outputFile == "" {
@@ -52,9 +106,13 @@ func (t Translator) Run() {
 		fmt.Println(e)
 	}
 
-	rw := root.RootWalker{}
+	session := ctx.NewSession()
+	rw := root.RootWalker{MaxSpecializations: specializations, Session: session, Filename: filepath.Base(inputFile)}
 	rootNode := parser.GetRootNode()
 	rootNode.Walk(&rw)
+	if err := rw.Err(); err != nil {
+		log.Fatal(err)
+	}
 
 	f, err := os.Create(outputFile)
 	if err != nil {
@@ -62,16 +120,38 @@ func (t Translator) Run() {
 	}
 	defer f.Close()
 
-	core, err := os.Create(inputFolder + "/core.go")
+	// The runtime is imported unless it is inlined, then it is written
+	// to the core file, which is created only if the runtime is used.
+	var core strings.Builder
+
+	var entryPoint *function.Function
+	if mode == "executable" {
+		entryPoint = rw.Main
+	}
+	file, err := lower.File(session, rootNode.(*node.Root), filepath.Base(inputFile), entryPoint)
 	if err != nil {
-		log.Fatalf("core file not created: %s", err)
+		log.Fatal(err)
 	}
 
-	gw := generator.NewGeneratorWalker(f, core, filepath.Base(inputFile))
-	rootNode.Walk(&gw)
-	gw.Final()
+	gen := generator.NewGenerator(f, &core, filepath.Base(inputFile))
+	gen.SetStrictArrayKeys(arrayKeys == "strict")
+	gen.SetInlineRuntime(inlineRuntime)
+	gen.Generate(file)
+	if err := gen.Final(); err != nil {
+		log.Fatal(err)
+	}
+
+	if core.Len() != 0 {
+		if err := ioutil.WriteFile(inputFolder+"/core.go", []byte(core.String()), 0644); err != nil {
+			log.Fatalf("core file not created: %s", err)
+		}
+	}
 
-	gw = generator.NewGeneratorWalker(os.Stdout, os.Stdout, filepath.Base(inputFile))
-	rootNode.Walk(&gw)
-	gw.Final()
+	gen = generator.NewGenerator(os.Stdout, os.Stdout, filepath.Base(inputFile))
+	gen.SetStrictArrayKeys(arrayKeys == "strict")
+	gen.SetInlineRuntime(inlineRuntime)
+	gen.Generate(file)
+	if err := gen.Final(); err != nil {
+		log.Fatal(err)
+	}
 }
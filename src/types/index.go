@@ -0,0 +1,93 @@
+package types
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"github.com/i582/php2go/src/goast"
+	"github.com/i582/php2go/src/utils"
+)
+
+// AddIndexType adds the slice type whose elements are read, for each
+// of such types the separate function is generated.
+func (v *VarInfo) AddIndexType(t Type) {
+	v.IndexTypes[t.String()] = t
+}
+
+// NeedIndexFunctions reports whether the elements of slices are read.
+func (v *VarInfo) NeedIndexFunctions() bool {
+	return len(v.IndexTypes) != 0
+}
+
+// IndexFunctions returns the functions reading the elements of slices
+// of types added by AddIndexType, the reads out of range are reported
+// by the policy of the package, see ArrayKeysPolicy.
+func (v *VarInfo) IndexFunctions(strictArrayKeys bool) ([]ast.Decl, error) {
+	var arrTypes []string
+	for t := range v.IndexTypes {
+		arrTypes = append(arrTypes, t)
+	}
+	sort.Strings(arrTypes)
+
+	var res []ast.Decl
+	for _, t := range arrTypes {
+		fn, err := indexFunction(v.IndexTypes[t], strictArrayKeys)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fn)
+	}
+
+	return res, nil
+}
+
+// indexFunction returns the function that reads the element of
+// the slice of the type t, like IndexElementTypeint64. The element
+// out of range is reported and the zero value is read instead.
+func indexFunction(t Type, strictArrayKeys bool) (*ast.FuncDecl, error) {
+	arrType, err := t.GoType()
+	if err != nil {
+		return nil, err
+	}
+	elemType, err := t.elemGoType()
+	if err != nil {
+		return nil, err
+	}
+
+	inRange := goast.Binary(
+		goast.Binary(goast.Ident("i"), token.GEQ, goast.Int(0)),
+		token.LAND,
+		goast.Binary(goast.Ident("i"), token.LSS, goast.CallName("int64", goast.CallName("len", goast.Ident("arr")))),
+	)
+
+	params := []*ast.Field{
+		goast.Field("arr", arrType),
+		goast.Field("i", goast.Ident("int64")),
+		goast.Field("pos", goast.Ident("string")),
+	}
+
+	return &ast.FuncDecl{
+		Name: goast.Ident("Index" + utils.TransformType(t.String())),
+		Type: goast.FuncType(params, []*ast.Field{goast.Field("", elemType)}),
+		Body: goast.Block(
+			&ast.IfStmt{
+				Cond: inRange,
+				Body: goast.Block(goast.Return(goast.Index(goast.Ident("arr"), goast.Ident("i")))),
+			},
+			goast.ExprStmt(goast.CallName("UndefinedKey", goast.Ident("i"), goast.Ident("pos"), ArrayKeysPolicy(strictArrayKeys))),
+			goast.VarDecl("zero", elemType),
+			goast.Return(goast.Ident("zero")),
+		),
+	}, nil
+}
+
+// ArrayKeysPolicy returns the runtime constant of the policy for reads
+// of undefined array keys, which is passed to the runtime functions
+// reading the elements.
+func ArrayKeysPolicy(strict bool) ast.Expr {
+	if strict {
+		return goast.Ident("Strict")
+	}
+	return goast.Ident("Lenient")
+}
@@ -2,6 +2,10 @@ package types
 
 import (
 	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/i582/php2go/src/goast"
 )
 
 const (
@@ -14,6 +18,12 @@ const (
 	Null
 
 	Arr
+	Generator
+
+	// Mixed is the type of values whose type is not inferred, like
+	// the arrays nested by the recursive calls. They are stored in
+	// Var like the values of union types.
+	Mixed
 
 	Lazy
 )
@@ -23,12 +33,22 @@ type Base uint8
 const (
 	None LazyType = iota
 	FunctionCall
+	ConstantFetch
+	GeneratorSend
+	GeneratorReturn
+	FunctionParam
 )
 
 type LazyType uint8
 
 type LazyTypeFields struct {
 	FunctionName string
+
+	// ConstantNames holds the fully qualified names
+	// that are tried in order to resolve the constant.
+	ConstantNames []string
+
+	ParamIndex int
 }
 
 type Type struct {
@@ -59,6 +79,74 @@ func NewLazyFunctionCallType(fn string) Type {
 	}
 }
 
+func NewLazyConstantType(names []string) Type {
+	return Type{
+		BaseType: Lazy,
+		LazyType: ConstantFetch,
+
+		LazyTypeFields: LazyTypeFields{
+			ConstantNames: names,
+		},
+	}
+}
+
+// NewLazyGeneratorSendType returns the type of values
+// sent into the generator returned by the function fn.
+func NewLazyGeneratorSendType(fn string) Type {
+	return Type{
+		BaseType: Lazy,
+		LazyType: GeneratorSend,
+
+		LazyTypeFields: LazyTypeFields{
+			FunctionName: fn,
+		},
+	}
+}
+
+// NewLazyGeneratorReturnType returns the type of value
+// returned by the generator function fn.
+func NewLazyGeneratorReturnType(fn string) Type {
+	return Type{
+		BaseType: Lazy,
+		LazyType: GeneratorReturn,
+
+		LazyTypeFields: LazyTypeFields{
+			FunctionName: fn,
+		},
+	}
+}
+
+// NewLazyParamType returns the type of the parameter i
+// of the function fn collected from the call sites.
+func NewLazyParamType(fn string, i int) Type {
+	return Type{
+		BaseType: Lazy,
+		LazyType: FunctionParam,
+
+		LazyTypeFields: LazyTypeFields{
+			FunctionName: fn,
+			ParamIndex:   i,
+		},
+	}
+}
+
+// NewGeneratorType returns the type of generator returned
+// by the function fn, keys and values are those yielded.
+func NewGeneratorType(fn string, keyTypes Types, elemTypes Types) Type {
+	return Type{
+		BaseType: Generator,
+
+		LazyTypeFields: LazyTypeFields{
+			FunctionName: fn,
+		},
+
+		Array: Array{
+			KeysTypes: keyTypes,
+			ElemTypes: elemTypes,
+		},
+	}
+}
+
 func (t Type) String() string {
 	var str string
 
@@ -79,38 +167,96 @@ func (t Type) String() string {
 		str += "null"
 
 	case Arr:
-		arr := t.Array
-		keys := arr.KeysTypes.String()
-		elems := arr.ElemTypes.String()
+		elems := t.elemTypeName()
 
+		// Arrays with keys other than 0..n-1 need the
+		// order of keys to be kept, so they are not Go maps.
 		if t.IsAssociative {
-			str += fmt.Sprintf("map[%s]%s", keys, elems)
+			str += "*OrderedArray"
 		} else {
 			str += fmt.Sprintf("[]%s", elems)
 		}
 
 		break
 
+	case Generator:
+		str += "*Generator"
+
+	case Mixed:
+		str += "mixed"
+
 	case Lazy:
 		str += "lazy"
 
 		switch t.LazyType {
 		case FunctionCall:
 			str += "<FunctionCall: " + t.FunctionName + ">"
+		case ConstantFetch:
+			str += "<ConstantFetch: " + strings.Join(t.ConstantNames, ", ") + ">"
+		case GeneratorSend:
+			str += "<GeneratorSend: " + t.FunctionName + ">"
+		case GeneratorReturn:
+			str += "<GeneratorReturn: " + t.FunctionName + ">"
+		case FunctionParam:
+			str += fmt.Sprintf("<FunctionParam: %s, %d>", t.FunctionName, t.ParamIndex)
 		}
 	}
 
 	return str
 }
 
+// GoType returns the Go type of the values of the type. The types
+// that are not inferred and void, which has no values, have no Go
+// type, for them the error is returned.
+func (t Type) GoType() (ast.Expr, error) {
+	switch t.BaseType {
+	case Integer, Float, String, Bool:
+		return goast.Ident(t.String()), nil
+	case Arr:
+		if t.IsAssociative {
+			return goast.Pointer(goast.Ident("OrderedArray")), nil
+		}
+		elem, err := t.elemGoType()
+		if err != nil {
+			return nil, err
+		}
+		return goast.SliceType(elem), nil
+	case Generator:
+		return goast.Pointer(goast.Ident("Generator")), nil
+	case Null, Mixed:
+		// Go has no type of the null value, so it is
+		// stored in Var like the values of any type.
+		return goast.Ident("Var"), nil
+	case Void:
+		return nil, fmt.Errorf("the result of the function that returns nothing is used")
+	}
+	return nil, fmt.Errorf("the type %s is not inferred", t)
+}
+
 func (t Type) Is(tp Base) bool {
 	return t.BaseType == tp
 }
 
+// IsScalar reports whether the type is int, float, string or bool,
+// whose values are stored unboxed in Var.
+func (t Type) IsScalar() bool {
+	switch t.BaseType {
+	case Integer, Float, String, Bool:
+		return true
+	}
+	return false
+}
+
 func (t Type) IsLazy() bool {
 	return t.BaseType == Lazy
 }
 
+// IsOrderedArray reports whether the type is the array with keys,
+// which is represented by OrderedArray at runtime.
+func (t Type) IsOrderedArray() bool {
+	return t.BaseType == Arr && t.IsAssociative
+}
+
 func (t Type) ElementTypes() (Types, bool) {
 	if t.BaseType != Arr {
 		return Types{}, false
@@ -119,12 +265,35 @@ func (t Type) ElementTypes() (Types, bool) {
 	return t.ElemTypes, true
 }
 
+// elemTypeName returns the Go type of elements of the slice.
+func (t Type) elemTypeName() string {
+	// Nothing is ever written to the array.
+	if t.ElemTypes.Len() == 0 {
+		return "interface{}"
+	}
+	return t.ElemTypes.GenerateName()
+}
+
+// elemGoType returns the Go type of elements of the slice.
+func (t Type) elemGoType() (ast.Expr, error) {
+	if t.ElemTypes.Len() == 0 {
+		return goast.InterfaceType(), nil
+	}
+	return t.ElemTypes.GoType()
+}
+
 type Array struct {
 	KeysTypes Types
 	ElemTypes Types
 	ArrayDim  uint8
 
 	IsAssociative bool
+
+	// Shape holds the types of elements by the keys known at
+	// translation time, it is set only if all keys are known.
+	// The map is shared between copies of the type and must
+	// not be modified, a new one is created instead.
+	Shape map[string]Types
 }
 
 func NewAssociativeArrayType(keyTypes Types, elemTypes Types, dim uint8) Type {
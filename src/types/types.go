@@ -2,8 +2,11 @@ package types
 
 import (
 	"fmt"
+	"go/ast"
 	"sort"
 	"strings"
+
+	"github.com/i582/php2go/src/goast"
 )
 
 type Types struct {
@@ -26,9 +29,11 @@ func NewBaseTypes(tp ...Base) Types {
 	return types
 }
 
+// Resolved reports whether the types, the element types
+// of arrays included, have no lazy types.
 func (ts *Types) Resolved() bool {
 	for _, t := range ts.Types {
-		if t.IsLazy() {
+		if t.IsLazy() || t.Is(Arr) && !t.ElemTypes.Resolved() {
 			return false
 		}
 	}
@@ -36,7 +41,7 @@ func (ts *Types) Resolved() bool {
 	return true
 }
 
-func (ts *Types) ElementType() Types {
+func (ts Types) ElementType() Types {
 	var res Types
 
 	for _, t := range ts.Types {
@@ -46,7 +51,7 @@ func (ts *Types) ElementType() Types {
 	return res
 }
 
-func (ts *Types) KeyType() Types {
+func (ts Types) KeyType() Types {
 	var res Types
 
 	for _, t := range ts.Types {
@@ -56,12 +61,20 @@ func (ts *Types) KeyType() Types {
 	return res
 }
 
+// SingleType reports whether the values are of one type, the values
+// of the mixed type are stored in Var like the values of unions.
 func (ts *Types) SingleType() bool {
-	return ts.Len() == 1
+	return ts.Len() == 1 && !ts.Types[0].Is(Mixed)
 }
 
+// Add adds the type t. The mixed type holds the values of all types,
+// so nothing is added to it, and it replaces the other types.
 func (ts *Types) Add(t Type) {
-	if ts.Contains(t) {
+	if ts.Is(Mixed) || ts.Contains(t) {
+		return
+	}
+	if t.Is(Mixed) {
+		ts.Types = []Type{t}
 		return
 	}
 
@@ -74,6 +87,14 @@ func (ts *Types) AddArrayType(keyTypes Types, elemTypes Types, dim uint8) {
 
 func (ts *Types) Contains(t Type) bool {
 	for _, tp := range ts.Types {
+		// The lazy types are the same only if they
+		// are resolved from the same source.
+		if t.IsLazy() {
+			if tp.IsLazy() && tp.String() == t.String() {
+				return true
+			}
+			continue
+		}
 		if tp.Is(t.BaseType) {
 			return true
 		}
@@ -112,6 +133,42 @@ func (ts *Types) Merge(ts2 Types) {
 	}
 }
 
+// MergeDeep is like Merge, but the array types are united into one
+// array type with the merged key and element types.
+func (ts *Types) MergeDeep(ts2 Types) {
+	for _, t := range ts2.Types {
+		i := ts.index(t.BaseType)
+		if i == -1 || t.BaseType != Arr {
+			ts.Add(t)
+			continue
+		}
+
+		// The types can be shared with other arrays,
+		// so they are copied before the modification.
+		ts.Types = append([]Type(nil), ts.Types...)
+		cur := &ts.Types[i]
+		cur.KeysTypes = cur.KeysTypes.Clone()
+		cur.KeysTypes.MergeDeep(t.KeysTypes)
+		cur.ElemTypes = cur.ElemTypes.Clone()
+		cur.ElemTypes.MergeDeep(t.ElemTypes)
+		cur.IsAssociative = cur.IsAssociative || t.IsAssociative
+		cur.Shape = nil
+	}
+}
+
+func (ts Types) Clone() Types {
+	return Types{Types: append([]Type(nil), ts.Types...)}
+}
+
+func (ts *Types) index(t Base) int {
+	for i, tp := range ts.Types {
+		if tp.Is(t) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (ts Types) Is(t Base) bool {
 	if ts.Len() == 0 {
 		return false
@@ -124,6 +181,10 @@ func (ts Types) Is(t Base) bool {
 	return false
 }
 
+func (ts Types) IsOrderedArray() bool {
+	return ts.Len() == 1 && ts.Types[0].IsOrderedArray()
+}
+
 func (ts *Types) Len() int {
 	return len(ts.Types)
 }
@@ -153,9 +214,55 @@ func (ts Types) GenerateName() string {
 	if ts.SingleType() {
 		return ts.String()
 	}
+	if ts.IsNullable() {
+		return "Null" + ts.NonNullType().String()
+	}
 	return "Var"
 }
 
+// GoType returns the Go type of the values of the types, which is
+// named GenerateName, or nil for the empty types. The error is returned
+// if one of the types has no Go type, see Type.GoType.
+func (ts Types) GoType() (ast.Expr, error) {
+	if ts.Len() == 0 {
+		return nil, nil
+	}
+	if ts.SingleType() {
+		return ts.Types[0].GoType()
+	}
+
+	for _, t := range ts.Types {
+		if _, err := t.GoType(); err != nil {
+			return nil, err
+		}
+	}
+	return goast.Ident(ts.GenerateName()), nil
+}
+
+// IsNullable reports whether the type is the scalar type or null,
+// such values are represented by the Null<T> struct instead of Var.
+func (ts Types) IsNullable() bool {
+	if ts.Len() != 2 || ts.index(Null) == -1 {
+		return false
+	}
+
+	switch ts.NonNullType().BaseType {
+	case Integer, Float, String, Bool:
+		return true
+	}
+	return false
+}
+
+// NonNullType returns the first type other than null.
+func (ts Types) NonNullType() Type {
+	for _, t := range ts.Types {
+		if !t.Is(Null) {
+			return t
+		}
+	}
+	return Type{}
+}
+
 func (ts Types) GenerateCreation(ts2 Types) (string, bool) {
 	if ts.Equal(ts2) {
 		return "", false
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"strings"
+	"unicode"
 
 	"github.com/i582/php2go/src/php/node"
 	"github.com/i582/php2go/src/php/node/name"
@@ -15,6 +16,64 @@ func NamePartsToString(parts []node.Node) string {
 	return strings.Join(s, `\`)
 }
 
+// StringLiteralValue returns the value of a simple single
+// or double quoted string literal with escapes resolved.
+func StringLiteralValue(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+
+	quote := s[0]
+	s = s[1 : len(s)-1]
+
+	if quote == '\'' {
+		s = strings.ReplaceAll(s, `\'`, `'`)
+		return strings.ReplaceAll(s, `\\`, `\`)
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'v':
+			b.WriteByte('\v')
+		case 'e':
+			b.WriteByte(0x1b)
+		case 'f':
+			b.WriteByte('\f')
+		case '\\', '$', '"':
+			b.WriteByte(s[i])
+		default:
+			// Unknown escape sequences are kept as is.
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// NamespaceName returns the name of the namespace by its name node,
+// the global namespace has an empty name.
+func NamespaceName(n node.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	return NamePartsToString(n.(*name.Name).Parts)
+}
+
 func FirstLetterUpperCase(s string) string {
 	return strings.ToUpper(s[0:1]) + s[1:]
 }
@@ -22,7 +81,10 @@ func FirstLetterUpperCase(s string) string {
 func TransformType(t string) string {
 	isMap := strings.HasPrefix(t, "map")
 
+	t = strings.ReplaceAll(t, "*", "")
+
 	t = strings.ReplaceAll(t, "[]", "ElementType")
+	t = strings.ReplaceAll(t, "interface{}", "Interface")
 
 	if isMap {
 		t = strings.Replace(t, "[", "WithKey", 1)
@@ -32,15 +94,63 @@ func TransformType(t string) string {
 	return t
 }
 
-func WithTypeCast(t string, need bool, w func(string), f func()) {
-	if need {
-		w(t)
-		w("(")
-	}
+var goReservedNames = map[string]struct{}{
+	"break": {}, "case": {}, "chan": {}, "const": {}, "continue": {},
+	"default": {}, "defer": {}, "else": {}, "fallthrough": {}, "for": {},
+	"func": {}, "go": {}, "goto": {}, "if": {}, "import": {},
+	"interface": {}, "map": {}, "package": {}, "range": {}, "return": {},
+	"select": {}, "struct": {}, "switch": {}, "type": {}, "var": {},
 
-	f()
+	"true": {}, "false": {}, "nil": {}, "iota": {}, "main": {}, "init": {},
+	"bool": {}, "string": {}, "int": {}, "int64": {}, "float64": {},
+	"len": {}, "cap": {}, "append": {}, "make": {}, "new": {}, "panic": {},
+}
 
-	if need {
-		w(")")
+// runtimeNames holds the names exported by the runtime, which is
+// imported with the dot or inlined into the package, so the PHP
+// names equal to them are changed like the reserved ones.
+var runtimeNames = map[string]struct{}{
+	"Add": {}, "ArrayKey": {}, "ArrayKeys": {}, "Compare": {}, "CompareType": {},
+	"Concat": {}, "ConstantGenerator": {}, "Constantarray": {}, "Constantbool": {},
+	"Constantfloat64": {}, "Constantint64": {}, "Constantnull": {}, "Constantstring": {},
+	"CopyArray": {}, "Div": {}, "Empty": {}, "Equal": {}, "Fetch": {}, "Generator": {},
+	"Greater": {}, "GreaterEqual": {}, "Identical": {}, "Isarray": {}, "IsarraySimple": {},
+	"Isbool": {}, "IsboolSimple": {}, "Isfloat64": {}, "Isfloat64Simple": {},
+	"Isint64": {}, "Isint64Simple": {}, "Isnull": {}, "IsnullSimple": {}, "Isset": {},
+	"Isstring": {}, "IsstringSimple": {}, "Lenient": {}, "Mul": {}, "NewGenerator": {},
+	"NewNullbool": {}, "NewNullboolFromInterface": {}, "NewNullfloat64": {},
+	"NewNullfloat64FromInterface": {}, "NewNullint64": {}, "NewNullint64FromInterface": {},
+	"NewNullstring": {}, "NewNullstringFromInterface": {}, "NewOrderedArray": {},
+	"NewVar": {}, "NewVarFromInterface": {}, "NewVarbool": {}, "NewVarfloat64": {},
+	"NewVarint64": {}, "NewVarstring": {}, "NotEqual": {}, "Nullbool": {},
+	"Nullfloat64": {}, "Nullint64": {}, "Nullstring": {}, "OrderedArray": {}, "Pair": {},
+	"Smaller": {}, "SmallerEqual": {}, "Strict": {}, "Sub": {}, "UndefinedKey": {},
+	"Unpack": {}, "ValueType": {}, "Var": {}, "Vars": {}, "Yielder": {},
+}
+
+// GoIdentifier turns a PHP name, possibly namespaced,
+// into a valid Go identifier.
+func GoIdentifier(name string) string {
+	name = strings.TrimPrefix(name, `\`)
+
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+			b.WriteRune(r)
+		case unicode.IsDigit(r) && i != 0:
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
 	}
+
+	res := b.String()
+	if _, ok := goReservedNames[res]; ok {
+		res += "_"
+	} else if _, ok := runtimeNames[res]; ok {
+		res += "_"
+	}
+
+	return res
 }
@@ -2,7 +2,9 @@ package variable
 
 import (
 	"fmt"
+	"go/ast"
 
+	"github.com/i582/php2go/src/goast"
 	"github.com/i582/php2go/src/types"
 	"github.com/i582/php2go/src/utils"
 )
@@ -14,12 +16,24 @@ type Variable struct {
 	WasInitialize bool
 	CurrentType   types.Types
 	FromIfElse    bool
+
+	// Local is set if the variable of a union type is shadowed by
+	// the local variable of its narrowed type, like in the branch
+	// guarded by is_int().
+	Local bool
 }
 
 func NewVariable(name string, typ types.Types) *Variable {
 	return &Variable{Name: name, Type: typ}
 }
 
+// GoName returns the name of the variable in the generated code, the
+// names reserved in Go, like map and len, are changed like the names
+// of functions.
+func (v Variable) GoName() string {
+	return utils.GoIdentifier(v.Name)
+}
+
 func (v Variable) String() string {
 	return fmt.Sprintf("$%s: %v", v.Name, v.Type)
 }
@@ -33,33 +47,42 @@ func (v *Variable) AddType(ts types.Types, inBranching bool) {
 	}
 }
 
-func (v *Variable) GenerateDefinition() string {
-	name := v.Type.GenerateName()
-	return fmt.Sprintf("%s := New%s()\n", v.Name, name)
+// GenerateDefinition returns the declaration of the variable
+// of union type, which holds null until it is assigned.
+func (v *Variable) GenerateDefinition() ast.Stmt {
+	return goast.Define(goast.Ident(v.GoName()), goast.CallName("New"+v.Type.GenerateName()))
 }
 
-func (v *Variable) GenerateAccess(inAssignLvalue, inAssignRvalue, inPrint, inCompare, inBoolean, inIsT bool) string {
-	var field string
+// GenerateAccess returns the access to the variable whose type at this
+// point is current. The variable of union type with the single current
+// type is read with the getter of the type, and is written by the setter
+// method, which is returned for the assignment target and is called with
+// the assigned value.
+func (v *Variable) GenerateAccess(current types.Types, inAssignLvalue, inAssignRvalue, inPrint, inCompare, inBoolean, inIsT bool) (ast.Expr, error) {
+	name := goast.Ident(v.GoName())
+	if v.Local {
+		return name, nil
+	}
+
 	varHasUnionType := !v.Type.SingleType()
-	currentTypeIsSingle := v.CurrentType.SingleType()
+	currentTypeIsSingle := current.SingleType()
 
 	if varHasUnionType && !currentTypeIsSingle {
 		if inPrint {
-			return fmt.Sprintf("%s.String()", v.Name)
+			return goast.Method(name, "String"), nil
 		}
 
 		if inBoolean {
-			return fmt.Sprintf("%s.Bool()", v.Name)
+			return goast.Method(name, "Bool"), nil
 		}
 	}
 
 	if varHasUnionType && currentTypeIsSingle {
 		if inAssignLvalue {
-			field = ".Set" + utils.TransformType(v.CurrentType.String()) + "("
-		} else {
-			field = ".Get" + utils.TransformType(v.CurrentType.String()) + "()"
+			return goast.Sel(name, current.Setter()), nil
 		}
+		return current.Getter(name)
 	}
 
-	return fmt.Sprintf("%s%s", v.Name, field)
+	return name, nil
 }
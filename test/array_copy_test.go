@@ -0,0 +1,92 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestArrayCopyOnAssign(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$a = [1, 2];
+	$b = $a;
+	$b[] = 3;
+	$c = $a;
+	echo $c[0];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	a := []int64{int64(1), int64(2)}
+	b := append([]int64(nil), a...)
+	b = append(b, int64(3))
+	c := a
+	fmt.Print(IndexElementTypeint64(c, int64(0), "test.php on line 7"))
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+func TestArrayCopyOnCall(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function AddOne($xs) {
+	$xs[] = 1;
+	return $xs;
+}
+
+function Foo() {
+	$m = [[1, 2], [3]];
+	$a = [1, 2];
+	$c = AddOne($a);
+	$n = $m;
+	$n[0][0] = 9;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func AddOne(xs []int64) []int64 {
+	xs = append(xs, int64(1))
+	return xs
+}
+
+func Foo() {
+	m := [][]int64{[]int64{int64(1), int64(2)}, []int64{int64(3)}}
+	a := []int64{int64(1), int64(2)}
+	c := AddOne(append([]int64(nil), a...))
+	n := CopyArray(m).([][]int64)
+	if int64(len(n)) == int64(0) {
+		n = append(n, nil)
+	}
+	n[int64(0)][int64(0)] = int64(9)
+}
+`))
+
+	s.RunTest()
+}
@@ -0,0 +1,28 @@
+<?php
+function Accumulate(int $n) {
+	$x = 0;
+	for ($i = 0; $i < $n; $i++) {
+		if ($i > 1000) {
+			$x = $x + 0.5;
+		} else {
+			$x = $x + $i;
+		}
+		$x = $x * 2 - $x - 1;
+	}
+	return $x;
+}
+
+function CountAbove(int $n, int $limit) {
+	$count = 0;
+	$v = 1;
+	for ($i = 0; $i < $n; $i++) {
+		if ($i == 10) {
+			$v = "7";
+		}
+		if ($v > $limit) {
+			$count = $count + 1;
+		}
+		$v = $v + 1;
+	}
+	return $count;
+}
@@ -0,0 +1,190 @@
+package bench
+
+//go:generate go run ../.. -i bench.php -o bench.go
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/i582/php2go/runtime"
+	"github.com/i582/php2go/src/ctx"
+	"github.com/i582/php2go/src/generator"
+	"github.com/i582/php2go/src/lower"
+	"github.com/i582/php2go/src/php/node"
+	"github.com/i582/php2go/src/php/php7"
+	"github.com/i582/php2go/src/root"
+)
+
+// TestGenerated checks that the benchmarked code is translated
+// from bench.php by the current version, run go generate if not.
+func TestGenerated(t *testing.T) {
+	src, err := ioutil.ReadFile("bench.php")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := php7.NewParser(src, "7.4")
+	parser.Parse()
+
+	session := ctx.NewSession()
+	rw := root.RootWalker{Session: session}
+	rootNode := parser.GetRootNode()
+	rootNode.Walk(&rw)
+
+	main := bytes.NewBuffer(nil)
+	core := bytes.NewBuffer(nil)
+	gen := generator.NewGenerator(main, core, "bench.php")
+	file, err := lower.File(session, rootNode.(*node.Root), "bench.php", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.Generate(file)
+	if err := gen.Final(); err != nil {
+		t.Fatal(err)
+	}
+
+	have, err := ioutil.ReadFile("bench.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(string(have), main.String()) {
+		t.Errorf("bench.go is outdated, run go generate:\n%s", cmp.Diff(string(have), main.String()))
+	}
+	if core.Len() != 0 {
+		t.Errorf("the runtime is written to the core file, but it is imported:\n%s", core.String())
+	}
+}
+
+const iterations = 10000
+
+func BenchmarkAccumulate(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Accumulate(iterations)
+	}
+}
+
+func BenchmarkAccumulateBoxed(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		accumulateBoxed(iterations)
+	}
+}
+
+func BenchmarkCountAbove(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		CountAbove(iterations, 100)
+	}
+}
+
+func BenchmarkCountAboveBoxed(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		countAboveBoxed(iterations, 100)
+	}
+}
+
+// boxedVar is the former representation of Var, which stores the value
+// as interface{}. The functions below follow the former runtime, so the
+// benchmarks compare the code generated before and after the change.
+type boxedVar struct {
+	Val  interface{}
+	Type ValueType
+}
+
+func newBoxed(val interface{}) boxedVar {
+	switch val.(type) {
+	case int64:
+		return boxedVar{Val: val, Type: Constantint64}
+	case float64:
+		return boxedVar{Val: val, Type: Constantfloat64}
+	case string:
+		return boxedVar{Val: val, Type: Constantstring}
+	}
+	return boxedVar{}
+}
+
+func boxedToNumber(v boxedVar, a, b boxedVar, op string) boxedVar {
+	switch v.Type {
+	case Constantint64, Constantfloat64:
+		return v
+	case Constantstring:
+		// The benchmarks use only numeric strings.
+		if n, err := strconv.ParseInt(v.Val.(string), 10, 64); err == nil {
+			return newBoxed(n)
+		}
+		f, _ := strconv.ParseFloat(v.Val.(string), 64)
+		return newBoxed(f)
+	}
+	panic("Unsupported operand types for " + op)
+}
+
+func (v boxedVar) float() float64 {
+	if v.Type == Constantint64 {
+		return float64(v.Val.(int64))
+	}
+	return v.Val.(float64)
+}
+
+func boxedAdd(a, b boxedVar) boxedVar {
+	x, y := boxedToNumber(a, a, b, "+"), boxedToNumber(b, a, b, "+")
+	if x.Type == Constantint64 && y.Type == Constantint64 {
+		return newBoxed(x.Val.(int64) + y.Val.(int64))
+	}
+	return newBoxed(x.float() + y.float())
+}
+
+func boxedSub(a, b boxedVar) boxedVar {
+	x, y := boxedToNumber(a, a, b, "-"), boxedToNumber(b, a, b, "-")
+	if x.Type == Constantint64 && y.Type == Constantint64 {
+		return newBoxed(x.Val.(int64) - y.Val.(int64))
+	}
+	return newBoxed(x.float() - y.float())
+}
+
+func boxedMul(a, b boxedVar) boxedVar {
+	x, y := boxedToNumber(a, a, b, "*"), boxedToNumber(b, a, b, "*")
+	if x.Type == Constantint64 && y.Type == Constantint64 {
+		return newBoxed(x.Val.(int64) * y.Val.(int64))
+	}
+	return newBoxed(x.float() * y.float())
+}
+
+func (v boxedVar) compareWithint64Greater(val int64) bool {
+	switch v.Type {
+	case Constantint64:
+		return v.Val.(int64) > val
+	case Constantstring:
+		return boxedToNumber(v, v, v, ">").float() > float64(val)
+	}
+	return false
+}
+
+func accumulateBoxed(n int64) boxedVar {
+	x := newBoxed(int64(0))
+	for i := int64(0); i < n; i++ {
+		if i > int64(1000) {
+			x = boxedAdd(x, newBoxed(0.5))
+		} else {
+			x = boxedAdd(x, newBoxed(i))
+		}
+		x = boxedSub(boxedSub(boxedMul(x, newBoxed(int64(2))), x), newBoxed(int64(1)))
+	}
+	return x
+}
+
+func countAboveBoxed(n int64, limit int64) int64 {
+	count := int64(0)
+	v := newBoxed(int64(1))
+	for i := int64(0); i < n; i++ {
+		if i == int64(10) {
+			v = newBoxed("7")
+		}
+		if v.compareWithint64Greater(limit) {
+			count = count + int64(1)
+		}
+		v = boxedAdd(v, newBoxed(int64(1)))
+	}
+	return count
+}
@@ -0,0 +1,245 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestConstants(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+const LIMIT = 10;
+const PRIMES = [2, 3, 5];
+define('TITLE', "Report");
+
+function Foo() {
+	$a = LIMIT + 1;
+	echo TITLE;
+	echo PRIMES[0];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+const LIMIT = int64(10)
+
+var PRIMES = []int64{int64(2), int64(3), int64(5)}
+
+const TITLE = "Report"
+
+func Foo() {
+	a := LIMIT + int64(1)
+	fmt.Print(TITLE)
+	fmt.Print(IndexElementTypeint64(PRIMES, int64(0), "test.php on line 9"))
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+func TestNamespacedConstants(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+namespace App\Config;
+
+const DEPTH = 3;
+define('App\Config\WIDTH', -1);
+define('HEIGHT', 2);
+
+function Foo() {
+	echo DEPTH;
+	echo WIDTH;
+	echo HEIGHT;
+	echo \App\Config\DEPTH;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+const App_Config_DEPTH = int64(3)
+
+const App_Config_WIDTH = -int64(1)
+
+const HEIGHT = int64(2)
+
+func Foo() {
+	fmt.Print(App_Config_DEPTH)
+	fmt.Print(App_Config_WIDTH)
+	fmt.Print(HEIGHT)
+	fmt.Print(App_Config_DEPTH)
+}
+`))
+
+	s.RunTest()
+}
+
+func TestRuntimeConstants(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.Executable = true
+	s.AddFile([]byte(`<?php
+$start = 5;
+define('START', $start);
+
+if (defined('START')) {
+	echo START;
+}
+echo defined('UNKNOWN');
+`))
+
+	s.AddExpected([]byte(`
+package main
+
+import (
+	"fmt"
+)
+
+var START int64
+
+var STARTDefined bool
+
+func main() {
+	start := int64(5)
+	START = start
+	STARTDefined = true
+	if STARTDefined {
+		fmt.Print(START)
+	}
+	fmt.Print(false)
+}
+`))
+
+	s.RunTest()
+}
+
+// TestConditionalDefine checks that the constant defined in the function
+// with the value known at translation time is defined at runtime, since
+// the define() may not run.
+func TestConditionalDefine(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.Executable = true
+	s.AddFile([]byte(`<?php
+function setup($d) {
+	if ($d) {
+		define('X', 3);
+	}
+}
+setup(false);
+if (defined('X')) {
+	echo "defined\n";
+} else {
+	echo "undefined\n";
+}
+`))
+
+	s.AddExpected([]byte(`
+package main
+
+import (
+	"fmt"
+)
+
+var X int64
+
+var XDefined bool
+
+func setup(d bool) {
+	if d {
+		X = int64(3)
+		XDefined = true
+	}
+}
+
+func main() {
+	setup(false)
+	if XDefined {
+		fmt.Print("defined\n")
+	} else {
+		fmt.Print("undefined\n")
+	}
+}
+`))
+
+	s.RunTest()
+}
+
+// TestLateDefine checks that the constants used before the top-level
+// define(), directly or by the called function, are defined at runtime,
+// and the constant used after it is the Go constant.
+func TestLateDefine(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.Executable = true
+	s.AddFile([]byte(`<?php
+function check() {
+	return defined('Y');
+}
+if (defined('X')) {
+	echo "x";
+}
+if (check()) {
+	echo "y";
+}
+define('X', 1);
+define('Y', 2);
+define('Z', 3);
+echo X + Y + Z;
+`))
+
+	s.AddExpected([]byte(`
+package main
+
+import (
+	"fmt"
+)
+
+var X int64
+
+var XDefined bool
+
+var Y int64
+
+var YDefined bool
+
+const Z = int64(3)
+
+func check() bool {
+	return YDefined
+}
+
+func main() {
+	if XDefined {
+		fmt.Print("x")
+	}
+	if check() {
+		fmt.Print("y")
+	}
+	X = int64(1)
+	XDefined = true
+	Y = int64(2)
+	YDefined = true
+	fmt.Print(X + Y + Z)
+}
+`))
+
+	s.RunTest()
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestEmptyArrayAppend(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$list = [];
+	for ($i = 0; $i < 3; $i++) {
+		$list[] = $i * 2;
+	}
+	$names = [];
+	array_push($names, "a", "b");
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+func Foo() {
+	list := []int64{}
+	for i := int64(0); i < int64(3); i++ {
+		list = append(list, i*int64(2))
+	}
+	names := []string{}
+	names = append(names, "a")
+	names = append(names, "b")
+}
+`))
+
+	s.RunTest()
+}
+
+func TestEmptyArrayKeyedWrite(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$m = [];
+	$m["x"] = 1.5;
+	$groups = [];
+	$groups["odd"][] = 1;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	m := NewOrderedArray()
+	m.Set("x", 1.5)
+	groups := NewOrderedArray()
+	_tmp1, _ := groups.Get("odd").([]int64)
+	groups.Set("odd", append(_tmp1, int64(1)))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestEmptyArrayMixedAppend(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$mixed = [];
+	$mixed[] = 1;
+	$mixed[] = "two";
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	mixed := []Var{}
+	mixed = append(mixed, NewVarFromInterface(int64(1)))
+	mixed = append(mixed, NewVarFromInterface("two"))
+}
+`))
+
+	s.RunTest()
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestExecutableTopLevelCode(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.Executable = true
+	s.AddFile([]byte(`<?php
+function Foo() {
+	echo "Foo";
+}
+
+$a = 10;
+echo $a;
+Foo();
+`))
+
+	s.AddExpected([]byte(`
+package main
+
+import (
+	"fmt"
+)
+
+func Foo() {
+	fmt.Print("Foo")
+}
+
+func main() {
+	a := int64(10)
+	fmt.Print(a)
+	Foo()
+}
+`))
+
+	s.RunTest()
+}
+
+func TestExecutableReservedFunctionNames(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.Executable = true
+	s.AddFile([]byte(`<?php
+function main() {
+	echo "main";
+}
+
+function init() {
+	echo "init";
+}
+
+init();
+main();
+`))
+
+	s.AddExpected([]byte(`
+package main
+
+import (
+	"fmt"
+)
+
+func main_() {
+	fmt.Print("main")
+}
+
+func init_() {
+	fmt.Print("init")
+}
+
+func main() {
+	init_()
+	main_()
+}
+`))
+
+	s.RunTest()
+}
+
+// TestReservedVariableNames checks that the variables and
+// the parameters named like Go keywords and builtins are renamed.
+func TestReservedVariableNames(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.Executable = true
+	s.AddFile([]byte(`<?php
+function Count($map, $type) {
+	$len = $map + $type;
+	foreach ([1, 2] as $range) {
+		$len = $len + $range;
+	}
+	return $len;
+}
+
+$map = ['a' => 1];
+$map['b'] = 2;
+echo Count(1, 2);
+`))
+
+	s.AddExpected([]byte(`
+package main
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Count(map_ int64, type_ int64) int64 {
+	len_ := map_ + type_
+	for _, range_ := range []int64{int64(1), int64(2)} {
+		len_ = len_ + range_
+	}
+	return len_
+}
+
+func main() {
+	map_ := NewOrderedArray(Pair{"a", int64(1)})
+	map_.Set("b", int64(2))
+	fmt.Print(Count(int64(1), int64(2)))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestLibraryDropsTopLevelCode(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	echo "Foo";
+}
+
+$a = 10;
+echo $a;
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Foo() {
+	fmt.Print("Foo")
+}
+`))
+
+	s.RunTest()
+}
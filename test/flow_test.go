@@ -0,0 +1,184 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestFlowLoop(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$x = 1;
+	$i = 0;
+	while ($i < 3) {
+		$x = $x . "a";
+		$i++;
+	}
+	echo $x;
+
+	$y = 1;
+	foreach (["a", "b"] as $s) {
+		$z = $y;
+		$y = $s;
+		echo $z;
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	x := NewVar()
+	x.Setint64(int64(1))
+	i := int64(0)
+	for i < int64(3) {
+		x.Setstring(Concat(x, NewVarstring("a")))
+		i++
+	}
+	fmt.Print(x.String())
+	y := NewVar()
+	y.Setint64(int64(1))
+	for _, s := range []string{"a", "b"} {
+		z := y
+		y.Setstring(s)
+		fmt.Print(z.String())
+	}
+}
+`))
+
+	s.RunTest()
+}
+
+func TestFlowJumps(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$x = 0;
+	$i = 0;
+	while (true) {
+		$i++;
+		if ($i > 5) {
+			break;
+		}
+		if ($i == 2) {
+			continue;
+		}
+		$x = "s";
+	}
+	echo $x;
+
+	for ($a = 0; $a < 3; $a++) {
+		foreach ([1, 2] as $b) {
+			if ($b == 2) {
+				continue 2;
+			}
+			echo $b;
+		}
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	x := NewVar()
+	x.Setint64(int64(0))
+	i := int64(0)
+	for true {
+		i++
+		if i > int64(5) {
+			break
+		}
+		if i == int64(2) {
+			continue
+		}
+		x.Setstring("s")
+	}
+	fmt.Print(x.String())
+_loop1:
+	for a := int64(0); a < int64(3); a++ {
+		for _, b := range []int64{int64(1), int64(2)} {
+			if b == int64(2) {
+				continue _loop1
+			}
+			fmt.Print(b)
+		}
+	}
+}
+`))
+
+	s.RunTest()
+}
+
+func TestFlowElseIf(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function PickKind(int $n) {
+	if ($n == 1) {
+		$v = 1;
+	} elseif ($n == 2) {
+		$v = "two";
+	} else {
+		$v = 3.5;
+	}
+	return $v;
+}
+
+function Describe(int $n) {
+	$v = $n;
+	if ($n > 10) {
+		return $v;
+	}
+	$v = "small";
+	return $v . $v;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func PickKind(n int64) Var {
+	var v Var
+	if n == int64(1) {
+		v.Setint64(int64(1))
+	} else {
+		if n == int64(2) {
+			v.Setstring("two")
+		} else {
+			v.Setfloat64(3.5)
+		}
+	}
+	return v
+}
+
+func Describe(n int64) Var {
+	v := NewVar()
+	v.Setint64(n)
+	if n > int64(10) {
+		return NewVarint64(v.Getint64())
+	}
+	v.Setstring("small")
+	return NewVarstring(v.Getstring() + v.Getstring())
+}
+`))
+
+	s.RunTest()
+}
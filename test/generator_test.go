@@ -0,0 +1,231 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestGenerator(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Numbers() {
+	$i = 0;
+	while ($i < 3) {
+		yield $i;
+		$i++;
+	}
+	return "done";
+}
+
+function Foo() {
+	$gen = Numbers();
+	foreach ($gen as $k => $v) {
+		echo $k + $v;
+	}
+	echo $gen->getReturn();
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Numbers() *Generator {
+	return NewGenerator(func(_gen *Yielder) interface{} {
+		i := int64(0)
+		for i < int64(3) {
+			_gen.Yield(i)
+			i++
+		}
+		return "done"
+	})
+}
+
+func Foo() {
+	gen := Numbers()
+	for _tmp1 := gen; _tmp1.Valid(); _tmp1.Next() {
+		k := _tmp1.Key().(int64)
+		v := _tmp1.Current().(int64)
+		fmt.Print(k + v)
+	}
+	fmt.Print(gen.GetReturn().(string))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestGeneratorDelegation(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Letters() {
+	yield "a" => "x";
+	yield from ["y", "z"];
+}
+
+function Foo() {
+	$l = Letters();
+	echo $l->current();
+	$l->next();
+	echo $l->valid();
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Letters() *Generator {
+	return NewGenerator(func(_gen *Yielder) interface{} {
+		_gen.YieldWithKey("a", "x")
+		for k, v := range []string{"y", "z"} {
+			_gen.YieldElement(int64(k), v)
+		}
+		return nil
+	})
+}
+
+func Foo() {
+	l := Letters()
+	fmt.Print(l.Current().(string))
+	l.Next()
+	fmt.Print(l.Valid())
+}
+`))
+
+	s.RunTest()
+}
+
+// TestGeneratorYieldLoopVariable checks that the yielded variable has
+// the types assigned to it by the later iterations of the loop.
+func TestGeneratorYieldLoopVariable(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Acc() {
+	$total = 0;
+	while (true) {
+		$x = yield $total;
+		$total = $total + $x;
+	}
+}
+
+function Foo() {
+	$a = Acc();
+	$a->current();
+	echo $a->send(7);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Acc() *Generator {
+	return NewGenerator(func(_gen *Yielder) interface{} {
+		total := NewVar()
+		total.Setint64(int64(0))
+		for true {
+			x := NewNullint64FromInterface(_gen.Yield(total))
+			total = Add(total, NewVarFromInterface(x))
+		}
+		return nil
+	})
+}
+
+func Foo() {
+	a := Acc()
+	a.Current()
+	fmt.Print(NewVarFromInterface(a.Send(int64(7))).String())
+}
+`))
+
+	s.RunTest()
+}
+
+// TestGeneratorCloseAndArrayKeys checks that the generators created
+// by foreach are closed when the loop is left, and that the keys of
+// the array delegated to by yield from do not change the next key.
+func TestGeneratorCloseAndArrayKeys(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Early() {
+	yield from [10, 20];
+	yield 30;
+}
+
+function First() {
+	foreach (Early() as $v) {
+		return $v;
+	}
+	return 0;
+}
+
+function Heads($n) {
+	for ($i = 0; $i < $n; $i++) {
+		foreach (Early() as $k => $v) {
+			echo $k, $v;
+			break;
+		}
+	}
+}
+
+Heads(2);
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Early() *Generator {
+	return NewGenerator(func(_gen *Yielder) interface{} {
+		for k, v := range []int64{int64(10), int64(20)} {
+			_gen.YieldElement(int64(k), v)
+		}
+		_gen.Yield(int64(30))
+		return nil
+	})
+}
+
+func First() int64 {
+	_tmp1 := Early()
+	defer _tmp1.Close()
+	for ; _tmp1.Valid(); _tmp1.Next() {
+		v := _tmp1.Current().(int64)
+		return v
+	}
+	return int64(0)
+}
+
+func Heads(n int64) {
+	for i := int64(0); i < n; i++ {
+		_tmp2 := Early()
+		for ; _tmp2.Valid(); _tmp2.Next() {
+			k := _tmp2.Key().(int64)
+			v := _tmp2.Current().(int64)
+			fmt.Print(k, v)
+			break
+		}
+		_tmp2.Close()
+	}
+}
+`))
+
+	s.RunTest()
+}
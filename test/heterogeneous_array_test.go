@@ -0,0 +1,78 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestHeterogeneousArray(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$xs = [1, "two", 3.5];
+	echo $xs[0] + 10;
+	$i = 1;
+	echo $xs[$i];
+	$xs[] = 4;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	xs := []Var{NewVarFromInterface(int64(1)), NewVarFromInterface("two"), NewVarFromInterface(3.5)}
+	fmt.Print(IndexElementTypeVar(xs, int64(0), "test.php on line 4").Getint64() + int64(10))
+	i := int64(1)
+	fmt.Print(IndexElementTypeVar(xs, i, "test.php on line 6").String())
+	xs = append(xs, NewVarFromInterface(int64(4)))
+}
+
+func IndexElementTypeVar(arr []Var, i int64, pos string) Var {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero Var
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+func TestRecordLikeArray(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$rec = ['id' => 5, 'name' => 'bob'];
+	$rec['name'] = 'ann';
+	echo $rec['id'] * 2;
+	echo $rec['name'];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	rec := NewOrderedArray(Pair{"id", int64(5)}, Pair{"name", "bob"})
+	rec.Set("name", "ann")
+	fmt.Print(rec.Fetch("id", int64(0), "test.php on line 5", Lenient).(int64) * int64(2))
+	fmt.Print(rec.Fetch("name", "", "test.php on line 6", Lenient).(string))
+}
+`))
+
+	s.RunTest()
+}
@@ -91,6 +91,7 @@ package test
 
 import (
 	"fmt"
+	. "github.com/i582/php2go/runtime"
 )
 
 func Foo() {
@@ -164,6 +165,7 @@ package test
 
 import (
 	"fmt"
+	. "github.com/i582/php2go/runtime"
 )
 
 func Foo() {
@@ -172,19 +174,19 @@ func Foo() {
 	if a.Getint64() > int64(100) {
 		a.Setstring("string")
 	}
-	if Isint64Simple(a.Getstring()) {
+	if Isint64(a) {
 		fmt.Print("integer")
 	}
 	b := NewVar()
 	b.Setfloat64(5.56)
-	if b.Getfloat64() != int64(10) {
+	if b.Getfloat64() != float64(int64(10)) {
 		b.Setstring("string")
 	}
-	if Isfloat64Simple(b.Getstring()) {
+	if Isfloat64(b) {
 		fmt.Print("float")
 	}
 	c := true
-	if b.Getstring() != int64(10) {
+	if b.CompareWithint64(int64(10), NotEqual) {
 		c = false
 	}
 	if IsboolSimple(c) {
@@ -195,7 +197,7 @@ func Foo() {
 	if d.Getstring() != "" {
 		d.Setint64(int64(12))
 	}
-	if IsstringSimple(d.Getint64()) {
+	if Isstring(d) {
 		fmt.Print("string")
 	}
 }
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestIssetEmpty(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$m = ["a" => 1];
+	if (isset($m["a"]["b"]) || !isset($undefined)) {
+		echo empty($m["a"]);
+	}
+	$s = "0";
+	echo empty($s);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	m := NewOrderedArray(Pair{"a", int64(1)})
+	if Isset(m, "a", "b") || !false {
+		fmt.Print(Empty(m, "a"))
+	}
+	s := "0"
+	fmt.Print(Empty(s))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestUnset(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$list = [1, 2, 3];
+	unset($list[1]);
+	$x = 5;
+	unset($x);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	list := NewOrderedArray(Pair{nil, int64(1)}, Pair{nil, int64(2)}, Pair{nil, int64(3)})
+	list.Delete(int64(1))
+	x := NewNullint64()
+	x.Setint64(int64(5))
+	x = NewNullint64()
+}
+`))
+
+	s.RunTest()
+}
@@ -0,0 +1,196 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestListSwap(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$a = 1;
+	$b = 2;
+	[$a, $b] = [$b, $a];
+	list(, $c) = [5, 6];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	a := int64(1)
+	b := int64(2)
+	_tmp1 := []int64{b, a}
+	a = IndexElementTypeint64(_tmp1, int64(0), "test.php on line 5")
+	b = IndexElementTypeint64(_tmp1, int64(1), "test.php on line 5")
+	_tmp2 := []int64{int64(5), int64(6)}
+	c := IndexElementTypeint64(_tmp2, int64(1), "test.php on line 6")
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+func TestListNestedAndKeyed(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$m = [[1, 2], [3, 4]];
+	[[$x, $y], $z] = $m;
+	$r = ['id' => 5, 'age' => 6];
+	['id' => $id, 'age' => $age] = $r;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	m := [][]int64{[]int64{int64(1), int64(2)}, []int64{int64(3), int64(4)}}
+	_tmp1 := m
+	_tmp2 := IndexElementTypeElementTypeint64(_tmp1, int64(0), "test.php on line 4")
+	x := IndexElementTypeint64(_tmp2, int64(0), "test.php on line 4")
+	y := IndexElementTypeint64(_tmp2, int64(1), "test.php on line 4")
+	z := IndexElementTypeElementTypeint64(_tmp1, int64(1), "test.php on line 4")
+	r := NewOrderedArray(Pair{"id", int64(5)}, Pair{"age", int64(6)})
+	_tmp3 := r
+	id := _tmp3.Fetch("id", int64(0), "test.php on line 6", Lenient).(int64)
+	age := _tmp3.Fetch("age", int64(0), "test.php on line 6", Lenient).(int64)
+}
+
+func IndexElementTypeElementTypeint64(arr [][]int64, i int64, pos string) []int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero []int64
+	return zero
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+// TestListMixedElements checks that the targets of a list of
+// differently typed elements get the types of their own elements.
+func TestListMixedElements(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	list($p, list($q, $r)) = [1, [2, 3]];
+	[$id, $name] = [5, "x"];
+	echo $p + $q + $r + $id, $name;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	_tmp1 := []Var{NewVarFromInterface(int64(1)), NewVarFromInterface([]int64{int64(2), int64(3)})}
+	p := IndexElementTypeVar(_tmp1, int64(0), "test.php on line 3").Getint64()
+	_tmp2 := IndexElementTypeVar(_tmp1, int64(1), "test.php on line 3").Value().([]int64)
+	q := IndexElementTypeint64(_tmp2, int64(0), "test.php on line 3")
+	r := IndexElementTypeint64(_tmp2, int64(1), "test.php on line 3")
+	_tmp3 := []Var{NewVarFromInterface(int64(5)), NewVarFromInterface("x")}
+	id := IndexElementTypeVar(_tmp3, int64(0), "test.php on line 4").Getint64()
+	name := IndexElementTypeVar(_tmp3, int64(1), "test.php on line 4").Getstring()
+	fmt.Print(p+q+r+id, name)
+}
+
+func IndexElementTypeVar(arr []Var, i int64, pos string) Var {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero Var
+	return zero
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+func TestListInForeach(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$rows = [[1, 2], [3, 4]];
+	foreach ($rows as [$id, $count]) {
+		echo $id + $count;
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	rows := [][]int64{[]int64{int64(1), int64(2)}, []int64{int64(3), int64(4)}}
+	for _, _tmp1 := range rows {
+		id := IndexElementTypeint64(_tmp1, int64(0), "test.php on line 4")
+		count := IndexElementTypeint64(_tmp1, int64(1), "test.php on line 4")
+		fmt.Print(id + count)
+	}
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
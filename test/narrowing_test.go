@@ -0,0 +1,89 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestNarrowing(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Pick(int $n) {
+	if ($n > 1) {
+		return "s";
+	}
+	return $n;
+}
+
+function Half(int $n) {
+	$x = Pick($n);
+	if (!is_int($x)) {
+		return 0;
+	}
+	return $x * 2;
+}
+
+function Foo() {
+	$x = Pick(1);
+	if (is_int($x)) {
+		$y = $x + 1;
+		echo $y;
+	} else {
+		echo $x . "!";
+	}
+	if (is_string($x) && $x == "s") {
+		echo "s";
+	}
+	$n = Pick(2);
+	if ($n !== null) {
+		echo "not null";
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Pick(n int64) Var {
+	if n > int64(1) {
+		return NewVarstring("s")
+	}
+	return NewVarint64(n)
+}
+
+func Half(n int64) int64 {
+	x := Pick(n)
+	if !Isint64(x) {
+		return int64(0)
+	}
+	return x.Getint64() * int64(2)
+}
+
+func Foo() {
+	x := Pick(int64(1))
+	if Isint64(x) {
+		x := x.Getint64()
+		y := x + int64(1)
+		fmt.Print(y)
+	} else {
+		x := x.Getstring()
+		fmt.Print(x + "!")
+	}
+	if Isstring(x) && x.Getstring() == "s" {
+		fmt.Print("s")
+	}
+	n := Pick(int64(2))
+	if !n.IsNull() {
+		fmt.Print("not null")
+	}
+}
+`))
+
+	s.RunTest()
+}
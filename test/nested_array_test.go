@@ -0,0 +1,152 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestNestedArrays(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$grid = [[1, 2], [3]];
+	$grid[1][] = 4;
+	$grid[0][0] = 5;
+	echo $grid[1][1];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	grid := [][]int64{[]int64{int64(1), int64(2)}, []int64{int64(3)}}
+	if int64(len(grid)) == int64(1) {
+		grid = append(grid, nil)
+	}
+	grid[int64(1)] = append(grid[int64(1)], int64(4))
+	if int64(len(grid)) == int64(0) {
+		grid = append(grid, nil)
+	}
+	grid[int64(0)][int64(0)] = int64(5)
+	fmt.Print(IndexElementTypeint64(IndexElementTypeElementTypeint64(grid, int64(1), "test.php on line 6"), int64(1), "test.php on line 6"))
+}
+
+func IndexElementTypeElementTypeint64(arr [][]int64, i int64, pos string) []int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero []int64
+	return zero
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+func TestNestedArraysAutovivification(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$conf = ['db' => ['host' => 'x']];
+	$conf['cache']['host'] = 'redis';
+	$tags = ['a' => [1, 2]];
+	$tags['b'][] = 3;
+	echo $conf['cache']['host'];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	conf := NewOrderedArray(Pair{"db", NewOrderedArray(Pair{"host", "x"})})
+	conf.Nested("cache").Set("host", "redis")
+	tags := NewOrderedArray(Pair{"a", []int64{int64(1), int64(2)}})
+	_tmp1, _ := tags.Get("b").([]int64)
+	tags.Set("b", append(_tmp1, int64(3)))
+	fmt.Print(conf.Fetch("cache", (*OrderedArray)(nil), "test.php on line 7", Lenient).(*OrderedArray).Fetch("host", "", "test.php on line 7", Lenient).(string))
+}
+`))
+
+	s.RunTest()
+}
+
+// TestNestedArrayMissingElement checks that the write to the element of
+// the missing nested array with the next key creates the array, the key
+// of the other expression is evaluated once.
+func TestNestedArrayMissingElement(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo(int $i) {
+	$g = [[1, 2], [3, 4]];
+	$g[2][] = 7;
+	$g[$i + 1][0] = 8;
+	echo $g[2][0];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo(i int64) {
+	g := [][]int64{[]int64{int64(1), int64(2)}, []int64{int64(3), int64(4)}}
+	if int64(len(g)) == int64(2) {
+		g = append(g, nil)
+	}
+	g[int64(2)] = append(g[int64(2)], int64(7))
+	_tmp1 := i + int64(1)
+	if int64(len(g)) == _tmp1 {
+		g = append(g, nil)
+	}
+	g[_tmp1][int64(0)] = int64(8)
+	fmt.Print(IndexElementTypeint64(IndexElementTypeElementTypeint64(g, int64(2), "test.php on line 6"), int64(0), "test.php on line 6"))
+}
+
+func IndexElementTypeElementTypeint64(arr [][]int64, i int64, pos string) []int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero []int64
+	return zero
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
@@ -0,0 +1,192 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestNullable(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Find(int $n) {
+	if ($n > 2) {
+		return $n * 10;
+	}
+	return null;
+}
+
+function Foo() {
+	$x = null;
+	if (Find(1) == null) {
+		$x = 5;
+	}
+	echo $x;
+	$y = Find(3);
+	if (is_null($y) || $y === null) {
+		echo "null";
+	}
+	if (is_int($y) && $y > 5) {
+		echo $y;
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Find(n int64) Nullint64 {
+	if n > int64(2) {
+		return NewNullint64FromInterface(n * int64(10))
+	}
+	return NewNullint64FromInterface(nil)
+}
+
+func Foo() {
+	x := NewNullint64()
+	x.Setnull(nil)
+	if Find(int64(1)).CompareWithnull(nil, Equal) {
+		x.Setint64(int64(5))
+	}
+	fmt.Print(x.String())
+	y := Find(int64(3))
+	if y.IsNull() || false {
+		fmt.Print("null")
+	}
+	if !y.IsNull() && y.Getint64() > int64(5) {
+		y := y.Getint64()
+		fmt.Print(y)
+	}
+}
+`))
+
+	s.RunTest()
+}
+
+func TestNullOnlyAndCoalesce(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Nothing() {
+	return null;
+}
+
+function Pick(array $m, $x) {
+	$v = null;
+	if (is_null($v)) {
+		echo $m["a"] ?? 5, $m["b"] ?? 5;
+	}
+	echo $x ?? 7, $undefined ?? "d";
+	$n = Nothing();
+	return $n;
+}
+
+function Main() {
+	Pick(["a" => 1], 3);
+	Pick(["a" => 1], null);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Nothing() Var {
+	return NewVar()
+}
+
+func Pick(m *OrderedArray, x Nullint64) Var {
+	v := NewVar()
+	if Isnull(v) {
+		fmt.Print(func() int64 {
+			if Isset(m, "a") {
+				return m.Fetch("a", int64(0), "test.php on line 9", Lenient).(int64)
+			}
+			return int64(5)
+		}(), func() int64 {
+			if Isset(m, "b") {
+				return m.Fetch("b", int64(0), "test.php on line 9", Lenient).(int64)
+			}
+			return int64(5)
+		}())
+	}
+	fmt.Print(func() int64 {
+		if !x.IsNull() {
+			return x.Getint64()
+		}
+		return int64(7)
+	}(), "d")
+	n := Nothing()
+	return n
+}
+
+func Main() {
+	Pick(NewOrderedArray(Pair{"a", int64(1)}), NewNullint64FromInterface(int64(3)))
+	Pick(NewOrderedArray(Pair{"a", int64(1)}), NewNullint64FromInterface(nil))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestReturnWithoutValue(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Log($c) {
+	if ($c) {
+		return;
+	}
+	echo "log";
+}
+
+function Find($c) {
+	if ($c) {
+		return;
+	}
+	return 5;
+}
+
+function Foo() {
+	Log(false);
+	echo Find(true), Find(false);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Log(c bool) {
+	if c {
+		return
+	}
+	fmt.Print("log")
+}
+
+func Find(c bool) Nullint64 {
+	if c {
+		return NewNullint64FromInterface(nil)
+	}
+	return NewNullint64FromInterface(int64(5))
+}
+
+func Foo() {
+	Log(false)
+	fmt.Print(Find(true).String(), Find(false).String())
+}
+`))
+
+	s.RunTest()
+}
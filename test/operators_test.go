@@ -0,0 +1,188 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestUnionOperators(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Pick(int $n) {
+	if ($n > 1) {
+		return "10";
+	}
+	return $n;
+}
+
+function Foo() {
+	$f = Pick(2);
+	$g = Pick(1);
+	echo $f + 1;
+	echo $f . "x";
+	$r = $f * $g - 0.5;
+	echo $r / 2;
+	if ($g < $f) {
+		echo "smaller";
+	}
+	if (5 == "5") {
+		echo "equal";
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Pick(n int64) Var {
+	if n > int64(1) {
+		return NewVarstring("10")
+	}
+	return NewVarint64(n)
+}
+
+func Foo() {
+	f := Pick(int64(2))
+	g := Pick(int64(1))
+	fmt.Print(Add(f, NewVarint64(int64(1))).String())
+	fmt.Print(Concat(f, NewVarstring("x")))
+	r := Sub(Mul(f, g), NewVarfloat64(0.5))
+	fmt.Print(Div(r, NewVarint64(int64(2))).String())
+	if Compare(g, f) < 0 {
+		fmt.Print("smaller")
+	}
+	if Compare(NewVarint64(int64(5)), NewVarstring("5")) == 0 {
+		fmt.Print("equal")
+	}
+}
+`))
+
+	s.RunTest()
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo(int $a, int $b, int $c) {
+	echo ($a + $b) * $c;
+	echo $a - ($b - $c);
+	echo -($a - $b);
+	echo !($a > 1 && $b > 2);
+	echo $a > 1 && ($b > 2 || $c > 3);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Foo(a int64, b int64, c int64) {
+	fmt.Print((a + b) * c)
+	fmt.Print(a - (b - c))
+	fmt.Print(-(a - b))
+	fmt.Print(!(a > int64(1) && b > int64(2)))
+	fmt.Print(a > int64(1) && (b > int64(2) || c > int64(3)))
+}
+`))
+
+	s.RunTest()
+}
+
+// TestIdenticalOperators checks that === compares the types before the
+// values, the value of union type is compared by its type first.
+func TestIdenticalOperators(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo(bool $c, int $i, float $x) {
+	if ($c) {
+		$f = 10;
+	} else {
+		$f = "a";
+	}
+	$a = $i === "1";
+	$b = $i === $x;
+	$d = $i === 1;
+	$e = $f === "10";
+	$g = $f !== 10;
+	$h = $f === 1.5;
+	$k = $f === $f;
+	echo $f == 10, $f === 10;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo(c bool, i int64, x float64) {
+	var f Var
+	if c {
+		f.Setint64(int64(10))
+	} else {
+		f.Setstring("a")
+	}
+	a := false
+	b := false
+	d := i == int64(1)
+	e := f.Type == Constantstring && f.Getstring() == "10"
+	g := !(f.Type == Constantint64 && f.Getint64() == int64(10))
+	h := false
+	k := Identical(f, f)
+	fmt.Print(f.CompareWithint64(int64(10), Equal), f.Type == Constantint64 && f.Getint64() == int64(10))
+}
+`))
+
+	s.RunTest()
+}
+
+// TestIntegerDivision checks that the division of integers is float
+// unless they are evenly divisible, which is known only at runtime
+// for the operands other than literals.
+func TestIntegerDivision(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo(int $a, int $b, float $x) {
+	echo $a / $b;
+	echo 10 / 8;
+	echo 10 / 5;
+	echo $x / $a;
+	if ($a / $b) {
+		echo "not zero";
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo(a int64, b int64, x float64) {
+	fmt.Print(Div(NewVarint64(a), NewVarint64(b)).String())
+	fmt.Print(float64(int64(10)) / float64(int64(8)))
+	fmt.Print(int64(10) / int64(5))
+	fmt.Print(x / float64(a))
+	if Div(NewVarint64(a), NewVarint64(b)).Bool() {
+		fmt.Print("not zero")
+	}
+}
+`))
+
+	s.RunTest()
+}
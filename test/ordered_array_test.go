@@ -0,0 +1,78 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestOrderedArray(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$ages = ['bob' => 30, 'ann' => 25];
+	$ages['zed'] = 50;
+	$ages[] = 60;
+	foreach ($ages as $age) {
+		echo $age;
+	}
+	echo $ages['ann'];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	ages := NewOrderedArray(Pair{"bob", int64(30)}, Pair{"ann", int64(25)})
+	ages.Set("zed", int64(50))
+	ages.Append(int64(60))
+	for _, _tmp1 := range ages.Entries() {
+		age := _tmp1.Value.(int64)
+		fmt.Print(age)
+	}
+	fmt.Print(ages.Fetch("ann", int64(0), "test.php on line 9", Lenient).(int64))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestOrderedArrayMixedKeys(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$words = [1 => 'one', '2' => 'two', 'three'];
+	foreach ($words as $n => $word) {
+		echo $n + 1;
+		echo $word;
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	words := NewOrderedArray(Pair{int64(1), "one"}, Pair{"2", "two"}, Pair{nil, "three"})
+	for _, _tmp1 := range words.Entries() {
+		n := _tmp1.Key.(int64)
+		word := _tmp1.Value.(string)
+		fmt.Print(n + int64(1))
+		fmt.Print(word)
+	}
+}
+`))
+
+	s.RunTest()
+}
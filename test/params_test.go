@@ -0,0 +1,179 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestDefaultParams(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Greet($name, $greeting = "Hello") {
+	return $greeting . ", " . $name;
+}
+
+function Foo() {
+	echo Greet("Bob");
+	echo Greet("Ann", "Hi");
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Greet(name string, greeting string) string {
+	return greeting + ", " + name
+}
+
+func Foo() {
+	fmt.Print(Greet("Bob", "Hello"))
+	fmt.Print(Greet("Ann", "Hi"))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestVariadicAndSpread(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Sum(int ...$nums) {
+	$s = 0;
+	foreach ($nums as $n) {
+		$s = $s + $n;
+	}
+	return $s;
+}
+
+function Add($a, $b) {
+	return $a + $b;
+}
+
+function Foo() {
+	$xs = [4, 5];
+	echo Sum(1, 2, 3);
+	echo Sum(...$xs);
+	echo Add(...$xs);
+	$ys = [1, ...$xs, 6];
+	echo Sum(...$ys);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Sum(nums ...int64) int64 {
+	s := int64(0)
+	for _, n := range nums {
+		s = s + n
+	}
+	return s
+}
+
+func Add_(a int64, b int64) int64 {
+	return a + b
+}
+
+func Foo() {
+	xs := []int64{int64(4), int64(5)}
+	fmt.Print(Sum(int64(1), int64(2), int64(3)))
+	fmt.Print(Sum(xs...))
+	fmt.Print(Add_(xs[0], xs[1]))
+	ys := append(append([]int64{int64(1)}, xs...), int64(6))
+	fmt.Print(Sum(ys...))
+}
+`))
+
+	s.RunTest()
+}
+
+// TestSpreadAfterArguments checks that the arguments and the arrays
+// spread into the variadic parameter after them are appended to
+// one slice, so none of them is lost.
+func TestSpreadAfterArguments(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Sum(int ...$nums) {
+	$s = 0;
+	foreach ($nums as $n) {
+		$s = $s + $n;
+	}
+	return $s;
+}
+
+function Foo() {
+	$xs = [2, 3, 4];
+	$ys = [5, 6];
+	echo Sum(1, ...$xs);
+	echo Sum(...$xs, ...$ys);
+	echo Sum(1, 2, ...$xs, ...$ys);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Sum(nums ...int64) int64 {
+	s := int64(0)
+	for _, n := range nums {
+		s = s + n
+	}
+	return s
+}
+
+func Foo() {
+	xs := []int64{int64(2), int64(3), int64(4)}
+	ys := []int64{int64(5), int64(6)}
+	fmt.Print(Sum(append([]int64{int64(1)}, xs...)...))
+	fmt.Print(Sum(append(append([]int64{}, xs...), ys...)...))
+	fmt.Print(Sum(append(append([]int64{int64(1), int64(2)}, xs...), ys...)...))
+}
+`))
+
+	s.RunTest()
+}
+
+// TestSpreadMixedAndKeyed checks the spread arrays of other element
+// types, which are converted to Var, and the spread into the array
+// with string keys, which appends the elements with the next indexes.
+func TestSpreadMixedAndKeyed(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$a = [1, 2];
+	$b = ["x", "y"];
+	$c = [...$a, ...$b];
+	$d = ['k' => 1, ...$a, 'z' => 9];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	a := []int64{int64(1), int64(2)}
+	b := []string{"x", "y"}
+	c := append(append([]Var{}, Vars(a)...), Vars(b)...)
+	d := NewOrderedArray(append(append([]Pair{Pair{"k", int64(1)}}, Unpack(a)...), Pair{"z", int64(9)})...)
+}
+`))
+
+	s.RunTest()
+}
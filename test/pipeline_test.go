@@ -0,0 +1,311 @@
+package test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/i582/php2go/src/pipeline"
+)
+
+func pipelineFiles() []*pipeline.File {
+	return []*pipeline.File{
+		{Name: "a.php", Src: []byte(`<?php
+define("LIMIT", 10);
+
+function Sum($xs) {
+	$res = 0;
+	foreach ($xs as $x) {
+		$res = $res + Cap($x);
+	}
+	return $res;
+}
+`)},
+		{Name: "b.php", Src: []byte(`<?php
+function Cap($x) {
+	if ($x > LIMIT) {
+		return LIMIT;
+	}
+	return $x;
+}
+
+function First($xs) {
+	return $xs[0];
+}
+`)},
+		{Name: "c.php", Src: []byte(`<?php
+function Total() {
+	$xs = [1, 2, 30];
+	return Sum($xs) + First($xs);
+}
+`)},
+	}
+}
+
+// TestPipeline translates the files that use the functions and the
+// constants of each other, the function reading the elements of slices
+// is declared once in the core file.
+func TestPipeline(t *testing.T) {
+	files := pipelineFiles()
+	core, err := pipeline.Translate(files, pipeline.Options{Package: "lib", Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"a.go": `// Code generated by php2go. PLEASE DO NOT EDIT.
+package lib
+
+const LIMIT = int64(10)
+
+func Sum(xs []int64) int64 {
+	res := int64(0)
+	for _, x := range xs {
+		res = res + Cap(x)
+	}
+	return res
+}
+`,
+		"b.go": `// Code generated by php2go. PLEASE DO NOT EDIT.
+package lib
+
+func Cap(x int64) int64 {
+	if x > LIMIT {
+		return LIMIT
+	}
+	return x
+}
+
+func First(xs []int64) int64 {
+	return IndexElementTypeint64(xs, int64(0), "b.php on line 10")
+}
+`,
+		"c.go": `// Code generated by php2go. PLEASE DO NOT EDIT.
+package lib
+
+func Total() int64 {
+	xs := []int64{int64(1), int64(2), int64(30)}
+	return Sum(xs) + First(xs)
+}
+`,
+		pipeline.CoreFile: `// Code generated by php2go. PLEASE DO NOT EDIT.
+package lib
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`,
+	}
+
+	have := map[string]string{pipeline.CoreFile: string(core)}
+	for _, f := range files {
+		for _, e := range f.Errors {
+			t.Error(e)
+		}
+		have[f.GoName()] = string(f.Code)
+	}
+
+	if !cmp.Equal(have, expected) {
+		t.Error(cmp.Diff(have, expected))
+	}
+}
+
+// chainFiles returns n files, each of them calls the function
+// and reads the constant declared in the previous one.
+func chainFiles(n int) []*pipeline.File {
+	var files []*pipeline.File
+	for i := 0; i < n; i++ {
+		res := "$xs[0]"
+		if i != 0 {
+			res = fmt.Sprintf("F%d($x) + $xs[C%d]", i-1, i-1)
+		}
+		files = append(files, &pipeline.File{
+			Name: fmt.Sprintf("f%d.php", i),
+			Src: []byte(fmt.Sprintf(`<?php
+const C%[1]d = %[2]d;
+
+function F%[1]d($x) {
+	$xs = [$x, 2.5];
+	return %[3]s;
+}
+
+function G%[1]d($n) {
+	$sum = 0;
+	for ($i = 0; $i < $n; $i++) {
+		if ($i > C%[1]d) {
+			$sum = $sum + F%[1]d($i);
+		}
+	}
+	return $sum;
+}
+`, i, i%2, res)),
+		})
+	}
+	return files
+}
+
+// TestPipelineWorkers checks that the output
+// does not depend on the number of the workers.
+func TestPipelineWorkers(t *testing.T) {
+	translate := func(workers int) map[string]string {
+		files := chainFiles(50)
+		core, err := pipeline.Translate(files, pipeline.Options{Package: "lib", Workers: workers})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := map[string]string{pipeline.CoreFile: string(core)}
+		for _, f := range files {
+			res[f.GoName()] = string(f.Code)
+		}
+		return res
+	}
+
+	want := translate(1)
+	for _, workers := range []int{2, 8} {
+		if have := translate(workers); !cmp.Equal(have, want) {
+			t.Errorf("workers %d:\n%s", workers, cmp.Diff(have, want))
+		}
+	}
+}
+
+// BenchmarkPipeline compares the translation of a few thousand
+// files by one worker with the translation by a worker per CPU.
+func BenchmarkPipeline(b *testing.B) {
+	workers := []int{1}
+	if n := runtime.GOMAXPROCS(0); n > 1 {
+		workers = append(workers, n)
+	}
+
+	for _, workers := range workers {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				files := chainFiles(3000)
+				b.StartTimer()
+
+				if _, err := pipeline.Translate(files, pipeline.Options{Package: "lib", Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestPipelineUnresolvedType checks that the values whose types
+// are not inferred are reported instead of generating invalid code.
+func TestPipelineUnresolvedType(t *testing.T) {
+	files := []*pipeline.File{
+		{Name: "a.php", Src: []byte(`<?php
+function Id($x) {
+	return $x;
+}
+`)},
+	}
+
+	_, err := pipeline.Translate(files, pipeline.Options{Package: "lib"})
+	expected := "a.go: a.php:3: the type of the result of Id is not inferred"
+	if err == nil || err.Error() != expected {
+		t.Errorf("have error %v, want %q", err, expected)
+	}
+}
+
+// TestPipelineUnsupported checks that the constructs that
+// are not supported are reported with their positions.
+func TestPipelineUnsupported(t *testing.T) {
+	files := []*pipeline.File{
+		{Name: "a.php", Src: []byte(`<?php
+function Mod($x, $y) {
+	return $x % $y;
+}
+
+function Max($x, $y) {
+	return $x > $y ? $x : $y;
+}
+
+Mod(1, 2);
+Max(1, 2);
+`)},
+	}
+
+	_, err := pipeline.Translate(files, pipeline.Options{Package: "lib"})
+	expected := "a.php:3: the expression binary.Mod is not supported"
+	if err == nil || err.Error() != expected {
+		t.Errorf("have error %v, want %q", err, expected)
+	}
+}
+
+// TestPipelineDuplicateFunction checks that the function
+// declared in two files is reported with both positions.
+func TestPipelineDuplicateFunction(t *testing.T) {
+	files := []*pipeline.File{
+		{Name: "a.php", Src: []byte(`<?php
+function Dup() {
+	return 1;
+}
+`)},
+		{Name: "b.php", Src: []byte(`<?php
+
+function Dup() {
+	return 2;
+}
+`)},
+	}
+
+	_, err := pipeline.Translate(files, pipeline.Options{Package: "lib"})
+	expected := "b.php:3: the function Dup is already declared at a.php:2"
+	if err == nil || err.Error() != expected {
+		t.Errorf("have error %v, want %q", err, expected)
+	}
+}
+
+// TestPipelinePanic checks that the failures of the translation
+// of a file are reported with the position of the statement.
+func TestPipelinePanic(t *testing.T) {
+	tests := []struct {
+		src string
+		pos string
+	}{
+		{
+			src: `<?php
+function Set() {
+	$a->b = 1;
+}
+`,
+			pos: "b.php:3",
+		},
+		{
+			src: `<?php
+function Call() {
+	$f = function() { return 1; };
+	return $f();
+}
+`,
+			pos: "b.php:4",
+		},
+	}
+
+	for _, test := range tests {
+		files := []*pipeline.File{
+			{Name: "a.php", Src: []byte("<?php\nfunction One() {\n\treturn 1;\n}\n")},
+			{Name: "b.php", Src: []byte(test.src)},
+		}
+
+		_, err := pipeline.Translate(files, pipeline.Options{Package: "lib", Workers: 2})
+		if err == nil || !strings.HasPrefix(err.Error(), test.pos+": ") {
+			t.Errorf("have error %v, want the error at %s", err, test.pos)
+		}
+	}
+}
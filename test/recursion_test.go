@@ -0,0 +1,182 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestRecursion(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function SumTo($n) {
+	if ($n > 0) {
+		return $n + SumTo($n - 1);
+	}
+	return 0;
+}
+
+function IsEvenNumber($n) {
+	if ($n == 0) {
+		return true;
+	}
+	return IsOddNumber($n - 1);
+}
+
+function IsOddNumber($n) {
+	if ($n == 0) {
+		return false;
+	}
+	return IsEvenNumber($n - 1);
+}
+
+function Foo() {
+	echo SumTo(4);
+	echo IsEvenNumber(4);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func SumTo(n int64) int64 {
+	if n > int64(0) {
+		return n + SumTo(n-int64(1))
+	}
+	return int64(0)
+}
+
+func IsEvenNumber(n int64) bool {
+	if n == int64(0) {
+		return true
+	}
+	return IsOddNumber(n - int64(1))
+}
+
+func IsOddNumber(n int64) bool {
+	if n == int64(0) {
+		return false
+	}
+	return IsEvenNumber(n - int64(1))
+}
+
+func Foo() {
+	fmt.Print(SumTo(int64(4)))
+	fmt.Print(IsEvenNumber(int64(4)))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestCallBeforeDeclaration(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Scaled($x) {
+	$v = Offset($x);
+	return $v * 2;
+}
+
+function Offset($y) {
+	return $y + 1;
+}
+
+function Forward($w) {
+	return Identity($w);
+}
+
+function Identity($v) {
+	return $v;
+}
+
+function Foo() {
+	echo Scaled(1.5);
+	echo Forward("s");
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Scaled(x float64) float64 {
+	v := Offset(x)
+	return v * float64(int64(2))
+}
+
+func Offset(y float64) float64 {
+	return y + float64(int64(1))
+}
+
+func Forward(w string) string {
+	return Identity(w)
+}
+
+func Identity(v string) string {
+	return v
+}
+
+func Foo() {
+	fmt.Print(Scaled(1.5))
+	fmt.Print(Forward("s"))
+}
+`))
+
+	s.RunTest()
+}
+
+func TestRecursiveNestedArray(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Nest($n) {
+	if ($n == 0) {
+		return [1];
+	}
+	return [Nest($n - 1)];
+}
+
+function Foo() {
+	$a = Nest(2);
+	echo is_array($a[0]);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Nest(n int64) []Var {
+	if n == int64(0) {
+		return []Var{NewVarFromInterface(int64(1))}
+	}
+	return []Var{NewVarFromInterface(Nest(n - int64(1)))}
+}
+
+func Foo() {
+	a := Nest(int64(2))
+	fmt.Print(Isarray(IndexElementTypeVar(a, int64(0), "test.php on line 11")))
+}
+
+func IndexElementTypeVar(arr []Var, i int64, pos string) Var {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero Var
+	return zero
+}
+`))
+
+	s.RunTest()
+}
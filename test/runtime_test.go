@@ -0,0 +1,129 @@
+package test
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/i582/php2go/src/inline"
+	"github.com/i582/php2go/src/testsuite"
+	"github.com/i582/php2go/src/utils"
+)
+
+func TestInlineRuntime(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.InlineRuntime = true
+	s.AddFile([]byte(`<?php
+function InlineFoo(bool $flag) {
+	$x = 1;
+	if ($flag) {
+		$x = "one";
+	}
+	echo $x;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func InlineFoo(flag bool) {
+	x := NewVar()
+	x.Setint64(int64(1))
+	if flag {
+		x.Setstring("one")
+	}
+	fmt.Print(x.String())
+}
+`))
+
+	s.RunTest()
+}
+
+// TestInlineSourceUpToDate checks that the runtime written to the core
+// file is the same as the runtime package, run go generate if not.
+func TestInlineSourceUpToDate(t *testing.T) {
+	want, err := inline.Render("../runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have, err := ioutil.ReadFile("../src/inline/source.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(string(have), string(want)) {
+		t.Errorf("src/inline/source.go is outdated, run go generate ./src/inline:\n%s", cmp.Diff(string(have), string(want)))
+	}
+}
+
+// TestRuntimeNameClash checks that the functions named like
+// the names of the runtime are renamed, since the runtime
+// is imported with the dot.
+func TestRuntimeNameClash(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Add($a, $b) {
+	return $a + $b;
+}
+
+function Concat(bool $flag) {
+	$x = 1;
+	if ($flag) {
+		$x = "one";
+	}
+	return $x . Add(1, 2);
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
+func Add_(a int64, b int64) int64 {
+	return a + b
+}
+
+func Concat_(flag bool) string {
+	x := NewVar()
+	x.Setint64(int64(1))
+	if flag {
+		x.Setstring("one")
+	}
+	return Concat(x, NewVarint64(Add_(int64(1), int64(2))))
+}
+`))
+
+	s.RunTest()
+}
+
+// TestRuntimeNamesRenamed checks that every name exported
+// by the runtime package is renamed by utils.GoIdentifier.
+func TestRuntimeNamesRenamed(t *testing.T) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), "../runtime", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range pkgs["runtime"].Files {
+		for name := range f.Scope.Objects {
+			if token.IsExported(name) && utils.GoIdentifier(name) == name {
+				t.Errorf("the runtime name %s is not renamed", name)
+			}
+		}
+	}
+}
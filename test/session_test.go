@@ -0,0 +1,77 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+// TestSessionsIndependent translates two programs declaring the function
+// with the same name concurrently, each gets the type of its own function.
+func TestSessionsIndependent(t *testing.T) {
+	t.Run("int", func(t *testing.T) {
+		t.Parallel()
+
+		s := testsuite.NewSuite(t)
+		s.AddFile([]byte(`<?php
+function Foo() {
+	return 1;
+}
+
+function Bar() {
+	echo Foo() + 1;
+}
+`))
+
+		s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Foo() int64 {
+	return int64(1)
+}
+
+func Bar() {
+	fmt.Print(Foo() + int64(1))
+}
+`))
+
+		s.RunTest()
+	})
+
+	t.Run("string", func(t *testing.T) {
+		t.Parallel()
+
+		s := testsuite.NewSuite(t)
+		s.AddFile([]byte(`<?php
+function Foo() {
+	return "a";
+}
+
+function Bar() {
+	echo Foo() . "b";
+}
+`))
+
+		s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+)
+
+func Foo() string {
+	return "a"
+}
+
+func Bar() {
+	fmt.Print(Foo() + "b")
+}
+`))
+
+		s.RunTest()
+	})
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestSpecialize(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.Specializations = 2
+	s.AddFile([]byte(`<?php
+function Larger($a, $b) {
+	if ($a > $b) {
+		return $a;
+	}
+	return $b;
+}
+
+function Foo() {
+	echo Larger(1, 2);
+	echo Larger(1.5, 0.5);
+	echo Larger(3, 4);
+	echo Larger("a", "b");
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Larger(a Var, b Var) Var {
+	if Compare(a, b) > 0 {
+		return a
+	}
+	return b
+}
+
+func Larger_int64_int64(a int64, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Larger_float64_float64(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func Foo() {
+	fmt.Print(Larger_int64_int64(int64(1), int64(2)))
+	fmt.Print(Larger_float64_float64(1.5, 0.5))
+	fmt.Print(Larger_int64_int64(int64(3), int64(4)))
+	fmt.Print(Larger(NewVarFromInterface("a"), NewVarFromInterface("b")).String())
+}
+`))
+
+	s.RunTest()
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/i582/php2go/src/testsuite"
+)
+
+func TestUndefinedKeyReads(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo(bool $flag) {
+	$list = [10, 20];
+	$m = ["a" => "x"];
+	if ($flag) {
+		$x = [1, 2];
+	} else {
+		$x = "str";
+	}
+	echo $list[5], $m["b"], $x[1];
+	$list[0]++;
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo(flag bool) {
+	list := []int64{int64(10), int64(20)}
+	m := NewOrderedArray(Pair{"a", "x"})
+	var x Var
+	if flag {
+		x.Set([]int64{int64(1), int64(2)})
+	} else {
+		x.Setstring("str")
+	}
+	fmt.Print(IndexElementTypeint64(list, int64(5), "test.php on line 10"), m.Fetch("b", "", "test.php on line 10", Lenient).(string), Fetch(x, int64(1), int64(0), "test.php on line 10", Lenient).(int64))
+	list[int64(0)]++
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Lenient)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+// TestUndefinedKeyStrict checks that the strict policy is passed
+// to the runtime by the reads of the elements.
+func TestUndefinedKeyStrict(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.StrictArrayKeys = true
+	s.AddFile([]byte(`<?php
+function Foo() {
+	$list = [10, 20];
+	$m = ["a" => "x"];
+	echo $list[5], $m["b"];
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo() {
+	list := []int64{int64(10), int64(20)}
+	m := NewOrderedArray(Pair{"a", "x"})
+	fmt.Print(IndexElementTypeint64(list, int64(5), "test.php on line 5"), m.Fetch("b", "", "test.php on line 5", Strict).(string))
+}
+
+func IndexElementTypeint64(arr []int64, i int64, pos string) int64 {
+	if i >= 0 && i < int64(len(arr)) {
+		return arr[i]
+	}
+	UndefinedKey(i, pos, Strict)
+	var zero int64
+	return zero
+}
+`))
+
+	s.RunTest()
+}
+
+// TestUndefinedKeyNullChecks checks that the comparisons of element
+// reads with null check the keys instead of reading the zero values.
+func TestUndefinedKeyNullChecks(t *testing.T) {
+	s := testsuite.NewSuite(t)
+	s.AddFile([]byte(`<?php
+function Foo(int $i) {
+	$list = [10, 20];
+	$m = ["a" => "x"];
+	$n = [[1], [2]];
+	if ($list[$i] === null) {
+		echo "undefined";
+	}
+	if (null !== $m["b"]) {
+		echo "defined";
+	}
+	if (is_null($n[$i][0])) {
+		echo "no element";
+	}
+}
+`))
+
+	s.AddExpected([]byte(`
+package test
+
+import (
+	"fmt"
+	. "github.com/i582/php2go/runtime"
+)
+
+func Foo(i int64) {
+	list := []int64{int64(10), int64(20)}
+	m := NewOrderedArray(Pair{"a", "x"})
+	n := [][]int64{[]int64{int64(1)}, []int64{int64(2)}}
+	if !Isset(list, i) {
+		fmt.Print("undefined")
+	}
+	if Isset(m, "b") {
+		fmt.Print("defined")
+	}
+	if !Isset(n, i, int64(0)) {
+		fmt.Print("no element")
+	}
+}
+`))
+
+	s.RunTest()
+}
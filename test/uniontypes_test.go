@@ -24,6 +24,10 @@ function Foo() {
 	s.AddExpected([]byte(`
 package test
 
+import (
+	. "github.com/i582/php2go/runtime"
+)
+
 func Foo() {
 	a := NewVar()
 	a.Setint64(int64(5))
@@ -61,6 +65,7 @@ package test
 
 import (
 	"fmt"
+	. "github.com/i582/php2go/runtime"
 )
 
 func Foo() {
@@ -96,6 +101,7 @@ package test
 
 import (
 	"fmt"
+	. "github.com/i582/php2go/runtime"
 )
 
 func Foo() {